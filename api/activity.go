@@ -29,6 +29,10 @@ const (
 	ActivityMemoUpdate ActivityType = "memo.update"
 	// ActivityMemoDelete is the type for deleting memos.
 	ActivityMemoDelete ActivityType = "memo.delete"
+	// ActivityMemoReaction is the type for reacting to memos.
+	ActivityMemoReaction ActivityType = "memo.reaction"
+	// ActivityMemoBatch is the type for applying an operation to many memos.
+	ActivityMemoBatch ActivityType = "memo.batch"
 
 	// Shortcut related.
 
@@ -52,6 +56,10 @@ const (
 	ActivityTagCreate ActivityType = "tag.create"
 	// ActivityTagDelete is the type for deleting tags.
 	ActivityTagDelete ActivityType = "tag.delete"
+	// ActivityTagRename is the type for renaming tags.
+	ActivityTagRename ActivityType = "tag.rename"
+	// ActivityTagMerge is the type for merging tags.
+	ActivityTagMerge ActivityType = "tag.merge"
 
 	// Server related.
 
@@ -92,6 +100,17 @@ type ActivityMemoCreatePayload struct {
 	Visibility string `json:"visibility"`
 }
 
+type ActivityMemoReactionPayload struct {
+	MemoID   int    `json:"memoId"`
+	Reaction string `json:"reaction"`
+}
+
+type ActivityMemoBatchPayload struct {
+	Operation  string `json:"operation"`
+	MemoIDList []int  `json:"memoIdList"`
+	Visibility string `json:"visibility,omitempty"`
+}
+
 type ActivityShortcutCreatePayload struct {
 	Title   string `json:"title"`
 	Payload string `json:"payload"`
@@ -107,6 +126,18 @@ type ActivityTagCreatePayload struct {
 	TagName string `json:"tagName"`
 }
 
+type ActivityTagRenamePayload struct {
+	TagName    string `json:"tagName"`
+	NewTagName string `json:"newTagName"`
+	MemoIDList []int  `json:"memoIdList"`
+}
+
+type ActivityTagMergePayload struct {
+	TagNameList   []string `json:"tagNameList"`
+	TargetTagName string   `json:"targetTagName"`
+	MemoIDList    []int    `json:"memoIdList"`
+}
+
 type ActivityServerStartPayload struct {
 	ServerID string           `json:"serverId"`
 	Profile  *profile.Profile `json:"profile"`
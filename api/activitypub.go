@@ -0,0 +1,49 @@
+package api
+
+// UserKeyPair is the RSA key pair signing the ActivityPub requests of a user, encoded in PEM.
+type UserKeyPair struct {
+	UserID    int   `json:"userId"`
+	CreatedTs int64 `json:"createdTs"`
+
+	PublicKey  string `json:"publicKey"`
+	PrivateKey string `json:"-"`
+}
+
+type UserKeyPairCreate struct {
+	UserID     int
+	PublicKey  string
+	PrivateKey string
+}
+
+type UserKeyPairFind struct {
+	UserID int
+}
+
+// ActivityPubFollower is a remote actor following a user through ActivityPub.
+type ActivityPubFollower struct {
+	ID int `json:"id"`
+
+	// Standard fields
+	UserID    int   `json:"userId"`
+	CreatedTs int64 `json:"createdTs"`
+
+	// Domain specific fields
+	Actor string `json:"actor"`
+	Inbox string `json:"inbox"`
+}
+
+type ActivityPubFollowerUpsert struct {
+	UserID int
+	Actor  string
+	Inbox  string
+}
+
+type ActivityPubFollowerFind struct {
+	UserID *int
+	Actor  *string
+}
+
+type ActivityPubFollowerDelete struct {
+	UserID int
+	Actor  string
+}
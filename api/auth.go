@@ -9,3 +9,15 @@ type SignUp struct {
 	Name string `json:"name"`
 	Pass string `json:"pass"`
 }
+
+type SSOSignIn struct {
+	IdentityProviderID int `json:"identityProviderId"`
+	// Code and RedirectURI are used by OAuth2 and OIDC identity providers.
+	Code        string `json:"code"`
+	RedirectURI string `json:"redirectUri"`
+	// State is returned by the OIDC authorize endpoint and echoed back by the provider.
+	State string `json:"state"`
+	// Name and Pass are used by LDAP identity providers.
+	Name string `json:"name"`
+	Pass string `json:"pass"`
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// maxCollectionNameLength is the max length of a collection name.
+	maxCollectionNameLength = 256
+	// MaxCollectionMemoCount is the max number of memos in a collection.
+	MaxCollectionMemoCount = 1000
+)
+
+// Collection is a named and ordered group of memos, like a notebook.
+type Collection struct {
+	ID int `json:"id"`
+
+	// Standard fields
+	CreatorID int   `json:"creatorId"`
+	CreatedTs int64 `json:"createdTs"`
+	UpdatedTs int64 `json:"updatedTs"`
+
+	// Domain specific fields
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Visibility is who can see the collection, the memos of the collection are still only visible by their own visibility.
+	Visibility Visibility `json:"visibility"`
+	// MemoIDList is the memos of the collection in order.
+	MemoIDList []int `json:"memoIdList"`
+}
+
+type CollectionCreate struct {
+	// Standard fields
+	CreatorID int `json:"-"`
+
+	// Domain specific fields
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Visibility  Visibility `json:"visibility"`
+	MemoIDList  []int      `json:"memoIdList"`
+}
+
+func (create CollectionCreate) Validate() error {
+	if err := validateCollectionName(create.Name); err != nil {
+		return err
+	}
+	if err := validateCollectionVisibility(create.Visibility); err != nil {
+		return err
+	}
+	return validateCollectionMemoIDList(create.MemoIDList)
+}
+
+type CollectionPatch struct {
+	ID int `json:"-"`
+
+	// Standard fields
+	UpdatedTs *int64
+
+	// Domain specific fields
+	Name        *string     `json:"name"`
+	Description *string     `json:"description"`
+	Visibility  *Visibility `json:"visibility"`
+	// MemoIDList replaces the memos of the collection in order.
+	MemoIDList []int `json:"memoIdList"`
+}
+
+func (patch CollectionPatch) Validate() error {
+	if patch.Name != nil {
+		if err := validateCollectionName(*patch.Name); err != nil {
+			return err
+		}
+	}
+	if patch.Visibility != nil {
+		if err := validateCollectionVisibility(*patch.Visibility); err != nil {
+			return err
+		}
+	}
+	if patch.MemoIDList != nil {
+		return validateCollectionMemoIDList(patch.MemoIDList)
+	}
+	return nil
+}
+
+type CollectionFind struct {
+	ID *int
+
+	// Standard fields
+	CreatorID *int
+
+	// Domain specific fields
+	VisibilityList []Visibility
+	// MemoID finds the collections containing the memo.
+	MemoID *int
+}
+
+type CollectionDelete struct {
+	ID int
+}
+
+// CollectionMemoUpsert adds a memo to a collection, at the end of the collection without a position.
+type CollectionMemoUpsert struct {
+	CollectionID int `json:"-"`
+
+	MemoID int `json:"memoId"`
+	// Position is the 0-based index of the memo in the collection.
+	Position *int `json:"position"`
+}
+
+type CollectionMemoDelete struct {
+	CollectionID int
+	MemoID       int
+}
+
+// CollectionPage is a collection with the memos that the viewer can see, in the order of the collection.
+type CollectionPage struct {
+	Collection *Collection `json:"collection"`
+	MemoList   []*Memo     `json:"memoList"`
+}
+
+func validateCollectionName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("collection name shouldn't be empty")
+	}
+	if len(name) > maxCollectionNameLength {
+		return fmt.Errorf("collection name is too long, maximum length is %d", maxCollectionNameLength)
+	}
+	return nil
+}
+
+// validateCollectionVisibility allows the visibilities without a user group.
+func validateCollectionVisibility(visibility Visibility) error {
+	switch visibility {
+	case Public, Protected, Private:
+		return nil
+	}
+	return fmt.Errorf("invalid collection visibility: %s", visibility)
+}
+
+func validateCollectionMemoIDList(memoIDList []int) error {
+	if len(memoIDList) > MaxCollectionMemoCount {
+		return fmt.Errorf("too many memos, up to %d memos in a collection", MaxCollectionMemoCount)
+	}
+	for i, id := range memoIDList {
+		for _, other := range memoIDList[:i] {
+			if id == other {
+				return fmt.Errorf("duplicated memo ID: %d", id)
+			}
+		}
+	}
+	return nil
+}
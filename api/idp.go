@@ -0,0 +1,92 @@
+package api
+
+type IdentityProviderType string
+
+const (
+	IdentityProviderOAuth2 IdentityProviderType = "OAUTH2"
+	IdentityProviderLDAP   IdentityProviderType = "LDAP"
+	IdentityProviderOIDC   IdentityProviderType = "OIDC"
+)
+
+func (t IdentityProviderType) String() string {
+	switch t {
+	case IdentityProviderOAuth2:
+		return "OAUTH2"
+	case IdentityProviderLDAP:
+		return "LDAP"
+	case IdentityProviderOIDC:
+		return "OIDC"
+	}
+	return ""
+}
+
+type IdentityProviderConfig struct {
+	OAuth2Config *IdentityProviderOAuth2Config `json:"oauth2Config"`
+	LDAPConfig   *IdentityProviderLDAPConfig   `json:"ldapConfig"`
+	OIDCConfig   *IdentityProviderOIDCConfig   `json:"oidcConfig"`
+}
+
+type IdentityProviderOAuth2Config struct {
+	ClientID     string        `json:"clientId"`
+	ClientSecret string        `json:"clientSecret"`
+	AuthURL      string        `json:"authUrl"`
+	TokenURL     string        `json:"tokenUrl"`
+	UserInfoURL  string        `json:"userInfoUrl"`
+	Scopes       []string      `json:"scopes"`
+	FieldMapping *FieldMapping `json:"fieldMapping"`
+}
+
+type IdentityProviderLDAPConfig struct {
+	URL                string        `json:"url"`
+	StartTLS           bool          `json:"startTls"`
+	InsecureSkipVerify bool          `json:"insecureSkipVerify"`
+	BindDN             string        `json:"bindDn"`
+	BindPassword       string        `json:"bindPassword"`
+	BaseDN             string        `json:"baseDn"`
+	UserFilter         string        `json:"userFilter"`
+	FieldMapping       *FieldMapping `json:"fieldMapping"`
+}
+
+type IdentityProviderOIDCConfig struct {
+	Issuer       string        `json:"issuer"`
+	ClientID     string        `json:"clientId"`
+	ClientSecret string        `json:"clientSecret"`
+	Scopes       []string      `json:"scopes"`
+	FieldMapping *FieldMapping `json:"fieldMapping"`
+}
+
+// IdentityProviderAuthorization is the starting point of an OpenID Connect sign-in.
+// The client redirects to AuthURL and passes State back to the SSO sign-in endpoint.
+type IdentityProviderAuthorization struct {
+	AuthURL string `json:"authUrl"`
+	State   string `json:"state"`
+}
+
+type FieldMapping struct {
+	Identifier  string `json:"identifier"`
+	DisplayName string `json:"displayName"`
+	Email       string `json:"email"`
+}
+
+type IdentityProvider struct {
+	ID               int                     `json:"id"`
+	Name             string                  `json:"name"`
+	Type             IdentityProviderType    `json:"type"`
+	IdentifierFilter string                  `json:"identifierFilter"`
+	Config           *IdentityProviderConfig `json:"config"`
+}
+
+type IdentityProviderCreate struct {
+	Name             string                  `json:"name"`
+	Type             IdentityProviderType    `json:"type"`
+	IdentifierFilter string                  `json:"identifierFilter"`
+	Config           *IdentityProviderConfig `json:"config"`
+}
+
+type IdentityProviderPatch struct {
+	ID               int                     `json:"-"`
+	Type             IdentityProviderType    `json:"type"`
+	Name             *string                 `json:"name"`
+	IdentifierFilter *string                 `json:"identifierFilter"`
+	Config           *IdentityProviderConfig `json:"config"`
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxPlaceNameLength is the max length of a place name.
+const maxPlaceNameLength = 256
+
+// Location is a geographic location in WGS 84 decimal degrees, south and west are negative.
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	PlaceName string  `json:"placeName"`
+}
+
+func (location Location) Validate() error {
+	if location.Latitude < -90 || location.Latitude > 90 {
+		return fmt.Errorf("invalid latitude: %v", location.Latitude)
+	}
+	if location.Longitude < -180 || location.Longitude > 180 {
+		return fmt.Errorf("invalid longitude: %v", location.Longitude)
+	}
+	if len(location.PlaceName) > maxPlaceNameLength {
+		return fmt.Errorf("place name is too long, maximum length is %d", maxPlaceNameLength)
+	}
+	return nil
+}
+
+// BoundingBox is an area between two longitudes and two latitudes.
+// The box crosses the antimeridian when MinLongitude is greater than MaxLongitude.
+type BoundingBox struct {
+	MinLongitude float64
+	MinLatitude  float64
+	MaxLongitude float64
+	MaxLatitude  float64
+}
+
+// ParseBoundingBox parses a bounding box in the GeoJSON order, e.g. "minLongitude,minLatitude,maxLongitude,maxLatitude".
+func ParseBoundingBox(s string) (*BoundingBox, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) != 4 {
+		return nil, fmt.Errorf("invalid bounding box: %s", s)
+	}
+	values := [4]float64{}
+	for i, field := range fields {
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bounding box: %s", s)
+		}
+		values[i] = value
+	}
+	box := &BoundingBox{
+		MinLongitude: values[0],
+		MinLatitude:  values[1],
+		MaxLongitude: values[2],
+		MaxLatitude:  values[3],
+	}
+	if err := (Location{Latitude: box.MinLatitude, Longitude: box.MinLongitude}).Validate(); err != nil {
+		return nil, fmt.Errorf("invalid bounding box: %s", s)
+	}
+	if err := (Location{Latitude: box.MaxLatitude, Longitude: box.MaxLongitude}).Validate(); err != nil {
+		return nil, fmt.Errorf("invalid bounding box: %s", s)
+	}
+	if box.MinLatitude > box.MaxLatitude {
+		return nil, fmt.Errorf("invalid bounding box: %s", s)
+	}
+	return box, nil
+}
+
+// GeoJSONFeatureCollection is a GeoJSON FeatureCollection object, see RFC 7946.
+type GeoJSONFeatureCollection struct {
+	Type     string            `json:"type"`
+	Features []*GeoJSONFeature `json:"features"`
+}
+
+// GeoJSONFeature is a GeoJSON Feature object with a point geometry.
+type GeoJSONFeature struct {
+	Type       string           `json:"type"`
+	ID         int              `json:"id"`
+	Geometry   *GeoJSONGeometry `json:"geometry"`
+	Properties map[string]any   `json:"properties"`
+}
+
+// GeoJSONGeometry is a GeoJSON Point object, the coordinates are the longitude and the latitude.
+type GeoJSONGeometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+// NewMemoGeoJSON creates a feature collection of the memos with a location, the memos without one are skipped.
+func NewMemoGeoJSON(memoList []*Memo) *GeoJSONFeatureCollection {
+	collection := &GeoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []*GeoJSONFeature{},
+	}
+	for _, memo := range memoList {
+		if memo.Location == nil {
+			continue
+		}
+		collection.Features = append(collection.Features, &GeoJSONFeature{
+			Type: "Feature",
+			ID:   memo.ID,
+			Geometry: &GeoJSONGeometry{
+				Type:        "Point",
+				Coordinates: []float64{memo.Location.Longitude, memo.Location.Latitude},
+			},
+			Properties: map[string]any{
+				"placeName":  memo.Location.PlaceName,
+				"content":    memo.Content,
+				"visibility": memo.Visibility,
+				"createdTs":  memo.CreatedTs,
+				"updatedTs":  memo.UpdatedTs,
+			},
+		})
+	}
+	return collection
+}
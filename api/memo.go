@@ -1,5 +1,12 @@
 package api
 
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // MaxContentLength means the max memo content bytes is 1MB.
 const MaxContentLength = 1 << 30
 
@@ -13,6 +20,8 @@ const (
 	Protected Visibility = "PROTECTED"
 	// Private is the PRIVATE visibility.
 	Private Visibility = "PRIVATE"
+	// Group is the GROUP visibility, the memo is visible to the members of its user group.
+	Group Visibility = "GROUP"
 )
 
 func (e Visibility) String() string {
@@ -23,6 +32,8 @@ func (e Visibility) String() string {
 		return "PROTECTED"
 	case Private:
 		return "PRIVATE"
+	case Group:
+		return "GROUP"
 	}
 	return "PRIVATE"
 }
@@ -35,15 +46,40 @@ type Memo struct {
 	CreatorID int       `json:"creatorId"`
 	CreatedTs int64     `json:"createdTs"`
 	UpdatedTs int64     `json:"updatedTs"`
+	// Version is increased by every change of the memo, the ETag of a memo is its quoted version.
+	Version int `json:"version"`
 
 	// Domain specific fields
 	Content    string     `json:"content"`
 	Visibility Visibility `json:"visibility"`
-	Pinned     bool       `json:"pinned"`
+	// GroupID is the user group that can see a GROUP visibility memo.
+	GroupID int `json:"groupId"`
+	// ParentID is the memo commented by this memo, 0 for a top-level memo.
+	ParentID int `json:"parentId"`
+	// Location is where the memo is written, nil for a memo without a location.
+	Location *Location `json:"location"`
 
 	// Related fields
 	CreatorName  string      `json:"creatorName"`
 	ResourceList []*Resource `json:"resourceList"`
+	CommentCount int         `json:"commentCount"`
+	// ReactionList is composed for the viewer carried by the context.
+	ReactionList []*MemoReactionSummary `json:"reactionList"`
+	// The organizer fields are composed for the viewer carried by the context, or for the creator without a viewer.
+	Pinned         bool `json:"pinned"`
+	PinnedPosition int  `json:"pinnedPosition"`
+	Bookmarked     bool `json:"bookmarked"`
+	ReadLater      bool `json:"readLater"`
+	// EmbedList and ResourceEmbedList are the embeds of the content resolved for the viewer, nil for an embedded memo.
+	EmbedList         []*MemoEmbed     `json:"embedList"`
+	ResourceEmbedList []*ResourceEmbed `json:"resourceEmbedList"`
+}
+
+// MemoHTML is the memo content rendered into sanitized HTML.
+type MemoHTML struct {
+	MemoID    int    `json:"memoId"`
+	UpdatedTs int64  `json:"updatedTs"`
+	HTML      string `json:"html"`
 }
 
 type MemoCreate struct {
@@ -53,7 +89,13 @@ type MemoCreate struct {
 
 	// Domain specific fields
 	Visibility Visibility `json:"visibility"`
+	GroupID    int        `json:"groupId"`
 	Content    string     `json:"content"`
+	ParentID   int        `json:"-"`
+	// TemplateID is the template the content starts with, the content of the request follows the expanded template.
+	TemplateID *int `json:"templateId"`
+	// Location is taken from the first geotagged resource of the memo when not set.
+	Location *Location `json:"location"`
 
 	// Related fields
 	ResourceIDList []int `json:"resourceIdList"`
@@ -70,9 +112,18 @@ type MemoPatch struct {
 	// Domain specific fields
 	Content    *string     `json:"content"`
 	Visibility *Visibility `json:"visibility"`
+	GroupID    *int        `json:"groupId"`
+	Location   *Location   `json:"location"`
+	// ClearLocation removes the location of the memo.
+	ClearLocation bool `json:"clearLocation"`
 
 	// Related fields
 	ResourceIDList []int `json:"resourceIdList"`
+
+	// ExpectedVersion and ExpectedUpdatedTs are the preconditions of the patch,
+	// the patch fails with a conflict when the memo has been changed since.
+	ExpectedVersion   *int   `json:"expectedVersion"`
+	ExpectedUpdatedTs *int64 `json:"expectedUpdatedTs"`
 }
 
 type MemoFind struct {
@@ -83,15 +134,149 @@ type MemoFind struct {
 	CreatorID *int
 
 	// Domain specific fields
-	Pinned         *bool
-	ContentSearch  *string
+	// Pinned, Bookmarked and ReadLater find by the organizer of the viewer, or of the creator without a viewer.
+	Pinned        *bool
+	Bookmarked    *bool
+	ReadLater     *bool
+	ContentSearch *string
+	// Tag finds the memos with the tag or one of its nested tags.
+	Tag            *string
 	VisibilityList []Visibility
+	// ViewerID is required to find GROUP visibility memos, only those of the viewer's groups are found.
+	ViewerID *int
+	// IncludeViewerMemos finds all the memos of the viewer besides those of VisibilityList.
+	IncludeViewerMemos bool
+	// ParentID finds the comments of a memo, only top-level memos are found when both ParentID and ID are nil.
+	ParentID *int
+	// TimelineUserID finds the memos of the user and of the users followed by the user.
+	// VisibilityList only applies to the memos of the followed users.
+	TimelineUserID *int
+	// Filter is a parsed filter expression, see ParseMemoFilter.
+	Filter *MemoFilter
+	// HasLocation finds the memos with or without a location.
+	HasLocation *bool
+	// BoundingBox finds the memos located in the box.
+	BoundingBox *BoundingBox
+
+	// Sort is the time the memos are ordered by, newest first, the created time by default.
+	Sort MemoSort
+	// PinnedFirst orders the pinned memos before the others, it is true by default.
+	PinnedFirst *bool
 
 	// Pagination
 	Limit  *int
 	Offset *int
+	// Cursor finds the memos after the cursor in the order of the list, it must be built with the same order.
+	Cursor *MemoCursor
 }
 
 type MemoDelete struct {
 	ID int
 }
+
+// MemoSort is the time memo lists are ordered by.
+type MemoSort string
+
+const (
+	// MemoSortCreated orders memos by their created time.
+	MemoSortCreated MemoSort = "created"
+	// MemoSortUpdated orders memos by their updated time.
+	MemoSortUpdated MemoSort = "updated"
+)
+
+func (e MemoSort) String() string {
+	switch e {
+	case MemoSortCreated:
+		return "created"
+	case MemoSortUpdated:
+		return "updated"
+	}
+	return "created"
+}
+
+// MemoCursor is the position of a memo in an ordered list, the ID breaks ties.
+type MemoCursor struct {
+	Sort MemoSort
+	// Pinned is nil when the list does not order pinned memos first.
+	Pinned *bool
+	// PinnedPosition orders the pinned memos, 0 for an unpinned memo.
+	PinnedPosition int
+	// Ts is the created or updated time of the memo, by the sort of the list.
+	Ts int64
+	ID int
+}
+
+// NewMemoCursor returns the cursor of the memo in a list of the sort.
+func NewMemoCursor(memo *Memo, sort MemoSort, pinnedFirst bool) *MemoCursor {
+	cursor := &MemoCursor{
+		Sort: sort,
+		Ts:   memo.CreatedTs,
+		ID:   memo.ID,
+	}
+	if sort == MemoSortUpdated {
+		cursor.Ts = memo.UpdatedTs
+	}
+	if pinnedFirst {
+		pinned := memo.Pinned
+		cursor.Pinned = &pinned
+		cursor.PinnedPosition = memo.PinnedPosition
+	}
+	return cursor
+}
+
+// String encodes the cursor as an opaque token for clients.
+func (cursor MemoCursor) String() string {
+	pinned := "-"
+	if cursor.Pinned != nil {
+		pinned = "0"
+		if *cursor.Pinned {
+			pinned = "1"
+		}
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s:%d:%d:%d", cursor.Sort.String(), pinned, cursor.PinnedPosition, cursor.Ts, cursor.ID)))
+}
+
+// ParseMemoCursor decodes a cursor token returned by MemoCursor.String.
+func ParseMemoCursor(token string) (*MemoCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %s", token)
+	}
+	fields := strings.Split(string(data), ":")
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cursor: %s", token)
+	}
+	cursor := &MemoCursor{
+		Sort: MemoSort(fields[0]),
+	}
+	if cursor.Sort != MemoSortCreated && cursor.Sort != MemoSortUpdated {
+		return nil, fmt.Errorf("invalid cursor: %s", token)
+	}
+	switch fields[1] {
+	case "-":
+	case "0", "1":
+		pinned := fields[1] == "1"
+		cursor.Pinned = &pinned
+	default:
+		return nil, fmt.Errorf("invalid cursor: %s", token)
+	}
+	if cursor.PinnedPosition, err = strconv.Atoi(fields[2]); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %s", token)
+	}
+	if cursor.Ts, err = strconv.ParseInt(fields[3], 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %s", token)
+	}
+	if cursor.ID, err = strconv.Atoi(fields[4]); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %s", token)
+	}
+	return cursor, nil
+}
+
+// MemoPage is a page of memos, NextCursor is empty on the last page.
+// Total is the number of memos of all the pages.
+// The memo list endpoints respond with a MemoPage instead of a bare list of memos.
+type MemoPage struct {
+	MemoList   []*Memo `json:"memoList"`
+	NextCursor string  `json:"nextCursor"`
+	Total      int     `json:"total"`
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"fmt"
+
+	"golang.org/x/exp/slices"
+)
+
+// maxMemoBatchSize is the max number of memo IDs in a batch request.
+const maxMemoBatchSize = 1000
+
+// MemoBatchOperation is the operation applied to every memo of a batch.
+type MemoBatchOperation string
+
+const (
+	MemoBatchArchive    MemoBatchOperation = "ARCHIVE"
+	MemoBatchRestore    MemoBatchOperation = "RESTORE"
+	MemoBatchVisibility MemoBatchOperation = "VISIBILITY"
+	MemoBatchPin        MemoBatchOperation = "PIN"
+	MemoBatchUnpin      MemoBatchOperation = "UNPIN"
+	MemoBatchDelete     MemoBatchOperation = "DELETE"
+)
+
+// MemoBatch applies an operation to the memos of the ID list, or to the top-level memos matching the filter.
+type MemoBatch struct {
+	// Standard fields
+	CreatorID int   `json:"-"`
+	UpdatedTs int64 `json:"-"`
+
+	// Domain specific fields
+	IDList []int `json:"idList"`
+	// Filter is a filter expression, see ParseMemoFilter. ParsedFilter is set by the service.
+	Filter       string             `json:"filter"`
+	ParsedFilter *MemoFilter        `json:"-"`
+	Operation    MemoBatchOperation `json:"operation"`
+	// Visibility and GroupID are the new visibility of the VISIBILITY operation.
+	Visibility Visibility `json:"visibility"`
+	GroupID    int        `json:"groupId"`
+}
+
+// MemoBatchResult is the memos changed by a batch.
+type MemoBatchResult struct {
+	Operation  MemoBatchOperation `json:"operation"`
+	MemoIDList []int              `json:"memoIdList"`
+}
+
+func (batch MemoBatch) Validate() error {
+	if len(batch.IDList) == 0 && batch.Filter == "" {
+		return fmt.Errorf("either the memo ID list or the filter is required")
+	}
+	if len(batch.IDList) != 0 && batch.Filter != "" {
+		return fmt.Errorf("the memo ID list and the filter can't be used together")
+	}
+	if len(batch.IDList) > maxMemoBatchSize {
+		return fmt.Errorf("too many memos, up to %d", maxMemoBatchSize)
+	}
+	switch batch.Operation {
+	case MemoBatchArchive, MemoBatchRestore, MemoBatchPin, MemoBatchUnpin, MemoBatchDelete:
+	case MemoBatchVisibility:
+		if !slices.Contains([]Visibility{Public, Protected, Private, Group}, batch.Visibility) {
+			return fmt.Errorf("invalid visibility: %s", batch.Visibility)
+		}
+	default:
+		return fmt.Errorf("invalid operation: %s", batch.Operation)
+	}
+	if batch.Filter != "" {
+		if _, err := ParseMemoFilter(batch.Filter); err != nil {
+			return fmt.Errorf("invalid filter: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package api
+
+// MemoEmbed is a memo embedded in the content of another memo, e.g. ![[memo:1]].
+type MemoEmbed struct {
+	MemoID int `json:"memoId"`
+	// Memo is nil when the memo is not found or the viewer can't see it, the two are not told apart.
+	Memo *Memo `json:"memo"`
+}
+
+// ResourceEmbed is a resource embedded in the content of a memo, e.g. ![[resource:1]].
+type ResourceEmbed struct {
+	ResourceID int `json:"resourceId"`
+	// Resource is nil when the resource is not found or is not one of the memo creator's resources.
+	Resource *Resource `json:"resource"`
+}
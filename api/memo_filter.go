@@ -0,0 +1,366 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// maxMemoFilterLength is the max bytes of a filter expression.
+	maxMemoFilterLength = 1024
+	// maxMemoFilterDepth is the max nesting of a filter expression.
+	maxMemoFilterDepth = 16
+)
+
+// MemoFilterOperator combines the children of a filter.
+type MemoFilterOperator string
+
+const (
+	MemoFilterAnd MemoFilterOperator = "AND"
+	MemoFilterOr  MemoFilterOperator = "OR"
+	MemoFilterNot MemoFilterOperator = "NOT"
+)
+
+// MemoFilterKey is the field a filter condition applies to.
+type MemoFilterKey string
+
+const (
+	// MemoFilterText finds the memos containing the text.
+	MemoFilterText MemoFilterKey = "text"
+	// MemoFilterTag finds the memos with the tag or one of its child tags.
+	MemoFilterTag MemoFilterKey = "tag"
+	// MemoFilterCreated finds the memos created in the time range.
+	MemoFilterCreated MemoFilterKey = "created"
+	// MemoFilterUpdated finds the memos updated in the time range.
+	MemoFilterUpdated MemoFilterKey = "updated"
+	// MemoFilterVisibility finds the memos of one of the visibilities.
+	MemoFilterVisibility MemoFilterKey = "visibility"
+	// MemoFilterHas finds the memos having resources or links.
+	MemoFilterHas MemoFilterKey = "has"
+	// MemoFilterPinned finds the pinned or the unpinned memos.
+	MemoFilterPinned MemoFilterKey = "pinned"
+)
+
+const (
+	MemoFilterHasResource = "resource"
+	MemoFilterHasLink     = "link"
+)
+
+// MemoFilter is a node of a parsed filter expression.
+// An operator node combines its children, a condition node has a key and the parsed value of the key.
+type MemoFilter struct {
+	Operator MemoFilterOperator `json:"operator,omitempty"`
+	Children []*MemoFilter      `json:"children,omitempty"`
+
+	Key MemoFilterKey `json:"key,omitempty"`
+	// Value is the text, the tag name or the thing the memos have.
+	Value          string       `json:"value,omitempty"`
+	VisibilityList []Visibility `json:"visibilityList,omitempty"`
+	Pinned         bool         `json:"pinned,omitempty"`
+	// FromTs and ToTs bound the created or updated time, ToTs is exclusive and nil is unbounded.
+	FromTs *int64 `json:"fromTs,omitempty"`
+	ToTs   *int64 `json:"toTs,omitempty"`
+}
+
+// ParseMemoFilter parses a filter expression, e.g. `#work -has:resource (created:2023-01-01..2023-03-31 OR pinned:true)`.
+//
+// Conditions are bare words or "quoted phrases" matching the content, #tag, and key:value pairs:
+// tag:name, created:DATE, updated:DATE, visibility:public,protected, has:resource, has:link, pinned:true|false and text:word.
+// DATE is a YYYY-MM-DD day in UTC or a FROM..TO range of days, either end can be omitted.
+// Conditions are combined with AND (the default between conditions), OR, NOT or a leading "-", and grouped with parentheses.
+func ParseMemoFilter(expression string) (*MemoFilter, error) {
+	if len(expression) > maxMemoFilterLength {
+		return nil, fmt.Errorf("filter is longer than %d bytes", maxMemoFilterLength)
+	}
+	tokens, err := tokenizeMemoFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("filter is empty")
+	}
+	parser := &memoFilterParser{tokens: tokens}
+	filter, err := parser.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if parser.pos < len(parser.tokens) {
+		return nil, fmt.Errorf("unexpected %q in filter", parser.tokens[parser.pos].text)
+	}
+	return filter, nil
+}
+
+type memoFilterTokenType int
+
+const (
+	memoFilterTokenWord memoFilterTokenType = iota
+	memoFilterTokenPhrase
+	memoFilterTokenLeftParen
+	memoFilterTokenRightParen
+	memoFilterTokenMinus
+)
+
+type memoFilterToken struct {
+	tokenType memoFilterTokenType
+	text      string
+}
+
+func tokenizeMemoFilter(expression string) ([]memoFilterToken, error) {
+	tokens := []memoFilterToken{}
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, memoFilterToken{tokenType: memoFilterTokenLeftParen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, memoFilterToken{tokenType: memoFilterTokenRightParen, text: ")"})
+			i++
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]):
+			tokens = append(tokens, memoFilterToken{tokenType: memoFilterTokenMinus, text: "-"})
+			i++
+		case r == '"':
+			phrase, next, err := readMemoFilterQuoted(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, memoFilterToken{tokenType: memoFilterTokenPhrase, text: phrase})
+			i = next
+		default:
+			// A word runs until a space or a parenthesis, a quoted part is kept whole, e.g. tag:"a b".
+			var word strings.Builder
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+				if runes[i] == '"' {
+					quoted, next, err := readMemoFilterQuoted(runes, i)
+					if err != nil {
+						return nil, err
+					}
+					word.WriteString(quoted)
+					i = next
+					continue
+				}
+				word.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, memoFilterToken{tokenType: memoFilterTokenWord, text: word.String()})
+		}
+	}
+	return tokens, nil
+}
+
+// readMemoFilterQuoted reads the quoted string starting at runes[start], a backslash escapes the next rune.
+func readMemoFilterQuoted(runes []rune, start int) (string, int, error) {
+	var quoted strings.Builder
+	for i := start + 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				quoted.WriteRune(runes[i])
+			}
+		case '"':
+			return quoted.String(), i + 1, nil
+		default:
+			quoted.WriteRune(runes[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quote in filter")
+}
+
+type memoFilterParser struct {
+	tokens []memoFilterToken
+	pos    int
+}
+
+func (p *memoFilterParser) peek() *memoFilterToken {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.pos]
+}
+
+func (p *memoFilterParser) peekKeyword(keyword string) bool {
+	token := p.peek()
+	return token != nil && token.tokenType == memoFilterTokenWord && token.text == keyword
+}
+
+func (p *memoFilterParser) parseOr(depth int) (*MemoFilter, error) {
+	if depth > maxMemoFilterDepth {
+		return nil, fmt.Errorf("filter is nested deeper than %d", maxMemoFilterDepth)
+	}
+	filter, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	children := []*MemoFilter{filter}
+	for p.peekKeyword("OR") {
+		p.pos++
+		filter, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, filter)
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return &MemoFilter{Operator: MemoFilterOr, Children: children}, nil
+}
+
+func (p *memoFilterParser) parseAnd(depth int) (*MemoFilter, error) {
+	filter, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	children := []*MemoFilter{filter}
+	for {
+		token := p.peek()
+		if token == nil || token.tokenType == memoFilterTokenRightParen || p.peekKeyword("OR") {
+			break
+		}
+		if p.peekKeyword("AND") {
+			p.pos++
+		}
+		filter, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, filter)
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return &MemoFilter{Operator: MemoFilterAnd, Children: children}, nil
+}
+
+func (p *memoFilterParser) parseUnary(depth int) (*MemoFilter, error) {
+	token := p.peek()
+	if token == nil {
+		return nil, fmt.Errorf("unexpected end of filter")
+	}
+	if token.tokenType == memoFilterTokenMinus || p.peekKeyword("NOT") {
+		p.pos++
+		if depth+1 > maxMemoFilterDepth {
+			return nil, fmt.Errorf("filter is nested deeper than %d", maxMemoFilterDepth)
+		}
+		filter, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &MemoFilter{Operator: MemoFilterNot, Children: []*MemoFilter{filter}}, nil
+	}
+
+	p.pos++
+	switch token.tokenType {
+	case memoFilterTokenLeftParen:
+		filter, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if next := p.peek(); next == nil || next.tokenType != memoFilterTokenRightParen {
+			return nil, fmt.Errorf("missing ) in filter")
+		}
+		p.pos++
+		return filter, nil
+	case memoFilterTokenRightParen:
+		return nil, fmt.Errorf("unexpected ) in filter")
+	case memoFilterTokenPhrase:
+		return &MemoFilter{Key: MemoFilterText, Value: token.text}, nil
+	case memoFilterTokenWord:
+		if token.text == "AND" || token.text == "OR" {
+			return nil, fmt.Errorf("unexpected %s in filter", token.text)
+		}
+		return parseMemoFilterCondition(token.text)
+	}
+	return nil, fmt.Errorf("unexpected %q in filter", token.text)
+}
+
+func parseMemoFilterCondition(word string) (*MemoFilter, error) {
+	if strings.HasPrefix(word, "#") && len(word) > 1 {
+		return &MemoFilter{Key: MemoFilterTag, Value: strings.TrimPrefix(word, "#")}, nil
+	}
+	key, value, ok := strings.Cut(word, ":")
+	if !ok {
+		return &MemoFilter{Key: MemoFilterText, Value: word}, nil
+	}
+
+	filter := &MemoFilter{Key: MemoFilterKey(strings.ToLower(key))}
+	switch filter.Key {
+	case MemoFilterText:
+		filter.Value = value
+	case MemoFilterTag:
+		filter.Value = strings.TrimPrefix(value, "#")
+	case MemoFilterCreated, MemoFilterUpdated:
+		fromTs, toTs, err := parseMemoFilterDateRange(value)
+		if err != nil {
+			return nil, err
+		}
+		filter.FromTs, filter.ToTs = fromTs, toTs
+	case MemoFilterVisibility:
+		for _, v := range strings.Split(value, ",") {
+			visibility := Visibility(strings.ToUpper(strings.TrimSpace(v)))
+			if visibility != Public && visibility != Protected && visibility != Private && visibility != Group {
+				return nil, fmt.Errorf("invalid visibility in filter: %s", v)
+			}
+			filter.VisibilityList = append(filter.VisibilityList, visibility)
+		}
+	case MemoFilterHas:
+		filter.Value = strings.ToLower(value)
+		if filter.Value != MemoFilterHasResource && filter.Value != MemoFilterHasLink {
+			return nil, fmt.Errorf("invalid has in filter: %s", value)
+		}
+	case MemoFilterPinned:
+		switch strings.ToLower(value) {
+		case "true":
+			filter.Pinned = true
+		case "false":
+			filter.Pinned = false
+		default:
+			return nil, fmt.Errorf("invalid pinned in filter: %s", value)
+		}
+	default:
+		// Words with colons such as URLs are searched as text.
+		return &MemoFilter{Key: MemoFilterText, Value: word}, nil
+	}
+	if filter.Value == "" && (filter.Key == MemoFilterText || filter.Key == MemoFilterTag) {
+		return nil, fmt.Errorf("empty %s in filter", filter.Key)
+	}
+	return filter, nil
+}
+
+// parseMemoFilterDateRange parses a day or a FROM..TO range of days into a time range, the end day is included.
+func parseMemoFilterDateRange(value string) (*int64, *int64, error) {
+	from, to, isRange := strings.Cut(value, "..")
+	if !isRange {
+		to = from
+	}
+	if from == "" && to == "" {
+		return nil, nil, fmt.Errorf("invalid date range in filter: %s", value)
+	}
+	var fromTs, toTs *int64
+	if from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid date in filter: %s", from)
+		}
+		ts := t.Unix()
+		fromTs = &ts
+	}
+	if to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid date in filter: %s", to)
+		}
+		ts := t.AddDate(0, 0, 1).Unix()
+		toTs = &ts
+	}
+	if fromTs != nil && toTs != nil && *fromTs >= *toTs {
+		return nil, nil, fmt.Errorf("invalid date range in filter: %s", value)
+	}
+	return fromTs, toTs, nil
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMemoFilter(t *testing.T) {
+	day := func(year int, month time.Month, d int) *int64 {
+		ts := time.Date(year, month, d, 0, 0, 0, 0, time.UTC).Unix()
+		return &ts
+	}
+	text := func(value string) *MemoFilter {
+		return &MemoFilter{Key: MemoFilterText, Value: value}
+	}
+
+	tests := []struct {
+		expression string
+		want       *MemoFilter
+	}{
+		{
+			expression: "a OR b c",
+			want: &MemoFilter{Operator: MemoFilterOr, Children: []*MemoFilter{
+				text("a"),
+				{Operator: MemoFilterAnd, Children: []*MemoFilter{text("b"), text("c")}},
+			}},
+		},
+		{
+			expression: "a AND b OR c",
+			want: &MemoFilter{Operator: MemoFilterOr, Children: []*MemoFilter{
+				{Operator: MemoFilterAnd, Children: []*MemoFilter{text("a"), text("b")}},
+				text("c"),
+			}},
+		},
+		{
+			expression: "(a OR b) c",
+			want: &MemoFilter{Operator: MemoFilterAnd, Children: []*MemoFilter{
+				{Operator: MemoFilterOr, Children: []*MemoFilter{text("a"), text("b")}},
+				text("c"),
+			}},
+		},
+		{
+			expression: "-a b",
+			want: &MemoFilter{Operator: MemoFilterAnd, Children: []*MemoFilter{
+				{Operator: MemoFilterNot, Children: []*MemoFilter{text("a")}},
+				text("b"),
+			}},
+		},
+		{
+			expression: "-(a OR b)",
+			want: &MemoFilter{Operator: MemoFilterNot, Children: []*MemoFilter{
+				{Operator: MemoFilterOr, Children: []*MemoFilter{text("a"), text("b")}},
+			}},
+		},
+		{
+			expression: "NOT -#work",
+			want: &MemoFilter{Operator: MemoFilterNot, Children: []*MemoFilter{
+				{Operator: MemoFilterNot, Children: []*MemoFilter{{Key: MemoFilterTag, Value: "work"}}},
+			}},
+		},
+		{
+			// A minus followed by a space is a word.
+			expression: "a - b",
+			want:       &MemoFilter{Operator: MemoFilterAnd, Children: []*MemoFilter{text("a"), text("-"), text("b")}},
+		},
+		{
+			expression: `"hello \"world\"" tag:"a b"`,
+			want: &MemoFilter{Operator: MemoFilterAnd, Children: []*MemoFilter{
+				text(`hello "world"`),
+				{Key: MemoFilterTag, Value: "a b"},
+			}},
+		},
+		{
+			expression: "https://example.com",
+			want:       text("https://example.com"),
+		},
+		{
+			expression: "visibility:public,Private has:LINK pinned:false",
+			want: &MemoFilter{Operator: MemoFilterAnd, Children: []*MemoFilter{
+				{Key: MemoFilterVisibility, VisibilityList: []Visibility{Public, Private}},
+				{Key: MemoFilterHas, Value: MemoFilterHasLink},
+				{Key: MemoFilterPinned, Pinned: false},
+			}},
+		},
+		{
+			expression: "created:2023-01-01",
+			want:       &MemoFilter{Key: MemoFilterCreated, FromTs: day(2023, 1, 1), ToTs: day(2023, 1, 2)},
+		},
+		{
+			expression: "updated:2023-01-01..2023-03-31",
+			want:       &MemoFilter{Key: MemoFilterUpdated, FromTs: day(2023, 1, 1), ToTs: day(2023, 4, 1)},
+		},
+		{
+			expression: "created:..2023-01-31",
+			want:       &MemoFilter{Key: MemoFilterCreated, ToTs: day(2023, 2, 1)},
+		},
+		{
+			expression: "created:2023-01-31..",
+			want:       &MemoFilter{Key: MemoFilterCreated, FromTs: day(2023, 1, 31)},
+		},
+		{
+			// The Unix epoch is a bound like any other day.
+			expression: "created:1970-01-01..",
+			want:       &MemoFilter{Key: MemoFilterCreated, FromTs: day(1970, 1, 1)},
+		},
+		{
+			expression: "created:..1969-12-31",
+			want:       &MemoFilter{Key: MemoFilterCreated, ToTs: day(1970, 1, 1)},
+		},
+		{
+			expression: "created:1969-12-31",
+			want:       &MemoFilter{Key: MemoFilterCreated, FromTs: day(1969, 12, 31), ToTs: day(1970, 1, 1)},
+		},
+		{
+			expression: strings.Repeat("(", maxMemoFilterDepth) + "a" + strings.Repeat(")", maxMemoFilterDepth),
+			want:       text("a"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			filter, err := ParseMemoFilter(test.expression)
+			require.NoError(t, err)
+			require.Equal(t, test.want, filter)
+		})
+	}
+}
+
+func TestParseMemoFilterError(t *testing.T) {
+	tests := []struct {
+		expression string
+		err        string
+	}{
+		{expression: "", err: "empty"},
+		{expression: "   ", err: "empty"},
+		{expression: strings.Repeat("a", maxMemoFilterLength+1), err: "longer"},
+		{expression: `"hello`, err: "unterminated quote"},
+		{expression: `tag:"a b`, err: "unterminated quote"},
+		{expression: `"a\"`, err: "unterminated quote"},
+		{expression: "(a", err: "missing )"},
+		{expression: "((a) OR b", err: "missing )"},
+		{expression: "a)", err: "unexpected \")\""},
+		{expression: ")", err: "unexpected )"},
+		{expression: "()", err: "unexpected )"},
+		{expression: "a OR", err: "unexpected end"},
+		{expression: "OR a", err: "unexpected OR"},
+		{expression: "a AND", err: "unexpected end"},
+		{expression: "-NOT", err: "unexpected end"},
+		{expression: "NOT", err: "unexpected end"},
+		{expression: strings.Repeat("(", maxMemoFilterDepth+1) + "a" + strings.Repeat(")", maxMemoFilterDepth+1), err: "nested deeper"},
+		{expression: strings.Repeat("-", maxMemoFilterDepth+1) + "a", err: "nested deeper"},
+		{expression: "created:", err: "invalid date range"},
+		{expression: "created:..", err: "invalid date range"},
+		{expression: "created:2023-13-01", err: "invalid date"},
+		{expression: "created:2023-03-01..2023-01-01", err: "invalid date range"},
+		{expression: "visibility:secret", err: "invalid visibility"},
+		{expression: "has:video", err: "invalid has"},
+		{expression: "pinned:yes", err: "invalid pinned"},
+		{expression: "tag:", err: "empty tag"},
+		{expression: "text:", err: "empty text"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			_, err := ParseMemoFilter(test.expression)
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
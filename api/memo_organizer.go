@@ -1,5 +1,8 @@
 package api
 
+import "fmt"
+
+// MemoOrganizer is how a user organizes a memo, any memo the user can see.
 type MemoOrganizer struct {
 	ID int
 
@@ -7,12 +10,20 @@ type MemoOrganizer struct {
 	MemoID int
 	UserID int
 	Pinned bool
+	// PinnedPosition orders the pinned memos of the user, lower positions first, 0 for an unpinned memo.
+	PinnedPosition int
+	Bookmarked     bool
+	ReadLater      bool
 }
 
+// MemoOrganizerUpsert changes the organizer fields that are set, unpinning resets the pinned position.
 type MemoOrganizerUpsert struct {
-	MemoID int  `json:"-"`
-	UserID int  `json:"-"`
-	Pinned bool `json:"pinned"`
+	MemoID         int   `json:"-"`
+	UserID         int   `json:"-"`
+	Pinned         *bool `json:"pinned"`
+	PinnedPosition *int  `json:"pinnedPosition"`
+	Bookmarked     *bool `json:"bookmarked"`
+	ReadLater      *bool `json:"readLater"`
 }
 
 type MemoOrganizerFind struct {
@@ -24,3 +35,23 @@ type MemoOrganizerDelete struct {
 	MemoID *int
 	UserID *int
 }
+
+// MemoPinnedOrder sets the pinned positions of the user's pinned memos in the order of the list, starting from 1.
+type MemoPinnedOrder struct {
+	UserID     int   `json:"-"`
+	MemoIDList []int `json:"memoIdList"`
+}
+
+func (order MemoPinnedOrder) Validate() error {
+	if len(order.MemoIDList) == 0 {
+		return fmt.Errorf("memo ID list shouldn't be empty")
+	}
+	for i, id := range order.MemoIDList {
+		for _, other := range order.MemoIDList[:i] {
+			if id == other {
+				return fmt.Errorf("duplicated memo ID: %d", id)
+			}
+		}
+	}
+	return nil
+}
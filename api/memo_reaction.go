@@ -0,0 +1,63 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// MaxReactionLength is the max bytes of a reaction, long enough for emoji sequences.
+const MaxReactionLength = 32
+
+type MemoReaction struct {
+	ID int `json:"id"`
+
+	// Standard fields
+	CreatedTs int64 `json:"createdTs"`
+
+	// Domain specific fields
+	MemoID   int    `json:"memoId"`
+	UserID   int    `json:"userId"`
+	Reaction string `json:"reaction"`
+}
+
+// MemoReactionSummary is the aggregated count of a reaction on a memo.
+type MemoReactionSummary struct {
+	Reaction string `json:"reaction"`
+	Count    int    `json:"count"`
+	// ReactedByMe is true when the viewer has given the reaction.
+	ReactedByMe bool `json:"reactedByMe"`
+}
+
+type MemoReactionUpsert struct {
+	MemoID   int    `json:"-"`
+	UserID   int    `json:"-"`
+	Reaction string `json:"reaction"`
+}
+
+type MemoReactionFind struct {
+	MemoID   *int
+	UserID   *int
+	Reaction *string
+}
+
+type MemoReactionDelete struct {
+	MemoID   int
+	UserID   int
+	Reaction string
+}
+
+func (upsert MemoReactionUpsert) Validate() error {
+	if upsert.Reaction == "" {
+		return fmt.Errorf("reaction shouldn't be empty")
+	}
+	if len(upsert.Reaction) > MaxReactionLength {
+		return fmt.Errorf("reaction is too long, maximum length is %d", MaxReactionLength)
+	}
+	if strings.IndexFunc(upsert.Reaction, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}) != -1 {
+		return fmt.Errorf("reaction shouldn't contain spaces")
+	}
+	return nil
+}
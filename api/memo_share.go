@@ -0,0 +1,63 @@
+package api
+
+import "fmt"
+
+type MemoShare struct {
+	ID int `json:"id"`
+
+	// Standard fields
+	CreatorID int   `json:"creatorId"`
+	CreatedTs int64 `json:"createdTs"`
+
+	// Domain specific fields
+	MemoID int    `json:"memoId"`
+	Token  string `json:"token"`
+	// ExpiredTs is the unix time the link stops working, 0 means never.
+	ExpiredTs    int64  `json:"expiredTs"`
+	PasswordHash string `json:"-"`
+	HasPassword  bool   `json:"hasPassword"`
+	// MaxViewCount limits how many times the link can be viewed, 0 means unlimited.
+	MaxViewCount int `json:"maxViewCount"`
+	ViewCount    int `json:"viewCount"`
+}
+
+type MemoShareCreate struct {
+	// Standard fields
+	CreatorID int `json:"-"`
+
+	// Domain specific fields
+	MemoID       int    `json:"-"`
+	Token        string `json:"-"`
+	ExpiredTs    int64  `json:"expiredTs"`
+	Password     string `json:"password"`
+	PasswordHash string `json:"-"`
+	MaxViewCount int    `json:"maxViewCount"`
+}
+
+type MemoShareFind struct {
+	ID *int
+
+	// Standard fields
+	CreatorID *int
+
+	// Domain specific fields
+	MemoID *int
+	Token  *string
+}
+
+type MemoShareDelete struct {
+	ID int
+}
+
+func (create MemoShareCreate) Validate(currentTs int64) error {
+	if create.ExpiredTs != 0 && create.ExpiredTs <= currentTs {
+		return fmt.Errorf("expiredTs should be in the future")
+	}
+	if create.MaxViewCount < 0 {
+		return fmt.Errorf("maxViewCount shouldn't be negative")
+	}
+	if len(create.Password) > 72 {
+		return fmt.Errorf("password is too long, maximum length is 72")
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package api
+
+// MemoStatsPeriod is the length of the buckets memos are counted in.
+type MemoStatsPeriod string
+
+const (
+	MemoStatsDay   MemoStatsPeriod = "day"
+	MemoStatsWeek  MemoStatsPeriod = "week"
+	MemoStatsMonth MemoStatsPeriod = "month"
+)
+
+// MemoStatsBucket is the number of memos created in a period.
+type MemoStatsBucket struct {
+	// Date is the first day of the period, formatted as 2006-01-02, or as 2006-01 for months. Weeks start on Monday.
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// MemoStats is the aggregates of the memos created by a user.
+type MemoStats struct {
+	// Timezone is the IANA time zone the days are counted in.
+	Timezone  string          `json:"timezone"`
+	Period    MemoStatsPeriod `json:"period"`
+	MemoCount int             `json:"memoCount"`
+	// BucketList is the periods with memos, from the oldest.
+	BucketList []*MemoStatsBucket `json:"bucketList"`
+	// ActiveDayCount is the number of days with memos.
+	ActiveDayCount int `json:"activeDayCount"`
+	// CurrentStreak is the consecutive days with memos until today, or until yesterday when there is no memo today yet.
+	CurrentStreak int `json:"currentStreak"`
+	LongestStreak int `json:"longestStreak"`
+}
+
+// MemoTagStatsBucket is the tag usage of the memos created in a period.
+type MemoTagStatsBucket struct {
+	Date string `json:"date"`
+	// TagCountList is ordered by the count, then by the tag name.
+	TagCountList []*MemoTagCount `json:"tagCountList"`
+}
+
+type MemoTagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// MemoTagUsage is a tag of a memo with the created time of the memo.
+type MemoTagUsage struct {
+	Tag           string
+	MemoCreatedTs int64
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MemoTemplateScope is who can use a memo template.
+type MemoTemplateScope string
+
+const (
+	// MemoTemplateUser is the scope of the templates only used by their creator.
+	MemoTemplateUser MemoTemplateScope = "USER"
+	// MemoTemplateSystem is the scope of the templates used by every user.
+	MemoTemplateSystem MemoTemplateScope = "SYSTEM"
+)
+
+func (s MemoTemplateScope) String() string {
+	switch s {
+	case MemoTemplateUser:
+		return "USER"
+	case MemoTemplateSystem:
+		return "SYSTEM"
+	}
+	return "USER"
+}
+
+// maxMemoTemplateNameLength is the max length of a template name.
+const maxMemoTemplateNameLength = 256
+
+type MemoTemplate struct {
+	ID int `json:"id"`
+
+	// Standard fields
+	CreatorID int   `json:"creatorId"`
+	CreatedTs int64 `json:"createdTs"`
+	UpdatedTs int64 `json:"updatedTs"`
+
+	// Domain specific fields
+	Scope MemoTemplateScope `json:"scope"`
+	Name  string            `json:"name"`
+	// Content is the memo content with the placeholders {date}, {time} and {user}.
+	Content string `json:"content"`
+}
+
+// Expand replaces the placeholders of the content with the date and the time of now, and the name of the user.
+func (template *MemoTemplate) Expand(now time.Time, user *User) string {
+	name := user.Nickname
+	if name == "" {
+		name = user.Name
+	}
+	return strings.NewReplacer(
+		"{date}", now.Format("2006-01-02"),
+		"{time}", now.Format("15:04"),
+		"{user}", name,
+	).Replace(template.Content)
+}
+
+type MemoTemplateCreate struct {
+	// Standard fields
+	CreatorID int `json:"-"`
+
+	// Domain specific fields
+	Scope   MemoTemplateScope `json:"scope"`
+	Name    string            `json:"name"`
+	Content string            `json:"content"`
+}
+
+func (create MemoTemplateCreate) Validate() error {
+	if create.Scope != MemoTemplateUser && create.Scope != MemoTemplateSystem {
+		return fmt.Errorf("invalid template scope: %s", create.Scope)
+	}
+	if err := validateMemoTemplateName(create.Name); err != nil {
+		return err
+	}
+	if len(create.Content) > MaxContentLength {
+		return fmt.Errorf("content size overflow, up to 1MB")
+	}
+	return nil
+}
+
+type MemoTemplatePatch struct {
+	ID int `json:"-"`
+
+	// Standard fields
+	UpdatedTs *int64
+
+	// Domain specific fields
+	Name    *string `json:"name"`
+	Content *string `json:"content"`
+}
+
+func (patch MemoTemplatePatch) Validate() error {
+	if patch.Name != nil {
+		if err := validateMemoTemplateName(*patch.Name); err != nil {
+			return err
+		}
+	}
+	if patch.Content != nil && len(*patch.Content) > MaxContentLength {
+		return fmt.Errorf("content size overflow, up to 1MB")
+	}
+	return nil
+}
+
+type MemoTemplateFind struct {
+	ID *int
+
+	// Standard fields
+	CreatorID *int
+
+	// Domain specific fields
+	Scope *MemoTemplateScope
+	// UserID finds the templates the user can use, those of the user and the system ones.
+	UserID *int
+}
+
+type MemoTemplateDelete struct {
+	ID int
+}
+
+func validateMemoTemplateName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("template name shouldn't be empty")
+	}
+	if len(name) > maxMemoTemplateNameLength {
+		return fmt.Errorf("template name is too long, maximum length is %d", maxMemoTemplateNameLength)
+	}
+	return nil
+}
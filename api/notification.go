@@ -0,0 +1,74 @@
+package api
+
+// NotificationType is the type of a notification.
+type NotificationType string
+
+const (
+	// NotificationComment is sent to the creator of a memo when someone comments on it.
+	NotificationComment NotificationType = "COMMENT"
+	// NotificationReaction is sent to the creator of a memo when someone reacts to it.
+	NotificationReaction NotificationType = "REACTION"
+	// NotificationMention is sent to the users mentioned with @username in a memo.
+	NotificationMention NotificationType = "MENTION"
+)
+
+func (e NotificationType) String() string {
+	switch e {
+	case NotificationComment:
+		return "COMMENT"
+	case NotificationReaction:
+		return "REACTION"
+	case NotificationMention:
+		return "MENTION"
+	}
+	return ""
+}
+
+type Notification struct {
+	ID int `json:"id"`
+
+	// Standard fields
+	CreatedTs int64 `json:"createdTs"`
+
+	// Domain specific fields
+	ReceiverID int              `json:"receiverId"`
+	SenderID   int              `json:"senderId"`
+	Type       NotificationType `json:"type"`
+	// MemoID is the comment for COMMENT, the reacted memo for REACTION and the mentioning memo for MENTION.
+	MemoID   int    `json:"memoId"`
+	Reaction string `json:"reaction"`
+	Read     bool   `json:"read"`
+
+	// Related fields
+	SenderName string `json:"senderName"`
+}
+
+type NotificationCreate struct {
+	ReceiverID int
+	SenderID   int
+	Type       NotificationType
+	MemoID     int
+	Reaction   string
+}
+
+type NotificationPatch struct {
+	ID int `json:"-"`
+
+	// ReceiverID makes sure only the receiver can patch the notification.
+	ReceiverID int `json:"-"`
+
+	// Domain specific fields
+	Read *bool `json:"read"`
+}
+
+type NotificationFind struct {
+	ID *int
+
+	// Domain specific fields
+	ReceiverID *int
+	Read       *bool
+
+	// Pagination
+	Limit  *int
+	Offset *int
+}
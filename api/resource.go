@@ -16,6 +16,10 @@ type Resource struct {
 	Type         string `json:"type"`
 	Size         int64  `json:"size"`
 	PublicID     string `json:"publicId"`
+	// Location is read from the EXIF metadata of an uploaded image, nil without one.
+	// It may reveal where the creator lives, so it is only shown to the creator as the CreatorLocation.
+	Location        *Location `json:"-"`
+	CreatorLocation *Location `json:"location,omitempty"`
 
 	// Related fields
 	LinkedMemoAmount int `json:"linkedMemoAmount"`
@@ -33,6 +37,8 @@ type ResourceCreate struct {
 	Type         string `json:"type"`
 	Size         int64  `json:"-"`
 	PublicID     string `json:"publicId"`
+	// Location is read from the EXIF metadata of an uploaded image.
+	Location *Location `json:"-"`
 }
 
 type ResourceFind struct {
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+)
+
+type Permission string
+
+const (
+	// PermissionMemoWrite allows creating, editing and deleting own memos.
+	PermissionMemoWrite Permission = "memo.write"
+	// PermissionMemoPublish allows publishing public memos even when public memos are disabled.
+	PermissionMemoPublish Permission = "memo.publish"
+	// PermissionResourceUpload allows uploading and editing own resources.
+	PermissionResourceUpload Permission = "resource.upload"
+	// PermissionStorageManage allows managing the storage services.
+	PermissionStorageManage Permission = "storage.manage"
+	// PermissionUserManage allows creating, editing and deleting other users.
+	PermissionUserManage Permission = "user.manage"
+	// PermissionSettingManage allows managing system settings, identity providers and roles.
+	PermissionSettingManage Permission = "setting.manage"
+)
+
+// PermissionList is every permission known to the server, in display order.
+var PermissionList = []Permission{
+	PermissionMemoWrite,
+	PermissionMemoPublish,
+	PermissionResourceUpload,
+	PermissionStorageManage,
+	PermissionUserManage,
+	PermissionSettingManage,
+}
+
+func (p Permission) String() string {
+	return string(p)
+}
+
+func (p Permission) IsValid() bool {
+	for _, permission := range PermissionList {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinRolePermissionList maps the built-in roles to their permissions. The built-in roles can't be edited.
+var builtinRolePermissionList = map[Role][]Permission{
+	Host: PermissionList,
+	Admin: {
+		PermissionMemoWrite,
+		PermissionMemoPublish,
+		PermissionResourceUpload,
+		PermissionStorageManage,
+		PermissionUserManage,
+	},
+	NormalUser: {
+		PermissionMemoWrite,
+		PermissionResourceUpload,
+	},
+}
+
+// IsBuiltin reports whether the role is one of HOST, ADMIN and USER.
+func (e Role) IsBuiltin() bool {
+	_, ok := builtinRolePermissionList[e]
+	return ok
+}
+
+// BuiltinRoleList returns the built-in roles with their permissions.
+func BuiltinRoleList() []*CustomRole {
+	list := []*CustomRole{}
+	for _, role := range []Role{Host, Admin, NormalUser} {
+		list = append(list, &CustomRole{
+			Name:           role,
+			Builtin:        true,
+			PermissionList: builtinRolePermissionList[role],
+		})
+	}
+	return list
+}
+
+// BuiltinRolePermissionList returns the permissions of a built-in role.
+func BuiltinRolePermissionList(role Role) ([]Permission, bool) {
+	permissionList, ok := builtinRolePermissionList[role]
+	return permissionList, ok
+}
+
+// MissingPermissionList returns the permissions of the list which are not granted, in the list order.
+func MissingPermissionList(granted []Permission, list []Permission) []Permission {
+	missingList := []Permission{}
+	for _, permission := range list {
+		found := false
+		for _, p := range granted {
+			if p == permission {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missingList = append(missingList, permission)
+		}
+	}
+	return missingList
+}
+
+// CustomRole is a host defined role mapped to a set of permissions.
+type CustomRole struct {
+	CreatedTs int64 `json:"createdTs"`
+	UpdatedTs int64 `json:"updatedTs"`
+
+	// Domain specific fields
+	Name           Role         `json:"name"`
+	Description    string       `json:"description"`
+	Builtin        bool         `json:"builtin"`
+	PermissionList []Permission `json:"permissionList"`
+}
+
+type CustomRoleCreate struct {
+	Name           Role         `json:"name"`
+	Description    string       `json:"description"`
+	PermissionList []Permission `json:"permissionList"`
+}
+
+type CustomRolePatch struct {
+	Name Role `json:"-"`
+
+	// Standard fields
+	UpdatedTs *int64
+
+	// Domain specific fields
+	Description    *string      `json:"description"`
+	PermissionList []Permission `json:"permissionList"`
+}
+
+type CustomRoleFind struct {
+	Name *Role
+}
+
+type CustomRoleDelete struct {
+	Name Role
+}
+
+var customRoleNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+func validatePermissionList(permissionList []Permission) error {
+	for _, permission := range permissionList {
+		if !permission.IsValid() {
+			return fmt.Errorf("invalid permission %q", permission)
+		}
+	}
+	return nil
+}
+
+func (create CustomRoleCreate) Validate() error {
+	if !customRoleNamePattern.MatchString(string(create.Name)) {
+		return fmt.Errorf("invalid role name, only 1 to 32 letters, digits, '_' and '-' are allowed")
+	}
+	if create.Name.IsBuiltin() {
+		return fmt.Errorf("role name %s is reserved", create.Name)
+	}
+	if len(create.Description) > 256 {
+		return fmt.Errorf("description is too long, maximum length is 256")
+	}
+	return validatePermissionList(create.PermissionList)
+}
+
+func (patch CustomRolePatch) Validate() error {
+	if patch.Description != nil && len(*patch.Description) > 256 {
+		return fmt.Errorf("description is too long, maximum length is 256")
+	}
+	return validatePermissionList(patch.PermissionList)
+}
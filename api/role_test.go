@@ -0,0 +1,59 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissingPermissionList(t *testing.T) {
+	adminPermissionList, ok := BuiltinRolePermissionList(Admin)
+	require.True(t, ok)
+	hostPermissionList, ok := BuiltinRolePermissionList(Host)
+	require.True(t, ok)
+	userPermissionList, ok := BuiltinRolePermissionList(NormalUser)
+	require.True(t, ok)
+
+	tests := []struct {
+		name    string
+		granted []Permission
+		list    []Permission
+		want    []Permission
+	}{
+		{
+			name:    "admin assigning a setting.manage role",
+			granted: adminPermissionList,
+			list:    []Permission{PermissionMemoWrite, PermissionSettingManage},
+			want:    []Permission{PermissionSettingManage},
+		},
+		{
+			name:    "admin assigning the user role",
+			granted: adminPermissionList,
+			list:    userPermissionList,
+			want:    []Permission{},
+		},
+		{
+			name:    "admin assigning the admin role",
+			granted: adminPermissionList,
+			list:    adminPermissionList,
+			want:    []Permission{},
+		},
+		{
+			name:    "host assigning a setting.manage role",
+			granted: hostPermissionList,
+			list:    []Permission{PermissionSettingManage, PermissionUserManage},
+			want:    []Permission{},
+		},
+		{
+			name:    "user assigning the admin role",
+			granted: userPermissionList,
+			list:    adminPermissionList,
+			want:    []Permission{PermissionMemoPublish, PermissionStorageManage, PermissionUserManage},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.want, MissingPermissionList(test.granted, test.list))
+		})
+	}
+}
@@ -1,5 +1,11 @@
 package api
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
 type Shortcut struct {
 	ID int `json:"id"`
 
@@ -51,3 +57,15 @@ type ShortcutDelete struct {
 	// Standard fields
 	CreatorID *int
 }
+
+// ParseShortcutPayload parses the payload of a shortcut into a memo filter, nil if the payload is empty.
+func ParseShortcutPayload(payload string) (*MemoFilter, error) {
+	if payload == "" {
+		return nil, nil
+	}
+	// The filters of legacy shortcuts are JSON lists of conditions, they would be searched as text and match nothing.
+	if trimmed := strings.TrimSpace(payload); (strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{")) && json.Valid([]byte(trimmed)) {
+		return nil, fmt.Errorf("JSON shortcut payloads are not supported, use a filter expression instead")
+	}
+	return ParseMemoFilter(payload)
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseShortcutPayload(t *testing.T) {
+	filter, err := ParseShortcutPayload("")
+	require.NoError(t, err)
+	require.Nil(t, filter)
+
+	filter, err = ParseShortcutPayload("#work [draft]")
+	require.NoError(t, err)
+	require.Equal(t, &MemoFilter{Operator: MemoFilterAnd, Children: []*MemoFilter{
+		{Key: MemoFilterTag, Value: "work"},
+		{Key: MemoFilterText, Value: "[draft]"},
+	}}, filter)
+
+	for _, payload := range []string{
+		`[{"type":"TAG","value":{"operator":"CONTAIN","value":"work"},"relation":"AND"}]`,
+		` {"type":"TAG"}`,
+		`[]`,
+	} {
+		_, err := ParseShortcutPayload(payload)
+		require.ErrorContains(t, err, "JSON shortcut payloads are not supported", payload)
+	}
+}
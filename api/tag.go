@@ -1,8 +1,19 @@
 package api
 
+import (
+	"fmt"
+	"strings"
+
+	"uamemos/plugin/markdown"
+)
+
 type Tag struct {
-	Name      string
-	CreatorID int
+	Name      string `json:"name"`
+	CreatorID int    `json:"creatorId"`
+
+	// MemoCount is the number of normal memos with the tag, LastUsedTs is the latest time the tag was added to one of them.
+	MemoCount  int   `json:"memoCount"`
+	LastUsedTs int64 `json:"lastUsedTs"`
 }
 
 type TagUpsert struct {
@@ -18,3 +29,92 @@ type TagDelete struct {
 	Name      string `json:"name"`
 	CreatorID int
 }
+
+// TagNode is a node of the tag tree, nested tags are separated by slashes, e.g. work/projectA.
+type TagNode struct {
+	// Name is the last segment of the tag name.
+	Name string `json:"name"`
+	// Path is the full tag name.
+	Path string `json:"path"`
+	// Exist is false for the parents only implied by nested tags.
+	Exist    bool       `json:"exist"`
+	Children []*TagNode `json:"children"`
+}
+
+// TagRename renames a tag and its nested tags.
+type TagRename struct {
+	CreatorID int `json:"-"`
+
+	Name    string `json:"name"`
+	NewName string `json:"newName"`
+}
+
+func (rename TagRename) Validate() error {
+	if err := validateTagName(rename.Name); err != nil {
+		return err
+	}
+	if err := validateTagName(rename.NewName); err != nil {
+		return err
+	}
+	if rename.Name == rename.NewName {
+		return fmt.Errorf("new tag name is the same as the tag name")
+	}
+	// The nested tags are renamed with the tag, so they would be renamed twice.
+	if isNestedTagName(rename.NewName, rename.Name) {
+		return fmt.Errorf("tag %s cannot be renamed into its nested tag %s", rename.Name, rename.NewName)
+	}
+	return nil
+}
+
+// TagMerge merges tags and their nested tags into the target tag.
+type TagMerge struct {
+	CreatorID int `json:"-"`
+
+	NameList   []string `json:"nameList"`
+	TargetName string   `json:"targetName"`
+}
+
+func (merge TagMerge) Validate() error {
+	if len(merge.NameList) == 0 {
+		return fmt.Errorf("tag name list shouldn't be empty")
+	}
+	for _, name := range merge.NameList {
+		if err := validateTagName(name); err != nil {
+			return err
+		}
+		if name == merge.TargetName {
+			return fmt.Errorf("tag %s cannot be merged into itself", name)
+		}
+		if isNestedTagName(merge.TargetName, name) {
+			return fmt.Errorf("tag %s cannot be merged into its nested tag %s", name, merge.TargetName)
+		}
+	}
+	return validateTagName(merge.TargetName)
+}
+
+// TagRewriteResult is the result of renaming or merging tags.
+type TagRewriteResult struct {
+	Name string `json:"name"`
+	// MemoIDList is the memos whose content is rewritten.
+	MemoIDList []int `json:"memoIdList"`
+}
+
+// FindTagList finds the distinct tags in the memo content, sorted by name.
+func FindTagList(content string) []string {
+	return markdown.ExtractTags([]byte(content))
+}
+
+// isNestedTagName returns true if the tag is nested under the parent tag, e.g. work/archive under work.
+func isNestedTagName(name, parent string) bool {
+	return strings.HasPrefix(name, parent+"/")
+}
+
+func validateTagName(name string) error {
+	if name == "" {
+		return fmt.Errorf("tag name shouldn't be empty")
+	}
+	if !markdown.IsValidTagName(name) {
+		return fmt.Errorf("invalid tag name: %s", name)
+	}
+	return nil
+}
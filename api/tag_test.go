@@ -0,0 +1,24 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagRenameValidate(t *testing.T) {
+	require.NoError(t, TagRename{Name: "work", NewName: "job"}.Validate())
+	require.NoError(t, TagRename{Name: "work/archive", NewName: "work"}.Validate())
+	require.NoError(t, TagRename{Name: "work", NewName: "workshop"}.Validate())
+	require.ErrorContains(t, TagRename{Name: "work", NewName: "work"}.Validate(), "same")
+	require.ErrorContains(t, TagRename{Name: "work", NewName: "work/archive"}.Validate(), "nested tag")
+}
+
+func TestTagMergeValidate(t *testing.T) {
+	require.NoError(t, TagMerge{NameList: []string{"work", "job"}, TargetName: "office"}.Validate())
+	require.NoError(t, TagMerge{NameList: []string{"work/archive"}, TargetName: "work"}.Validate())
+	require.ErrorContains(t, TagMerge{NameList: []string{"work", "job"}, TargetName: "job"}.Validate(), "into itself")
+	require.ErrorContains(t, TagMerge{NameList: []string{"work/archive", "work"}, TargetName: "work/archive"}.Validate(), "into itself")
+	require.ErrorContains(t, TagMerge{NameList: []string{"job", "work"}, TargetName: "work/archive"}.Validate(), "nested tag")
+	require.ErrorContains(t, TagMerge{NameList: []string{"work", "work/archive"}, TargetName: "work/archive"}.Validate(), "nested tag")
+}
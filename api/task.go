@@ -0,0 +1,42 @@
+package api
+
+// Task is a task list item in the content of a memo, e.g. - [ ] todo.
+type Task struct {
+	MemoID int `json:"memoId"`
+	// Index is the position of the task among the tasks of the memo, starting from 0.
+	Index int `json:"index"`
+
+	// Standard fields
+	CreatorID int   `json:"creatorId"`
+	CreatedTs int64 `json:"createdTs"`
+	UpdatedTs int64 `json:"updatedTs"`
+
+	// Domain specific fields
+	Content string `json:"content"`
+	Done    bool   `json:"done"`
+}
+
+type TaskFind struct {
+	MemoID    *int
+	CreatorID *int
+	Done      *bool
+
+	// Pagination
+	Limit  *int
+	Offset *int
+}
+
+type TaskPatch struct {
+	MemoID int `json:"-"`
+	Index  int `json:"-"`
+
+	// Domain specific fields
+	Done bool `json:"done"`
+}
+
+// TaskCount is the number of tasks in the normal memos of a user.
+type TaskCount struct {
+	CreatorID int `json:"creatorId"`
+	OpenCount int `json:"openCount"`
+	DoneCount int `json:"doneCount"`
+}
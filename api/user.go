@@ -25,7 +25,8 @@ func (e Role) String() string {
 	case NormalUser:
 		return "USER"
 	}
-	return "USER"
+	// Custom roles are named by the host.
+	return string(e)
 }
 
 type User struct {
@@ -44,6 +45,13 @@ type User struct {
 	OpenID          string         `json:"openId"`
 	AvatarURL       string         `json:"avatarUrl"`
 	UserSettingList []*UserSetting `json:"userSettingList"`
+	// PermissionList is only composed for the current session user.
+	PermissionList []Permission `json:"permissionList,omitempty"`
+	// UnreadNotificationCount is only composed for the current session user.
+	UnreadNotificationCount int `json:"unreadNotificationCount,omitempty"`
+	// FollowerCount and FollowingCount are only composed for the user profile.
+	FollowerCount  int `json:"followerCount,omitempty"`
+	FollowingCount int `json:"followingCount,omitempty"`
 }
 
 type UserFind struct {
@@ -77,6 +85,7 @@ type UserPatch struct {
 
 	// Domain specific fields
 	Username     *string `json:"username"`
+	Role         *Role   `json:"role"`
 	Email        *string `json:"email"`
 	Nickname     *string `json:"nickname"`
 	Password     *string `json:"password"`
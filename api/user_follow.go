@@ -0,0 +1,22 @@
+package api
+
+type UserFollow struct {
+	FollowerID  int   `json:"followerId"`
+	FollowingID int   `json:"followingId"`
+	CreatedTs   int64 `json:"createdTs"`
+}
+
+type UserFollowUpsert struct {
+	FollowerID  int
+	FollowingID int
+}
+
+type UserFollowFind struct {
+	FollowerID  *int
+	FollowingID *int
+}
+
+type UserFollowDelete struct {
+	FollowerID  int
+	FollowingID int
+}
@@ -0,0 +1,94 @@
+package api
+
+import "fmt"
+
+type UserGroup struct {
+	ID int `json:"id"`
+
+	// Standard fields
+	CreatorID int   `json:"creatorId"`
+	CreatedTs int64 `json:"createdTs"`
+	UpdatedTs int64 `json:"updatedTs"`
+
+	// Domain specific fields
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	// Related fields
+	MemberIDList []int `json:"memberIdList"`
+}
+
+type UserGroupCreate struct {
+	// Standard fields
+	CreatorID int `json:"-"`
+
+	// Domain specific fields
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type UserGroupPatch struct {
+	ID int `json:"-"`
+
+	// Standard fields
+	UpdatedTs *int64
+
+	// Domain specific fields
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+type UserGroupFind struct {
+	ID *int
+
+	// MemberID finds the groups the user belongs to.
+	MemberID *int
+}
+
+type UserGroupDelete struct {
+	ID int
+}
+
+type UserGroupMember struct {
+	GroupID   int   `json:"groupId"`
+	UserID    int   `json:"userId"`
+	CreatedTs int64 `json:"createdTs"`
+}
+
+type UserGroupMemberUpsert struct {
+	GroupID int `json:"-"`
+	UserID  int `json:"userId"`
+}
+
+type UserGroupMemberFind struct {
+	GroupID *int
+	UserID  *int
+}
+
+type UserGroupMemberDelete struct {
+	GroupID int
+	UserID  int
+}
+
+func (create UserGroupCreate) Validate() error {
+	if create.Name == "" {
+		return fmt.Errorf("name shouldn't be empty")
+	}
+	if len(create.Name) > 64 {
+		return fmt.Errorf("name is too long, maximum length is 64")
+	}
+	if len(create.Description) > 256 {
+		return fmt.Errorf("description is too long, maximum length is 256")
+	}
+	return nil
+}
+
+func (patch UserGroupPatch) Validate() error {
+	if patch.Name != nil && (*patch.Name == "" || len(*patch.Name) > 64) {
+		return fmt.Errorf("name should be 1 to 64 characters")
+	}
+	if patch.Description != nil && len(*patch.Description) > 256 {
+		return fmt.Errorf("description is too long, maximum length is 256")
+	}
+	return nil
+}
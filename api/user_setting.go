@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"golang.org/x/exp/slices"
 )
@@ -16,6 +17,10 @@ const (
 	UserSettingAppearanceKey UserSettingKey = "appearance"
 	// UserSettingMemoVisibilityKey is the key type for user preference memo default visibility.
 	UserSettingMemoVisibilityKey UserSettingKey = "memo-visibility"
+	// UserSettingMemoVisibilityGroupKey is the key type for user preference memo default user group of GROUP visibility.
+	UserSettingMemoVisibilityGroupKey UserSettingKey = "memo-visibility-group"
+	// UserSettingTimezoneKey is the key type for user timezone, an IANA time zone name such as "Asia/Shanghai".
+	UserSettingTimezoneKey UserSettingKey = "timezone"
 )
 
 // String returns the string format of UserSettingKey type.
@@ -27,6 +32,10 @@ func (key UserSettingKey) String() string {
 		return "appearance"
 	case UserSettingMemoVisibilityKey:
 		return "memo-visibility"
+	case UserSettingMemoVisibilityGroupKey:
+		return "memo-visibility-group"
+	case UserSettingTimezoneKey:
+		return "timezone"
 	}
 	return ""
 }
@@ -52,7 +61,7 @@ var (
 		"zh-Hant",
 	}
 	UserSettingAppearanceValue     = []string{"system", "light", "dark"}
-	UserSettingMemoVisibilityValue = []Visibility{Private, Protected, Public}
+	UserSettingMemoVisibilityValue = []Visibility{Private, Protected, Public, Group}
 )
 
 type UserSetting struct {
@@ -96,6 +105,24 @@ func (upsert UserSettingUpsert) Validate() error {
 		if !slices.Contains(UserSettingMemoVisibilityValue, memoVisibilityValue) {
 			return fmt.Errorf("invalid user setting memo visibility value")
 		}
+	} else if upsert.Key == UserSettingMemoVisibilityGroupKey {
+		groupID := 0
+		err := json.Unmarshal([]byte(upsert.Value), &groupID)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal user setting memo visibility group value")
+		}
+		if groupID <= 0 {
+			return fmt.Errorf("invalid user setting memo visibility group value")
+		}
+	} else if upsert.Key == UserSettingTimezoneKey {
+		timezoneValue := ""
+		err := json.Unmarshal([]byte(upsert.Value), &timezoneValue)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal user setting timezone value")
+		}
+		if _, err := time.LoadLocation(timezoneValue); err != nil || timezoneValue == "" {
+			return fmt.Errorf("invalid user setting timezone value")
+		}
 	} else {
 		return fmt.Errorf("invalid user setting key")
 	}
@@ -0,0 +1,200 @@
+// Package activitypub implements the parts of ActivityPub needed to federate memos:
+// * ActivityStreams objects of actors, notes and activities;
+// * HTTP signatures of server-to-server requests;
+// * a client fetching remote actors and delivering activities to inboxes;
+// * a background queue retrying failed deliveries.
+package activitypub
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+)
+
+const (
+	// ContentType is the media type of ActivityStreams documents.
+	ContentType = "application/activity+json"
+	// LDContentType is the JSON-LD media type of ActivityStreams documents, also accepted by servers.
+	LDContentType = `application/ld+json; profile="https://www.w3.org/ns/activitystreams"`
+	// WebFingerContentType is the media type of WebFinger responses.
+	WebFingerContentType = "application/jrd+json"
+
+	// ActivityStreamsContext is the JSON-LD context of ActivityStreams.
+	ActivityStreamsContext = "https://www.w3.org/ns/activitystreams"
+	// SecurityContext is the JSON-LD context of the actor public keys.
+	SecurityContext = "https://w3id.org/security/v1"
+	// PublicCollection addresses an object to everyone.
+	PublicCollection = "https://www.w3.org/ns/activitystreams#Public"
+)
+
+// Activity types handled by the package.
+const (
+	TypeAccept = "Accept"
+	TypeCreate = "Create"
+	TypeDelete = "Delete"
+	TypeFollow = "Follow"
+	TypeUndo   = "Undo"
+	TypeUpdate = "Update"
+)
+
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+type Endpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+type Actor struct {
+	Context           any        `json:"@context,omitempty"`
+	ID                string     `json:"id"`
+	Type              string     `json:"type"`
+	PreferredUsername string     `json:"preferredUsername"`
+	Name              string     `json:"name,omitempty"`
+	Summary           string     `json:"summary,omitempty"`
+	URL               string     `json:"url,omitempty"`
+	Inbox             string     `json:"inbox"`
+	Outbox            string     `json:"outbox,omitempty"`
+	Followers         string     `json:"followers,omitempty"`
+	Endpoints         *Endpoints `json:"endpoints,omitempty"`
+	PublicKey         *PublicKey `json:"publicKey,omitempty"`
+}
+
+type Note struct {
+	Context      any      `json:"@context,omitempty"`
+	ID           string   `json:"id"`
+	Type         string   `json:"type"`
+	AttributedTo string   `json:"attributedTo"`
+	Content      string   `json:"content"`
+	URL          string   `json:"url,omitempty"`
+	Published    string   `json:"published,omitempty"`
+	Updated      string   `json:"updated,omitempty"`
+	To           []string `json:"to,omitempty"`
+	Cc           []string `json:"cc,omitempty"`
+}
+
+// Activity is an activity of an actor, Object is either an IRI or an embedded object.
+type Activity struct {
+	Context   any      `json:"@context,omitempty"`
+	ID        string   `json:"id"`
+	Type      string   `json:"type"`
+	Actor     string   `json:"actor"`
+	Published string   `json:"published,omitempty"`
+	To        []string `json:"to,omitempty"`
+	Cc        []string `json:"cc,omitempty"`
+	Object    any      `json:"object"`
+}
+
+// ObjectID returns the IRI of the object, either given directly or as the id of the embedded object.
+func (activity *Activity) ObjectID() string {
+	switch object := activity.Object.(type) {
+	case string:
+		return object
+	case map[string]any:
+		if id, ok := object["id"].(string); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// ObjectActivity decodes the embedded object as an activity, e.g. the Follow undone by an Undo.
+func (activity *Activity) ObjectActivity() (*Activity, error) {
+	object, ok := activity.Object.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("object of %s activity is not embedded", activity.Type)
+	}
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	objectActivity := &Activity{}
+	if err := json.Unmarshal(data, objectActivity); err != nil {
+		return nil, err
+	}
+	return objectActivity, nil
+}
+
+type OrderedCollection struct {
+	Context      any    `json:"@context,omitempty"`
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	TotalItems   int    `json:"totalItems"`
+	OrderedItems []any  `json:"orderedItems,omitempty"`
+}
+
+type WebFingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href,omitempty"`
+}
+
+type WebFinger struct {
+	Subject string          `json:"subject"`
+	Aliases []string        `json:"aliases,omitempty"`
+	Links   []WebFingerLink `json:"links"`
+}
+
+// GenerateKeyPair generates a RSA key pair encoded in PEM, the private key in PKCS #8 and the public key in PKIX.
+func GenerateKeyPair() (privateKeyPEM string, publicKeyPEM string, err error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", err
+	}
+	privateKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", "", err
+	}
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	privateKeyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyBytes}))
+	publicKeyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes}))
+	return privateKeyPEM, publicKeyPEM, nil
+}
+
+// ParsePrivateKey parses a RSA private key in PKCS #8 or PKCS #1 PEM.
+func ParsePrivateKey(privateKeyPEM string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privateKeyPEM))
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key PEM")
+	}
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not a RSA key")
+	}
+	return privateKey, nil
+}
+
+// ParsePublicKey parses a RSA public key in PKIX or PKCS #1 PEM.
+func ParsePublicKey(publicKeyPEM string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return nil, fmt.Errorf("invalid public key PEM")
+	}
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is not a RSA key")
+	}
+	return publicKey, nil
+}
@@ -0,0 +1,240 @@
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRemote is a remote server with one actor, whose inbox verifies the signatures of the deliveries.
+type fakeRemote struct {
+	server     *httptest.Server
+	privateKey *rsa.PrivateKey
+	publicKey  string
+	// failCount is the number of deliveries rejected before accepting.
+	failCount atomic.Int32
+
+	mutex      sync.Mutex
+	activities []*Activity
+}
+
+func newFakeRemote(t *testing.T) *fakeRemote {
+	privateKeyPEM, publicKeyPEM, err := GenerateKeyPair()
+	require.NoError(t, err)
+	privateKey, err := ParsePrivateKey(privateKeyPEM)
+	require.NoError(t, err)
+
+	remote := &fakeRemote{privateKey: privateKey, publicKey: publicKeyPEM}
+	client := newClient(5*time.Second, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/actor", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_ = json.NewEncoder(w).Encode(remote.actor())
+	})
+	mux.HandleFunc("/inbox", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if _, err := VerifyRequest(r.Context(), r, body, client.FetchPublicKey); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if remote.failCount.Add(-1) >= 0 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		activity := &Activity{}
+		if err := json.Unmarshal(body, activity); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		remote.mutex.Lock()
+		remote.activities = append(remote.activities, activity)
+		remote.mutex.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	})
+	remote.server = httptest.NewServer(mux)
+	t.Cleanup(remote.server.Close)
+	return remote
+}
+
+func (remote *fakeRemote) actor() *Actor {
+	actorID := remote.server.URL + "/actor"
+	return &Actor{
+		Context:           []string{ActivityStreamsContext, SecurityContext},
+		ID:                actorID,
+		Type:              "Person",
+		PreferredUsername: "remote",
+		Inbox:             remote.server.URL + "/inbox",
+		PublicKey: &PublicKey{
+			ID:           actorID + "#main-key",
+			Owner:        actorID,
+			PublicKeyPem: remote.publicKey,
+		},
+	}
+}
+
+func (remote *fakeRemote) receivedActivities() []*Activity {
+	remote.mutex.Lock()
+	defer remote.mutex.Unlock()
+	return append([]*Activity{}, remote.activities...)
+}
+
+func TestSignRequest(t *testing.T) {
+	privateKeyPEM, publicKeyPEM, err := GenerateKeyPair()
+	require.NoError(t, err)
+	privateKey, err := ParsePrivateKey(privateKeyPEM)
+	require.NoError(t, err)
+	publicKey, err := ParsePublicKey(publicKeyPEM)
+	require.NoError(t, err)
+	getPublicKey := func(_ context.Context, keyID string) (*rsa.PublicKey, error) {
+		require.Equal(t, "https://example.com/actor#main-key", keyID)
+		return publicKey, nil
+	}
+
+	body := []byte(`{"type":"Follow"}`)
+	newSignedRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "https://example.com/inbox", bytes.NewReader(body))
+		require.NoError(t, SignRequest(req, "https://example.com/actor#main-key", privateKey, body))
+		return req
+	}
+
+	keyID, err := VerifyRequest(context.Background(), newSignedRequest(), body, getPublicKey)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/actor#main-key", keyID)
+
+	_, err = VerifyRequest(context.Background(), newSignedRequest(), []byte(`{"type":"Undo"}`), getPublicKey)
+	require.ErrorContains(t, err, "digest")
+
+	req := newSignedRequest()
+	req.URL.Path = "/other-inbox"
+	_, err = VerifyRequest(context.Background(), req, body, getPublicKey)
+	require.ErrorContains(t, err, "verification failed")
+
+	req = newSignedRequest()
+	req.Header.Set("Date", time.Now().Add(-24*time.Hour).UTC().Format(http.TimeFormat))
+	_, err = VerifyRequest(context.Background(), req, body, getPublicKey)
+	require.ErrorContains(t, err, "date")
+
+	req = httptest.NewRequest(http.MethodPost, "https://example.com/inbox", bytes.NewReader(body))
+	_, err = VerifyRequest(context.Background(), req, body, getPublicKey)
+	require.ErrorContains(t, err, "missing")
+}
+
+func TestClient(t *testing.T) {
+	remote := newFakeRemote(t)
+	client := newClient(5*time.Second, nil)
+	ctx := context.Background()
+
+	actor, err := client.FetchActor(ctx, remote.server.URL+"/actor")
+	require.NoError(t, err)
+	require.Equal(t, remote.server.URL+"/inbox", actor.Inbox)
+
+	_, err = client.FetchPublicKey(ctx, remote.server.URL+"/actor#other-key")
+	require.Error(t, err)
+
+	// The fake inbox fetches the key of the signature from the fake actor, so the remote signs as its own actor.
+	follow := &Activity{
+		ID:     remote.server.URL + "/follow/1",
+		Type:   TypeFollow,
+		Actor:  actor.ID,
+		Object: "https://example.com/actor",
+	}
+	require.NoError(t, client.Deliver(ctx, actor.Inbox, actor.PublicKey.ID, remote.privateKey, follow))
+
+	otherPrivateKeyPEM, _, err := GenerateKeyPair()
+	require.NoError(t, err)
+	otherPrivateKey, err := ParsePrivateKey(otherPrivateKeyPEM)
+	require.NoError(t, err)
+	require.Error(t, client.Deliver(ctx, actor.Inbox, actor.PublicKey.ID, otherPrivateKey, follow))
+
+	activities := remote.receivedActivities()
+	require.Len(t, activities, 1)
+	require.Equal(t, TypeFollow, activities[0].Type)
+	require.Equal(t, "https://example.com/actor", activities[0].ObjectID())
+}
+
+func TestClientPrivateAddress(t *testing.T) {
+	remote := newFakeRemote(t)
+	client := NewClient(5 * time.Second)
+	ctx := context.Background()
+
+	_, err := client.FetchActor(ctx, remote.server.URL+"/actor")
+	require.ErrorContains(t, err, "non public address")
+	_, err = client.FetchPublicKey(ctx, remote.server.URL+"/actor#main-key")
+	require.ErrorContains(t, err, "non public address")
+	err = client.Deliver(ctx, remote.server.URL+"/inbox", "https://example.com/actor#main-key", remote.privateKey, &Activity{Type: TypeFollow})
+	require.ErrorContains(t, err, "non public address")
+	require.Empty(t, remote.receivedActivities())
+
+	for address, public := range map[string]bool{
+		"93.184.216.34":          true,
+		"2606:2800:220:1::":      true,
+		"127.0.0.1":              false,
+		"::1":                    false,
+		"10.1.2.3":               false,
+		"172.16.0.1":             false,
+		"192.168.1.1":            false,
+		"169.254.169.254":        false,
+		"fe80::1":                false,
+		"fd00::1":                false,
+		"100.64.0.1":             false,
+		"0.0.0.0":                false,
+		"::ffff:127.0.0.1":       false,
+		"::ffff:169.254.169.254": false,
+	} {
+		require.Equal(t, public, isPublicAddress(netip.MustParseAddr(address)), address)
+	}
+}
+
+func TestDeliveryQueue(t *testing.T) {
+	remote := newFakeRemote(t)
+	remote.failCount.Store(2)
+	actor := remote.actor()
+
+	queue := NewDeliveryQueue(newClient(5*time.Second, nil), 16)
+	queue.RetryInterval = 10 * time.Millisecond
+	var errorCount atomic.Int32
+	queue.OnError = func(_ *Delivery, _ error) {
+		errorCount.Add(1)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.Run(ctx)
+
+	undo := &Activity{
+		ID:    remote.server.URL + "/undo/1",
+		Type:  TypeUndo,
+		Actor: actor.ID,
+		Object: &Activity{
+			ID:     remote.server.URL + "/follow/1",
+			Type:   TypeFollow,
+			Actor:  actor.ID,
+			Object: "https://example.com/actor",
+		},
+	}
+	require.True(t, queue.Enqueue(&Delivery{
+		Inbox:      actor.Inbox,
+		KeyID:      actor.PublicKey.ID,
+		PrivateKey: remote.privateKey,
+		Activity:   undo,
+	}))
+	queue.Wait()
+
+	require.Equal(t, int32(2), errorCount.Load())
+	activities := remote.receivedActivities()
+	require.Len(t, activities, 1)
+	follow, err := activities[0].ObjectActivity()
+	require.NoError(t, err)
+	require.Equal(t, TypeFollow, follow.Type)
+	require.Equal(t, "https://example.com/actor", follow.ObjectID())
+}
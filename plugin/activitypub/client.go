@@ -0,0 +1,162 @@
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// maxResponseBodySize limits the remote documents read by the client.
+const maxResponseBodySize = 1 << 20
+
+// Client talks to remote ActivityPub servers.
+type Client struct {
+	httpClient *http.Client
+}
+
+// NewClient creates a client which refuses to connect to loopback, private and link-local addresses,
+// since the remote URLs come from unauthenticated requests.
+func NewClient(timeout time.Duration) *Client {
+	return newClient(timeout, controlRemoteAddress)
+}
+
+// newClient creates a client whose connections are checked by the control, if any.
+func newClient(timeout time.Duration, control func(network, address string, c syscall.RawConn) error) *Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: control,
+	}
+	return &Client{
+		httpClient: &http.Client{
+			Timeout: timeout,
+			// The transport has no proxy, so that the dialer sees the remote address.
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: timeout,
+				MaxIdleConns:        100,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+	}
+}
+
+// FetchActor fetches the actor document of the IRI.
+func (c *Client) FetchActor(ctx context.Context, actorID string) (*Actor, error) {
+	if err := validateRemoteURL(actorID); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, actorID, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", ContentType+", "+LDContentType)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch actor %s, status: %d", actorID, resp.StatusCode)
+	}
+
+	actor := &Actor{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBodySize)).Decode(actor); err != nil {
+		return nil, fmt.Errorf("failed to decode actor %s, err: %w", actorID, err)
+	}
+	if actor.ID == "" || actor.Inbox == "" {
+		return nil, fmt.Errorf("invalid actor %s", actorID)
+	}
+	return actor, nil
+}
+
+// FetchPublicKey fetches the public key by the key ID, which is the actor IRI with a fragment by convention.
+// It can be used as the PublicKeyGetter of VerifyRequest.
+func (c *Client) FetchPublicKey(ctx context.Context, keyID string) (*rsa.PublicKey, error) {
+	u, err := url.Parse(keyID)
+	if err != nil {
+		return nil, err
+	}
+	u.Fragment = ""
+	actor, err := c.FetchActor(ctx, u.String())
+	if err != nil {
+		return nil, err
+	}
+	if actor.PublicKey == nil || actor.PublicKey.ID != keyID {
+		return nil, fmt.Errorf("actor %s does not own key %s", actor.ID, keyID)
+	}
+	return ParsePublicKey(actor.PublicKey.PublicKeyPem)
+}
+
+// Deliver posts the activity to the inbox, signed with the private key of the key ID.
+func (c *Client) Deliver(ctx context.Context, inbox string, keyID string, privateKey *rsa.PrivateKey, activity any) error {
+	if err := validateRemoteURL(inbox); err != nil {
+		return err
+	}
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", LDContentType)
+	req.Header.Set("Accept", ContentType)
+	if err := SignRequest(req, keyID, privateKey, body); err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBodySize))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to deliver to %s, status: %d", inbox, resp.StatusCode)
+	}
+	return nil
+}
+
+func validateRemoteURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid remote URL: %s", rawURL)
+	}
+	return nil
+}
+
+// controlRemoteAddress refuses the connections to non public addresses. It runs after the host is resolved,
+// so it also applies to every resolved address and to redirects.
+func controlRemoteAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !isPublicAddress(addrPort.Addr()) {
+		return fmt.Errorf("refusing to connect to non public address %s", addrPort.Addr())
+	}
+	return nil
+}
+
+func isPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !addr.IsLoopback() && !addr.IsLinkLocalUnicast() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which is not routed on the internet.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
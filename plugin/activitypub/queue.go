@@ -0,0 +1,96 @@
+package activitypub
+
+import (
+	"context"
+	"crypto/rsa"
+	"sync"
+	"time"
+)
+
+// Delivery is an activity to be delivered to a remote inbox.
+type Delivery struct {
+	Inbox      string
+	KeyID      string
+	PrivateKey *rsa.PrivateKey
+	Activity   any
+
+	attempt int
+}
+
+// DeliveryQueue delivers the enqueued activities in the background.
+// Failed deliveries are retried with an exponential backoff until MaxAttempts is reached.
+type DeliveryQueue struct {
+	client     *Client
+	deliveries chan *Delivery
+	// pending counts the deliveries being delivered or waiting for a retry.
+	pending sync.WaitGroup
+
+	MaxAttempts   int
+	RetryInterval time.Duration
+	// OnError is called with the failed delivery and its error, if not nil.
+	OnError func(delivery *Delivery, err error)
+}
+
+func NewDeliveryQueue(client *Client, size int) *DeliveryQueue {
+	return &DeliveryQueue{
+		client:        client,
+		deliveries:    make(chan *Delivery, size),
+		MaxAttempts:   5,
+		RetryInterval: 30 * time.Second,
+	}
+}
+
+// Enqueue adds the delivery to the queue without blocking, it reports false if the queue is full.
+func (q *DeliveryQueue) Enqueue(delivery *Delivery) bool {
+	q.pending.Add(1)
+	select {
+	case q.deliveries <- delivery:
+		return true
+	default:
+		q.pending.Done()
+		return false
+	}
+}
+
+// Run delivers the queued activities until the context is done.
+func (q *DeliveryQueue) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case delivery := <-q.deliveries:
+			q.deliver(ctx, delivery)
+		}
+	}
+}
+
+// Wait blocks until every enqueued delivery has succeeded or given up.
+func (q *DeliveryQueue) Wait() {
+	q.pending.Wait()
+}
+
+func (q *DeliveryQueue) deliver(ctx context.Context, delivery *Delivery) {
+	delivery.attempt++
+	err := q.client.Deliver(ctx, delivery.Inbox, delivery.KeyID, delivery.PrivateKey, delivery.Activity)
+	if err == nil {
+		q.pending.Done()
+		return
+	}
+	if q.OnError != nil {
+		q.OnError(delivery, err)
+	}
+	if delivery.attempt >= q.MaxAttempts || ctx.Err() != nil {
+		q.pending.Done()
+		return
+	}
+
+	delay := q.RetryInterval << (delivery.attempt - 1)
+	time.AfterFunc(delay, func() {
+		select {
+		case q.deliveries <- delivery:
+		default:
+			// Drop the retry rather than blocking when the queue is full.
+			q.pending.Done()
+		}
+	})
+}
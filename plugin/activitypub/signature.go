@@ -0,0 +1,153 @@
+package activitypub
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// MaxSignatureClockSkew is the max difference between the Date header of a signed request and the local clock.
+const MaxSignatureClockSkew = 12 * time.Hour
+
+// PublicKeyGetter returns the public key of the key ID of a signature.
+type PublicKeyGetter func(ctx context.Context, keyID string) (*rsa.PublicKey, error)
+
+// SignRequest signs the request with a rsa-sha256 HTTP signature (draft-cavage-http-signatures), as Mastodon does.
+// The body is digested and covered by the signature if not nil.
+func SignRequest(req *http.Request, keyID string, privateKey *rsa.PrivateKey, body []byte) error {
+	if req.Header.Get("Date") == "" {
+		req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+	headers := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		req.Header.Set("Digest", digestBody(body))
+		headers = append(headers, "digest")
+	}
+
+	signingString, err := buildSigningString(req, headers)
+	if err != nil {
+		return err
+	}
+	hashed := sha256.Sum256([]byte(signingString))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hashed[:])
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+// VerifyRequest verifies the HTTP signature of the request and returns the key ID of the signature.
+// Signed POST requests must cover the digest of the body.
+func VerifyRequest(ctx context.Context, req *http.Request, body []byte, getPublicKey PublicKeyGetter) (string, error) {
+	params, err := parseSignatureHeader(req.Header.Get("Signature"))
+	if err != nil {
+		return "", err
+	}
+	keyID := params["keyId"]
+	if keyID == "" {
+		return "", fmt.Errorf("signature key ID is missing")
+	}
+	if algorithm := params["algorithm"]; algorithm != "" && algorithm != "rsa-sha256" && algorithm != "hs2019" {
+		return "", fmt.Errorf("unsupported signature algorithm: %s", algorithm)
+	}
+	signature, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil || len(signature) == 0 {
+		return "", fmt.Errorf("invalid signature")
+	}
+
+	headers := []string{"date"}
+	if v := params["headers"]; v != "" {
+		headers = strings.Fields(strings.ToLower(v))
+	}
+	required := []string{"(request-target)", "date"}
+	if req.Method == http.MethodPost {
+		required = append(required, "digest")
+	}
+	for _, header := range required {
+		if !slices.Contains(headers, header) {
+			return "", fmt.Errorf("signature does not cover %s", header)
+		}
+	}
+
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil {
+		return "", fmt.Errorf("invalid date header: %s", req.Header.Get("Date"))
+	}
+	if skew := time.Since(date); skew > MaxSignatureClockSkew || skew < -MaxSignatureClockSkew {
+		return "", fmt.Errorf("date header is out of range: %s", req.Header.Get("Date"))
+	}
+	if slices.Contains(headers, "digest") && req.Header.Get("Digest") != digestBody(body) {
+		return "", fmt.Errorf("digest does not match the body")
+	}
+
+	signingString, err := buildSigningString(req, headers)
+	if err != nil {
+		return "", err
+	}
+	publicKey, err := getPublicKey(ctx, keyID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get public key %s, err: %w", keyID, err)
+	}
+	hashed := sha256.Sum256([]byte(signingString))
+	if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hashed[:], signature); err != nil {
+		return "", fmt.Errorf("signature verification failed")
+	}
+	return keyID, nil
+}
+
+func buildSigningString(req *http.Request, headers []string) (string, error) {
+	lines := make([]string, 0, len(headers))
+	for _, header := range headers {
+		var value string
+		switch header {
+		case "(request-target)":
+			value = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			// The Host header is moved to the request field by both clients and servers.
+			value = req.Host
+			if value == "" {
+				value = req.URL.Host
+			}
+		default:
+			values := req.Header.Values(header)
+			if len(values) == 0 {
+				return "", fmt.Errorf("signed header %s is missing", header)
+			}
+			value = strings.Join(values, ", ")
+		}
+		lines = append(lines, header+": "+value)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// parseSignatureHeader parses the comma separated key="value" parameters of the Signature header.
+func parseSignatureHeader(header string) (map[string]string, error) {
+	if header == "" {
+		return nil, fmt.Errorf("signature header is missing")
+	}
+	params := map[string]string{}
+	for _, param := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid signature header: %s", header)
+		}
+		params[key] = strings.Trim(value, `"`)
+	}
+	return params, nil
+}
+
+func digestBody(body []byte) string {
+	hash := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(hash[:])
+}
@@ -0,0 +1,181 @@
+// Package exif reads the GPS location from the EXIF metadata of JPEG images.
+//
+// Only the GPS latitude and longitude are read, the other EXIF tags are skipped.
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrNoLocation is returned when the image has no EXIF GPS location.
+var ErrNoLocation = errors.New("exif: no GPS location")
+
+// Location is a GPS location in decimal degrees, south and west are negative.
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+const (
+	markerStartOfImage = 0xD8
+	markerStartOfScan  = 0xDA
+	markerApp1         = 0xE1
+
+	tagGPSInfo      = 0x8825
+	tagGPSLatRef    = 0x0001
+	tagGPSLat       = 0x0002
+	tagGPSLongRef   = 0x0003
+	tagGPSLong      = 0x0004
+	typeASCII       = 2
+	typeRational    = 5
+	ifdEntrySize    = 12
+	rationalSize    = 8
+	maxIFDEntryList = 1024
+)
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// ExtractLocation finds the GPS location in the EXIF segment of the JPEG image, the data may be only the head of the image.
+func ExtractLocation(data []byte) (*Location, error) {
+	tiff, err := findTIFF(data)
+	if err != nil {
+		return nil, err
+	}
+	return parseTIFF(tiff)
+}
+
+// findTIFF finds the TIFF structure of the EXIF segment among the segments before the image data.
+func findTIFF(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != markerStartOfImage {
+		return nil, fmt.Errorf("exif: not a JPEG image")
+	}
+	offset := 2
+	for offset+4 <= len(data) {
+		if data[offset] != 0xFF {
+			return nil, fmt.Errorf("exif: invalid JPEG marker at %d", offset)
+		}
+		marker := data[offset+1]
+		if marker == markerStartOfScan {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		if length < 2 {
+			return nil, fmt.Errorf("exif: invalid JPEG segment length at %d", offset)
+		}
+		start, end := offset+4, offset+2+length
+		if end > len(data) {
+			break
+		}
+		if marker == markerApp1 && bytes.HasPrefix(data[start:end], exifHeader) {
+			return data[start+len(exifHeader) : end], nil
+		}
+		offset = end
+	}
+	return nil, ErrNoLocation
+}
+
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+func parseTIFF(data []byte) (*Location, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("exif: invalid TIFF header")
+	}
+	r := &tiffReader{data: data}
+	switch string(data[:2]) {
+	case "II":
+		r.order = binary.LittleEndian
+	case "MM":
+		r.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("exif: invalid TIFF byte order")
+	}
+	if r.order.Uint16(data[2:]) != 42 {
+		return nil, fmt.Errorf("exif: invalid TIFF header")
+	}
+
+	ifd0, err := r.readIFD(r.order.Uint32(data[4:]))
+	if err != nil {
+		return nil, err
+	}
+	gpsInfo, ok := ifd0[tagGPSInfo]
+	if !ok {
+		return nil, ErrNoLocation
+	}
+	gpsIFD, err := r.readIFD(r.order.Uint32(gpsInfo[8:]))
+	if err != nil {
+		return nil, err
+	}
+
+	latitude, err := r.readCoordinate(gpsIFD, tagGPSLat, tagGPSLatRef, 'S')
+	if err != nil {
+		return nil, err
+	}
+	longitude, err := r.readCoordinate(gpsIFD, tagGPSLong, tagGPSLongRef, 'W')
+	if err != nil {
+		return nil, err
+	}
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return nil, fmt.Errorf("exif: GPS location out of range")
+	}
+	return &Location{
+		Latitude:  latitude,
+		Longitude: longitude,
+	}, nil
+}
+
+// readIFD reads the entries of the image file directory at the offset, keyed by tag.
+func (r *tiffReader) readIFD(offset uint32) (map[uint16][]byte, error) {
+	if uint64(offset)+2 > uint64(len(r.data)) {
+		return nil, fmt.Errorf("exif: IFD offset out of range")
+	}
+	count := int(r.order.Uint16(r.data[offset:]))
+	if count > maxIFDEntryList {
+		return nil, fmt.Errorf("exif: too many IFD entries")
+	}
+	start := int(offset) + 2
+	if start+count*ifdEntrySize > len(r.data) {
+		return nil, fmt.Errorf("exif: IFD out of range")
+	}
+	entries := map[uint16][]byte{}
+	for i := 0; i < count; i++ {
+		entry := r.data[start+i*ifdEntrySize : start+(i+1)*ifdEntrySize]
+		entries[r.order.Uint16(entry)] = entry
+	}
+	return entries, nil
+}
+
+// readCoordinate reads the degrees, minutes and seconds of a coordinate, negated by the negative reference.
+func (r *tiffReader) readCoordinate(ifd map[uint16][]byte, tag, refTag uint16, negativeRef byte) (float64, error) {
+	entry, ok := ifd[tag]
+	if !ok {
+		return 0, ErrNoLocation
+	}
+	if r.order.Uint16(entry[2:]) != typeRational || r.order.Uint32(entry[4:]) != 3 {
+		return 0, fmt.Errorf("exif: invalid GPS coordinate type")
+	}
+	offset := uint64(r.order.Uint32(entry[8:]))
+	if offset+3*rationalSize > uint64(len(r.data)) {
+		return 0, fmt.Errorf("exif: GPS coordinate out of range")
+	}
+	coordinate := 0.0
+	for i, unit := range []float64{1, 60, 3600} {
+		value := r.data[offset+uint64(i*rationalSize):]
+		numerator, denominator := r.order.Uint32(value), r.order.Uint32(value[4:])
+		if denominator == 0 {
+			return 0, fmt.Errorf("exif: invalid GPS coordinate")
+		}
+		coordinate += float64(numerator) / float64(denominator) / unit
+	}
+
+	// The reference is an ASCII letter with its terminating NUL, which fits in the entry.
+	if ref, ok := ifd[refTag]; ok && r.order.Uint16(ref[2:]) == typeASCII && ref[8] == negativeRef {
+		coordinate = -coordinate
+	}
+	return coordinate, nil
+}
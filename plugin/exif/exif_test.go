@@ -0,0 +1,91 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// buildJPEG builds a JPEG head with an EXIF segment holding the GPS IFD of the coordinates.
+func buildJPEG(order binary.ByteOrder, latRef, longRef string, lat, long [3][2]uint32) []byte {
+	var tiff bytes.Buffer
+	write := func(values ...any) {
+		for _, v := range values {
+			_ = binary.Write(&tiff, order, v)
+		}
+	}
+	if order == binary.LittleEndian {
+		tiff.WriteString("II")
+	} else {
+		tiff.WriteString("MM")
+	}
+	write(uint16(42))
+	write(uint32(8))
+
+	// IFD0 at 8 with the GPS IFD pointer, the GPS IFD follows at 26.
+	write(uint16(1))
+	write(uint16(tagGPSInfo), uint16(4), uint32(1), uint32(26))
+	write(uint32(0))
+
+	// The GPS IFD has 4 entries, the rationals follow at 26+2+4*12+4=80.
+	write(uint16(4))
+	write(uint16(tagGPSLatRef), uint16(typeASCII), uint32(2))
+	tiff.WriteString(latRef + "\x00\x00\x00")
+	write(uint16(tagGPSLat), uint16(typeRational), uint32(3), uint32(80))
+	write(uint16(tagGPSLongRef), uint16(typeASCII), uint32(2))
+	tiff.WriteString(longRef + "\x00\x00\x00")
+	write(uint16(tagGPSLong), uint16(typeRational), uint32(3), uint32(104))
+	write(uint32(0))
+	for _, rational := range append(lat[:], long[:]...) {
+		write(rational[0])
+		write(rational[1])
+	}
+
+	var jpeg bytes.Buffer
+	jpeg.Write([]byte{0xFF, markerStartOfImage})
+	// An APP0 segment comes before the EXIF segment.
+	jpeg.Write([]byte{0xFF, 0xE0, 0x00, 0x04, 0x00, 0x00})
+	jpeg.Write([]byte{0xFF, markerApp1})
+	_ = binary.Write(&jpeg, binary.BigEndian, uint16(2+len(exifHeader)+tiff.Len()))
+	jpeg.Write(exifHeader)
+	jpeg.Write(tiff.Bytes())
+	jpeg.Write([]byte{0xFF, markerStartOfScan})
+	return jpeg.Bytes()
+}
+
+func TestExtractLocation(t *testing.T) {
+	// 48°51'30" N, 2°17'40.2" E
+	lat := [3][2]uint32{{48, 1}, {51, 1}, {30, 1}}
+	long := [3][2]uint32{{2, 1}, {17, 1}, {402, 10}}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		location, err := ExtractLocation(buildJPEG(order, "N", "E", lat, long))
+		require.NoError(t, err)
+		require.InDelta(t, 48.858333, location.Latitude, 1e-6)
+		require.InDelta(t, 2.294500, location.Longitude, 1e-6)
+	}
+
+	location, err := ExtractLocation(buildJPEG(binary.BigEndian, "S", "W", lat, long))
+	require.NoError(t, err)
+	require.InDelta(t, -48.858333, location.Latitude, 1e-6)
+	require.InDelta(t, -2.294500, location.Longitude, 1e-6)
+}
+
+func TestExtractLocationError(t *testing.T) {
+	_, err := ExtractLocation([]byte("\x89PNG\r\n"))
+	require.Error(t, err)
+
+	_, err = ExtractLocation([]byte{0xFF, markerStartOfImage, 0xFF, markerStartOfScan})
+	require.ErrorIs(t, err, ErrNoLocation)
+
+	zero := [3][2]uint32{{1, 0}, {0, 1}, {0, 1}}
+	_, err = ExtractLocation(buildJPEG(binary.LittleEndian, "N", "E", zero, zero))
+	require.Error(t, err)
+
+	// A truncated segment is not read.
+	data := buildJPEG(binary.LittleEndian, "N", "E", [3][2]uint32{{1, 1}, {0, 1}, {0, 1}}, [3][2]uint32{{1, 1}, {0, 1}, {0, 1}})
+	_, err = ExtractLocation(data[:40])
+	require.ErrorIs(t, err, ErrNoLocation)
+}
@@ -1,6 +1,8 @@
 package idp
 
 type IdentityProviderUserInfo struct {
+	// Subject is the stable ID of the user at the identity provider, the Identifier is used when it is empty.
+	Subject     string
 	Identifier  string
 	DisplayName string
 	Email       string
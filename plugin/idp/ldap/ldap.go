@@ -0,0 +1,145 @@
+// Package ldap is the plugin for LDAP Identity Provider.
+package ldap
+
+import (
+	"crypto/tls"
+	"strings"
+
+	"uamemos/plugin/idp"
+	"uamemos/store"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+)
+
+// usernamePlaceholder is replaced with the escaped username in the user filter.
+const usernamePlaceholder = "{username}"
+
+// ErrInvalidCredentials is returned when the user is not found or the password does not match.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// IdentityProvider represents an LDAP Identity Provider.
+type IdentityProvider struct {
+	config *store.IdentityProviderLDAPConfig
+}
+
+// NewIdentityProvider initializes a new LDAP Identity Provider with the given configuration.
+func NewIdentityProvider(config *store.IdentityProviderLDAPConfig) (*IdentityProvider, error) {
+	if config.FieldMapping == nil {
+		return nil, errors.New(`the field "fieldMapping" is empty but required`)
+	}
+	for v, field := range map[string]string{
+		config.URL:                     "url",
+		config.BaseDN:                  "baseDn",
+		config.UserFilter:              "userFilter",
+		config.FieldMapping.Identifier: "fieldMapping.identifier",
+	} {
+		if v == "" {
+			return nil, errors.Errorf(`the field "%s" is empty but required`, field)
+		}
+	}
+	if !strings.Contains(config.UserFilter, usernamePlaceholder) {
+		return nil, errors.Errorf(`the field "userFilter" must contain the %q placeholder`, usernamePlaceholder)
+	}
+
+	return &IdentityProvider{
+		config: config,
+	}, nil
+}
+
+// Authenticate searches the directory for the given username, binds as the found entry
+// with the given password and returns the mapped user information.
+func (p *IdentityProvider) Authenticate(username, password string) (*idp.IdentityProviderUserInfo, error) {
+	// Most directory servers treat a bind with an empty password as an anonymous bind,
+	// which would succeed for any existing user.
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := p.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if p.config.BindDN != "" {
+		if err := conn.Bind(p.config.BindDN, p.config.BindPassword); err != nil {
+			return nil, errors.Wrap(err, "failed to bind with service account")
+		}
+	}
+
+	attributes := []string{p.config.FieldMapping.Identifier}
+	if p.config.FieldMapping.DisplayName != "" {
+		attributes = append(attributes, p.config.FieldMapping.DisplayName)
+	}
+	if p.config.FieldMapping.Email != "" {
+		attributes = append(attributes, p.config.FieldMapping.Email)
+	}
+	searchRequest := ldap.NewSearchRequest(
+		p.config.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		// Ask for two entries so that an ambiguous filter can be detected.
+		2,
+		0,
+		false,
+		strings.ReplaceAll(p.config.UserFilter, usernamePlaceholder, ldap.EscapeFilter(username)),
+		attributes,
+		nil,
+	)
+	result, err := conn.Search(searchRequest)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, errors.Wrap(err, "failed to search user")
+	}
+	if result == nil || len(result.Entries) == 0 {
+		return nil, ErrInvalidCredentials
+	}
+	if len(result.Entries) > 1 {
+		return nil, errors.Errorf("the user filter matches more than one entry for username %q", username)
+	}
+
+	entry := result.Entries[0]
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, errors.Wrap(err, "failed to bind as user")
+	}
+
+	userInfo := &idp.IdentityProviderUserInfo{
+		Identifier: entry.GetAttributeValue(p.config.FieldMapping.Identifier),
+	}
+	if userInfo.Identifier == "" {
+		return nil, errors.Errorf("the attribute %q is not found in entry or has empty value", p.config.FieldMapping.Identifier)
+	}
+
+	// Best effort to map optional fields
+	if p.config.FieldMapping.DisplayName != "" {
+		userInfo.DisplayName = entry.GetAttributeValue(p.config.FieldMapping.DisplayName)
+	}
+	if userInfo.DisplayName == "" {
+		userInfo.DisplayName = userInfo.Identifier
+	}
+	if p.config.FieldMapping.Email != "" {
+		userInfo.Email = entry.GetAttributeValue(p.config.FieldMapping.Email)
+	}
+	return userInfo, nil
+}
+
+func (p *IdentityProvider) dial() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{
+		// nolint:gosec
+		InsecureSkipVerify: p.config.InsecureSkipVerify,
+	}
+	conn, err := ldap.DialURL(p.config.URL, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to ldap server")
+	}
+	if p.config.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "failed to start tls")
+		}
+	}
+	return conn, nil
+}
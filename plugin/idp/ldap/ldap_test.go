@@ -0,0 +1,297 @@
+package ldap
+
+import (
+	"net"
+	"testing"
+
+	"uamemos/plugin/idp"
+	"uamemos/store"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewIdentityProvider(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *store.IdentityProviderLDAPConfig
+		containsErr string
+	}{
+		{
+			name: "no url",
+			config: &store.IdentityProviderLDAPConfig{
+				BaseDN:     "ou=people,dc=example,dc=com",
+				UserFilter: "(uid={username})",
+				FieldMapping: &store.FieldMapping{
+					Identifier: "uid",
+				},
+			},
+			containsErr: `the field "url" is empty but required`,
+		},
+		{
+			name: "no baseDn",
+			config: &store.IdentityProviderLDAPConfig{
+				URL:        "ldap://127.0.0.1:389",
+				UserFilter: "(uid={username})",
+				FieldMapping: &store.FieldMapping{
+					Identifier: "uid",
+				},
+			},
+			containsErr: `the field "baseDn" is empty but required`,
+		},
+		{
+			name: "no field mapping",
+			config: &store.IdentityProviderLDAPConfig{
+				URL:        "ldap://127.0.0.1:389",
+				BaseDN:     "ou=people,dc=example,dc=com",
+				UserFilter: "(uid={username})",
+			},
+			containsErr: `the field "fieldMapping" is empty but required`,
+		},
+		{
+			name: "no username placeholder",
+			config: &store.IdentityProviderLDAPConfig{
+				URL:        "ldap://127.0.0.1:389",
+				BaseDN:     "ou=people,dc=example,dc=com",
+				UserFilter: "(uid=*)",
+				FieldMapping: &store.FieldMapping{
+					Identifier: "uid",
+				},
+			},
+			containsErr: `the field "userFilter" must contain the "{username}" placeholder`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewIdentityProvider(test.config)
+			assert.ErrorContains(t, err, test.containsErr)
+		})
+	}
+}
+
+type mockEntry struct {
+	dn         string
+	password   string
+	attributes map[string]string
+}
+
+// mockServer is an in-process stand-in for a directory server. It understands simple binds
+// and searches whose filter matches the "(uid=...)" filter of an entry exactly.
+type mockServer struct {
+	t        *testing.T
+	listener net.Listener
+	baseDN   string
+	entries  []*mockEntry
+}
+
+func newMockServer(t *testing.T, baseDN string, entries ...*mockEntry) *mockServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &mockServer{
+		t:        t,
+		listener: listener,
+		baseDN:   baseDN,
+		entries:  entries,
+	}
+	go s.serve()
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	return s
+}
+
+func (s *mockServer) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *mockServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *mockServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		messageID := packet.Children[0].Value.(int64)
+		request := packet.Children[1]
+		switch request.Tag {
+		case ldap.ApplicationBindRequest:
+			name := request.Children[1].Value.(string)
+			password := request.Children[2].Data.String()
+			resultCode := uint16(ldap.LDAPResultInvalidCredentials)
+			for _, entry := range s.entries {
+				if entry.dn == name && entry.password == password {
+					resultCode = ldap.LDAPResultSuccess
+				}
+			}
+			s.write(conn, messageID, newResult(ldap.ApplicationBindResponse, resultCode))
+		case ldap.ApplicationSearchRequest:
+			baseDN := request.Children[0].Value.(string)
+			filter, err := ldap.DecompileFilter(request.Children[6])
+			require.NoError(s.t, err)
+			for _, entry := range s.entries {
+				if baseDN != s.baseDN || filter != "(uid="+entry.attributes["uid"]+")" {
+					continue
+				}
+				s.write(conn, messageID, newSearchResultEntry(entry))
+			}
+			s.write(conn, messageID, newResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+		case ldap.ApplicationUnbindRequest:
+			return
+		}
+	}
+}
+
+func (s *mockServer) write(conn net.Conn, messageID int64, response *ber.Packet) {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+	packet.AppendChild(response)
+	_, err := conn.Write(packet.Bytes())
+	require.NoError(s.t, err)
+}
+
+func newResult(tag ber.Tag, resultCode uint16) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(resultCode), "Result Code"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return packet
+}
+
+func newSearchResultEntry(entry *mockEntry) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "Object Name"))
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for name, value := range entry.attributes {
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		attribute.AppendChild(values)
+		attributes.AppendChild(attribute)
+	}
+	packet.AppendChild(attributes)
+	return packet
+}
+
+func TestIdentityProvider(t *testing.T) {
+	const (
+		testBaseDN       = "ou=people,dc=example,dc=com"
+		testBindDN       = "cn=memos,dc=example,dc=com"
+		testBindPassword = "test-bind-password"
+	)
+	s := newMockServer(t, testBaseDN,
+		&mockEntry{
+			dn:       testBindDN,
+			password: testBindPassword,
+		},
+		&mockEntry{
+			dn:       "uid=alice,ou=people,dc=example,dc=com",
+			password: "alice-password",
+			attributes: map[string]string{
+				"uid":  "alice",
+				"cn":   "Alice Liddell",
+				"mail": "alice@example.com",
+			},
+		},
+		&mockEntry{
+			dn:       "uid=bob,ou=people,dc=example,dc=com",
+			password: "bob-password",
+			attributes: map[string]string{
+				"uid": "bob",
+			},
+		},
+	)
+
+	ldapIdentityProvider, err := NewIdentityProvider(
+		&store.IdentityProviderLDAPConfig{
+			URL:          s.URL(),
+			BindDN:       testBindDN,
+			BindPassword: testBindPassword,
+			BaseDN:       testBaseDN,
+			UserFilter:   "(uid={username})",
+			FieldMapping: &store.FieldMapping{
+				Identifier:  "uid",
+				DisplayName: "cn",
+				Email:       "mail",
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	t.Run("valid credentials", func(t *testing.T) {
+		userInfo, err := ldapIdentityProvider.Authenticate("alice", "alice-password")
+		require.NoError(t, err)
+		assert.Equal(t, &idp.IdentityProviderUserInfo{
+			Identifier:  "alice",
+			DisplayName: "Alice Liddell",
+			Email:       "alice@example.com",
+		}, userInfo)
+	})
+
+	t.Run("missing optional attributes", func(t *testing.T) {
+		userInfo, err := ldapIdentityProvider.Authenticate("bob", "bob-password")
+		require.NoError(t, err)
+		assert.Equal(t, &idp.IdentityProviderUserInfo{
+			Identifier:  "bob",
+			DisplayName: "bob",
+		}, userInfo)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		_, err := ldapIdentityProvider.Authenticate("alice", "bob-password")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("empty password", func(t *testing.T) {
+		_, err := ldapIdentityProvider.Authenticate("alice", "")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		_, err := ldapIdentityProvider.Authenticate("carol", "carol-password")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("filter injection", func(t *testing.T) {
+		_, err := ldapIdentityProvider.Authenticate("*", "alice-password")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+}
+
+func TestIdentityProviderServiceAccount(t *testing.T) {
+	const testBaseDN = "ou=people,dc=example,dc=com"
+	s := newMockServer(t, testBaseDN)
+
+	ldapIdentityProvider, err := NewIdentityProvider(
+		&store.IdentityProviderLDAPConfig{
+			URL:          s.URL(),
+			BindDN:       "cn=memos,dc=example,dc=com",
+			BindPassword: "wrong-password",
+			BaseDN:       testBaseDN,
+			UserFilter:   "(uid={username})",
+			FieldMapping: &store.FieldMapping{
+				Identifier: "uid",
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	_, err = ldapIdentityProvider.Authenticate("alice", "alice-password")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidCredentials)
+	assert.ErrorContains(t, err, "failed to bind with service account")
+}
@@ -0,0 +1,179 @@
+// Package oidc is the plugin for OpenID Connect Identity Provider.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+
+	"uamemos/plugin/idp"
+	"uamemos/store"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// IdentityProvider represents an OpenID Connect Identity Provider.
+type IdentityProvider struct {
+	config   *store.IdentityProviderOIDCConfig
+	provider *oidc.Provider
+}
+
+// NewIdentityProvider initializes a new OpenID Connect Identity Provider with the given configuration.
+// The provider metadata is fetched from the issuer's .well-known/openid-configuration document.
+func NewIdentityProvider(ctx context.Context, config *store.IdentityProviderOIDCConfig) (*IdentityProvider, error) {
+	if config.FieldMapping == nil {
+		return nil, errors.New(`the field "fieldMapping" is empty but required`)
+	}
+	for v, field := range map[string]string{
+		config.Issuer:                  "issuer",
+		config.ClientID:                "clientId",
+		config.FieldMapping.Identifier: "fieldMapping.identifier",
+	} {
+		if v == "" {
+			return nil, errors.Errorf(`the field "%s" is empty but required`, field)
+		}
+	}
+
+	provider, err := oidc.NewProvider(ctx, config.Issuer)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to discover provider")
+	}
+
+	return &IdentityProvider{
+		config:   config,
+		provider: provider,
+	}, nil
+}
+
+func (p *IdentityProvider) oauth2Config(redirectURL string) *oauth2.Config {
+	scopes := p.config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+	return &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       scopes,
+		Endpoint:     p.provider.Endpoint(),
+	}
+}
+
+// AuthCodeURL returns the URL of the provider's consent page. The nonce is bound to the ID token
+// and the code verifier is sent as an S256 PKCE challenge.
+func (p *IdentityProvider) AuthCodeURL(redirectURL, state, nonce, codeVerifier string) string {
+	return p.oauth2Config(redirectURL).AuthCodeURL(
+		state,
+		oidc.Nonce(nonce),
+		oauth2.SetAuthURLParam("code_challenge", codeChallenge(codeVerifier)),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+}
+
+// ExchangeToken returns the exchanged OAuth2 token using the given authorization code and PKCE code verifier.
+func (p *IdentityProvider) ExchangeToken(ctx context.Context, redirectURL, code, codeVerifier string) (*oauth2.Token, error) {
+	token, err := p.oauth2Config(redirectURL).Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", codeVerifier))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to exchange access token")
+	}
+	return token, nil
+}
+
+// UserInfo verifies the ID token of the given OAuth2 token and returns the parsed user information.
+// Claims are taken from the ID token, the userinfo endpoint is only queried for mapped claims it lacks.
+func (p *IdentityProvider) UserInfo(ctx context.Context, token *oauth2.Token, nonce string) (*idp.IdentityProviderUserInfo, error) {
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New(`missing "id_token" from token response`)
+	}
+
+	// Verifies signature against the provider's JWKS, issuer, audience and expiry.
+	idToken, err := p.provider.Verifier(&oidc.Config{ClientID: p.config.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to verify id token")
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("id token nonce mismatch")
+	}
+
+	claims := map[string]any{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, errors.Wrap(err, "failed to parse id token claims")
+	}
+
+	fieldMapping := p.config.FieldMapping
+	if p.missingClaim(claims, fieldMapping.Identifier, fieldMapping.DisplayName, fieldMapping.Email) && p.provider.UserInfoEndpoint() != "" {
+		userInfo, err := p.provider.UserInfo(ctx, oauth2.StaticTokenSource(token))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get user information")
+		}
+		// The userinfo response must describe the same end-user as the ID token.
+		if userInfo.Subject != idToken.Subject {
+			return nil, errors.New("userinfo subject does not match id token subject")
+		}
+		userInfoClaims := map[string]any{}
+		if err := userInfo.Claims(&userInfoClaims); err != nil {
+			return nil, errors.Wrap(err, "failed to parse userinfo claims")
+		}
+		for key, value := range userInfoClaims {
+			if _, ok := claims[key]; !ok {
+				claims[key] = value
+			}
+		}
+	}
+
+	userInfo := &idp.IdentityProviderUserInfo{
+		Subject: idToken.Subject,
+	}
+	if v, ok := claims[fieldMapping.Identifier].(string); ok {
+		userInfo.Identifier = v
+	}
+	if userInfo.Identifier == "" {
+		return nil, errors.Errorf("the field %q is not found in claims or has empty value", fieldMapping.Identifier)
+	}
+
+	// Best effort to map optional fields
+	if fieldMapping.DisplayName != "" {
+		if v, ok := claims[fieldMapping.DisplayName].(string); ok {
+			userInfo.DisplayName = v
+		}
+	}
+	if userInfo.DisplayName == "" {
+		userInfo.DisplayName = userInfo.Identifier
+	}
+	if fieldMapping.Email != "" {
+		if v, ok := claims[fieldMapping.Email].(string); ok {
+			userInfo.Email = v
+		}
+	}
+	return userInfo, nil
+}
+
+func (*IdentityProvider) missingClaim(claims map[string]any, keys ...string) bool {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if v, ok := claims[key].(string); !ok || v == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateCodeVerifier returns a random PKCE code verifier.
+func GenerateCodeVerifier() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func codeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
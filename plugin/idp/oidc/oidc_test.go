@@ -0,0 +1,297 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"uamemos/plugin/idp"
+	"uamemos/store"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestNewIdentityProvider(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *store.IdentityProviderOIDCConfig
+		containsErr string
+	}{
+		{
+			name: "no issuer",
+			config: &store.IdentityProviderOIDCConfig{
+				ClientID: "test-client-id",
+				FieldMapping: &store.FieldMapping{
+					Identifier: "preferred_username",
+				},
+			},
+			containsErr: `the field "issuer" is empty but required`,
+		},
+		{
+			name: "no clientId",
+			config: &store.IdentityProviderOIDCConfig{
+				Issuer: "https://accounts.example.com",
+				FieldMapping: &store.FieldMapping{
+					Identifier: "preferred_username",
+				},
+			},
+			containsErr: `the field "clientId" is empty but required`,
+		},
+		{
+			name: "no field mapping",
+			config: &store.IdentityProviderOIDCConfig{
+				Issuer:   "https://accounts.example.com",
+				ClientID: "test-client-id",
+			},
+			containsErr: `the field "fieldMapping" is empty but required`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewIdentityProvider(context.Background(), test.config)
+			assert.ErrorContains(t, err, test.containsErr)
+		})
+	}
+}
+
+// mockProvider is an in-process OpenID Provider serving discovery, JWKS, token and userinfo endpoints.
+type mockProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	// The claims and signing key of the next issued ID token.
+	idTokenClaims map[string]any
+	signingKey    *rsa.PrivateKey
+	userInfo      map[string]any
+	codeVerifier  string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	p := &mockProvider{
+		t:   t,
+		key: key,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		p.writeJSON(w, map[string]any{
+			"issuer":                                p.server.URL,
+			"authorization_endpoint":                p.server.URL + "/authorize",
+			"token_endpoint":                        p.server.URL + "/token",
+			"userinfo_endpoint":                     p.server.URL + "/userinfo",
+			"jwks_uri":                              p.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		p.writeJSON(w, jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{
+				{Key: &p.key.PublicKey, KeyID: "test-key", Algorithm: "RS256", Use: "sig"},
+			},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		p.codeVerifier = r.PostForm.Get("code_verifier")
+		if r.PostForm.Get("code") != "test-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			p.writeJSON(w, map[string]any{"error": "invalid_grant"})
+			return
+		}
+		p.writeJSON(w, map[string]any{
+			"access_token": "test-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     p.sign(),
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		p.writeJSON(w, p.userInfo)
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *mockProvider) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	require.NoError(p.t, json.NewEncoder(w).Encode(v))
+}
+
+func (p *mockProvider) sign() string {
+	signingKey := p.signingKey
+	if signingKey == nil {
+		signingKey = p.key
+	}
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: signingKey, KeyID: "test-key"}},
+		(&jose.SignerOptions{}).WithType("JWT"),
+	)
+	require.NoError(p.t, err)
+	payload, err := json.Marshal(p.idTokenClaims)
+	require.NoError(p.t, err)
+	object, err := signer.Sign(payload)
+	require.NoError(p.t, err)
+	raw, err := object.CompactSerialize()
+	require.NoError(p.t, err)
+	return raw
+}
+
+func (p *mockProvider) claims(overrides map[string]any) map[string]any {
+	claims := map[string]any{
+		"iss":                p.server.URL,
+		"sub":                "user-1",
+		"aud":                "test-client-id",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              "test-nonce",
+		"preferred_username": "alice",
+		"name":               "Alice Liddell",
+		"email":              "alice@example.com",
+	}
+	for key, value := range overrides {
+		if value == nil {
+			delete(claims, key)
+			continue
+		}
+		claims[key] = value
+	}
+	return claims
+}
+
+func TestIdentityProvider(t *testing.T) {
+	ctx := context.Background()
+	p := newMockProvider(t)
+
+	oidcIdentityProvider, err := NewIdentityProvider(ctx, &store.IdentityProviderOIDCConfig{
+		Issuer:       p.server.URL,
+		ClientID:     "test-client-id",
+		ClientSecret: "test-client-secret",
+		FieldMapping: &store.FieldMapping{
+			Identifier:  "preferred_username",
+			DisplayName: "name",
+			Email:       "email",
+		},
+	})
+	require.NoError(t, err)
+
+	const redirectURL = "https://memos.example.com/auth/callback"
+	codeVerifier, err := GenerateCodeVerifier()
+	require.NoError(t, err)
+
+	t.Run("auth code url", func(t *testing.T) {
+		authURL, err := url.Parse(oidcIdentityProvider.AuthCodeURL(redirectURL, "test-state", "test-nonce", codeVerifier))
+		require.NoError(t, err)
+		query := authURL.Query()
+		assert.Equal(t, p.server.URL+"/authorize", authURL.Scheme+"://"+authURL.Host+authURL.Path)
+		assert.Equal(t, "test-state", query.Get("state"))
+		assert.Equal(t, "test-nonce", query.Get("nonce"))
+		assert.Equal(t, "openid profile email", query.Get("scope"))
+		assert.Equal(t, "S256", query.Get("code_challenge_method"))
+		assert.Equal(t, codeChallenge(codeVerifier), query.Get("code_challenge"))
+	})
+
+	exchange := func(t *testing.T) *oauth2.Token {
+		token, err := oidcIdentityProvider.ExchangeToken(ctx, redirectURL, "test-code", codeVerifier)
+		require.NoError(t, err)
+		assert.Equal(t, codeVerifier, p.codeVerifier)
+		return token
+	}
+
+	t.Run("valid id token", func(t *testing.T) {
+		p.idTokenClaims = p.claims(nil)
+		userInfo, err := oidcIdentityProvider.UserInfo(ctx, exchange(t), "test-nonce")
+		require.NoError(t, err)
+		assert.Equal(t, &idp.IdentityProviderUserInfo{
+			Subject:     "user-1",
+			Identifier:  "alice",
+			DisplayName: "Alice Liddell",
+			Email:       "alice@example.com",
+		}, userInfo)
+	})
+
+	t.Run("userinfo fallback", func(t *testing.T) {
+		p.idTokenClaims = p.claims(map[string]any{"name": nil, "email": nil})
+		p.userInfo = map[string]any{
+			"sub":   "user-1",
+			"name":  "Alice from userinfo",
+			"email": "alice@userinfo.example.com",
+		}
+		userInfo, err := oidcIdentityProvider.UserInfo(ctx, exchange(t), "test-nonce")
+		require.NoError(t, err)
+		assert.Equal(t, &idp.IdentityProviderUserInfo{
+			Subject:     "user-1",
+			Identifier:  "alice",
+			DisplayName: "Alice from userinfo",
+			Email:       "alice@userinfo.example.com",
+		}, userInfo)
+	})
+
+	t.Run("userinfo subject mismatch", func(t *testing.T) {
+		p.idTokenClaims = p.claims(map[string]any{"email": nil})
+		p.userInfo = map[string]any{
+			"sub":   "user-2",
+			"email": "mallory@example.com",
+		}
+		_, err := oidcIdentityProvider.UserInfo(ctx, exchange(t), "test-nonce")
+		assert.ErrorContains(t, err, "userinfo subject does not match")
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		p.idTokenClaims = p.claims(nil)
+		_, err := oidcIdentityProvider.UserInfo(ctx, exchange(t), "another-nonce")
+		assert.ErrorContains(t, err, "nonce mismatch")
+	})
+
+	t.Run("wrong audience", func(t *testing.T) {
+		p.idTokenClaims = p.claims(map[string]any{"aud": "another-client-id"})
+		_, err := oidcIdentityProvider.UserInfo(ctx, exchange(t), "test-nonce")
+		assert.ErrorContains(t, err, "failed to verify id token")
+	})
+
+	t.Run("wrong issuer", func(t *testing.T) {
+		p.idTokenClaims = p.claims(map[string]any{"iss": "https://evil.example.com"})
+		_, err := oidcIdentityProvider.UserInfo(ctx, exchange(t), "test-nonce")
+		assert.ErrorContains(t, err, "failed to verify id token")
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		p.idTokenClaims = p.claims(map[string]any{"exp": time.Now().Add(-time.Hour).Unix()})
+		_, err := oidcIdentityProvider.UserInfo(ctx, exchange(t), "test-nonce")
+		assert.ErrorContains(t, err, "failed to verify id token")
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		p.signingKey = key
+		defer func() {
+			p.signingKey = nil
+		}()
+		p.idTokenClaims = p.claims(nil)
+		_, err = oidcIdentityProvider.UserInfo(ctx, exchange(t), "test-nonce")
+		assert.ErrorContains(t, err, "failed to verify id token")
+	})
+
+	t.Run("missing id token", func(t *testing.T) {
+		token := (&oauth2.Token{AccessToken: "test-access-token"}).WithExtra(map[string]any{})
+		_, err := oidcIdentityProvider.UserInfo(ctx, token, "test-nonce")
+		assert.ErrorContains(t, err, `missing "id_token"`)
+	})
+}
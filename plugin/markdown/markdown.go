@@ -0,0 +1,360 @@
+// Package markdown parses the Markdown dialect of memos:
+// * CommonMark with the GitHub flavored tables, strikethroughs, autolinks and task lists;
+// * tags such as #work or the nested #work/projectA;
+// * embedded memos and resources such as ![[memo:1]] and ![[resource:1]].
+//
+// Tags and embeds are not parsed in code spans and code blocks.
+package markdown
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// md renders line breaks as memos do, raw HTML and dangerous link destinations are omitted as goldmark does by default.
+var md = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, &memoExtension{}),
+	goldmark.WithRendererOptions(html.WithHardWraps()),
+)
+
+// Parse parses the content into an AST, the segments of the nodes refer to the source.
+func Parse(source []byte) ast.Node {
+	return md.Parser().Parse(text.NewReader(source))
+}
+
+// RenderHTML renders the content into sanitized HTML.
+func RenderHTML(source []byte) (string, error) {
+	var buf bytes.Buffer
+	if err := md.Convert(source, &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// ExtractTags returns the distinct tag names in the content, sorted by name.
+func ExtractTags(source []byte) []string {
+	tagSet := map[string]bool{}
+	walk(Parse(source), func(node ast.Node) {
+		if tag, ok := node.(*Tag); ok {
+			tagSet[tag.Name] = true
+		}
+	})
+	tagList := []string{}
+	for tag := range tagSet {
+		tagList = append(tagList, tag)
+	}
+	sort.Strings(tagList)
+	return tagList
+}
+
+// ReplaceTags replaces the tags for which replace returns a new name, the rest of the content is kept as is.
+func ReplaceTags(source []byte, replace func(name string) (string, bool)) []byte {
+	segments := []text.Segment{}
+	names := []string{}
+	walk(Parse(source), func(node ast.Node) {
+		if tag, ok := node.(*Tag); ok {
+			if name, ok := replace(tag.Name); ok {
+				segments = append(segments, tag.Segment)
+				names = append(names, name)
+			}
+		}
+	})
+
+	var buf bytes.Buffer
+	last := 0
+	for i, segment := range segments {
+		buf.Write(source[last:segment.Start])
+		buf.WriteString("#" + names[i])
+		last = segment.Stop
+	}
+	buf.Write(source[last:])
+	return buf.Bytes()
+}
+
+// ExtractEmbeddedMemoIDs returns the distinct IDs of the embedded memos in order of appearance.
+func ExtractEmbeddedMemoIDs(source []byte) []int {
+	idList := []int{}
+	walk(Parse(source), func(node ast.Node) {
+		if embeddedMemo, ok := node.(*EmbeddedMemo); ok {
+			for _, id := range idList {
+				if id == embeddedMemo.MemoID {
+					return
+				}
+			}
+			idList = append(idList, embeddedMemo.MemoID)
+		}
+	})
+	return idList
+}
+
+// ExtractEmbeddedResourceIDs returns the distinct IDs of the embedded resources in order of appearance.
+func ExtractEmbeddedResourceIDs(source []byte) []int {
+	idList := []int{}
+	walk(Parse(source), func(node ast.Node) {
+		if embeddedResource, ok := node.(*EmbeddedResource); ok {
+			for _, id := range idList {
+				if id == embeddedResource.ResourceID {
+					return
+				}
+			}
+			idList = append(idList, embeddedResource.ResourceID)
+		}
+	})
+	return idList
+}
+
+// ExtractLinks returns the destinations of the links and autolinks in order of appearance.
+func ExtractLinks(source []byte) []string {
+	linkList := []string{}
+	walk(Parse(source), func(node ast.Node) {
+		switch n := node.(type) {
+		case *ast.Link:
+			linkList = append(linkList, string(n.Destination))
+		case *ast.AutoLink:
+			linkList = append(linkList, string(n.URL(source)))
+		}
+	})
+	return linkList
+}
+
+// IsValidTagName reports whether the name is parsed as a whole tag, nested tag names have no empty segment.
+func IsValidTagName(name string) bool {
+	if name == "" || name[0] == '/' || name[len(name)-1] == '/' || bytes.Contains([]byte(name), []byte("//")) {
+		return false
+	}
+	for _, r := range name {
+		if !isTagRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// walk calls fn for every node of the tree in document order.
+func walk(root ast.Node, fn func(node ast.Node)) {
+	_ = ast.Walk(root, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			fn(node)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// isTagRune reports whether the rune can be a part of a tag name.
+func isTagRune(r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) {
+		return true
+	}
+	switch r {
+	case '-', '_', '/':
+		return true
+	}
+	// Emojis and other non-ASCII symbols are allowed.
+	return r >= utf8.RuneSelf && unicode.IsSymbol(r)
+}
+
+// KindTag is the kind of Tag nodes.
+var KindTag = ast.NewNodeKind("Tag")
+
+// Tag is an inline tag, e.g. #work/projectA.
+type Tag struct {
+	ast.BaseInline
+
+	// Name is the tag name without the leading #.
+	Name string
+	// Segment is the source of the tag including the leading #.
+	Segment text.Segment
+}
+
+func (n *Tag) Kind() ast.NodeKind {
+	return KindTag
+}
+
+func (n *Tag) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Name": n.Name}, nil)
+}
+
+// KindEmbeddedMemo is the kind of EmbeddedMemo nodes.
+var KindEmbeddedMemo = ast.NewNodeKind("EmbeddedMemo")
+
+// EmbeddedMemo is an inline reference to another memo, e.g. ![[memo:1]].
+type EmbeddedMemo struct {
+	ast.BaseInline
+
+	MemoID int
+	// Segment is the source of the whole reference.
+	Segment text.Segment
+}
+
+func (n *EmbeddedMemo) Kind() ast.NodeKind {
+	return KindEmbeddedMemo
+}
+
+func (n *EmbeddedMemo) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"MemoID": strconv.Itoa(n.MemoID)}, nil)
+}
+
+// KindEmbeddedResource is the kind of EmbeddedResource nodes.
+var KindEmbeddedResource = ast.NewNodeKind("EmbeddedResource")
+
+// EmbeddedResource is an inline reference to a resource, e.g. ![[resource:1]].
+type EmbeddedResource struct {
+	ast.BaseInline
+
+	ResourceID int
+	// Segment is the source of the whole reference.
+	Segment text.Segment
+}
+
+func (n *EmbeddedResource) Kind() ast.NodeKind {
+	return KindEmbeddedResource
+}
+
+func (n *EmbeddedResource) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"ResourceID": strconv.Itoa(n.ResourceID)}, nil)
+}
+
+type tagParser struct{}
+
+func (p *tagParser) Trigger() []byte {
+	return []byte{'#'}
+}
+
+func (p *tagParser) Parse(_ ast.Node, block text.Reader, _ parser.Context) ast.Node {
+	// A # in the middle of a word such as issue#1 is not a tag.
+	if preceding := block.PrecendingCharacter(); unicode.IsLetter(preceding) || unicode.IsDigit(preceding) {
+		return nil
+	}
+	line, segment := block.PeekLine()
+	end := 1
+	for end < len(line) {
+		r, size := utf8.DecodeRune(line[end:])
+		if !isTagRune(r) {
+			break
+		}
+		end += size
+	}
+	// Trailing slashes end the sentence rather than nest the tag.
+	for end > 1 && line[end-1] == '/' {
+		end--
+	}
+	if end == 1 {
+		return nil
+	}
+	block.Advance(end)
+	return &Tag{
+		Name:    string(line[1:end]),
+		Segment: text.NewSegment(segment.Start, segment.Start+end),
+	}
+}
+
+var (
+	embedPrefix          = []byte("![[")
+	embedSuffix          = []byte("]]")
+	embeddedMemoType     = []byte("memo:")
+	embeddedResourceType = []byte("resource:")
+)
+
+// embedParser parses the embedded memos and resources.
+type embedParser struct{}
+
+func (p *embedParser) Trigger() []byte {
+	return []byte{'!'}
+}
+
+func (p *embedParser) Parse(_ ast.Node, block text.Reader, _ parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+	if !bytes.HasPrefix(line, embedPrefix) {
+		return nil
+	}
+	rest := line[len(embedPrefix):]
+	end := bytes.Index(rest, embedSuffix)
+	if end <= 0 {
+		return nil
+	}
+	reference := rest[:end]
+	embedType := embeddedMemoType
+	if bytes.HasPrefix(reference, embeddedResourceType) {
+		embedType = embeddedResourceType
+	} else if !bytes.HasPrefix(reference, embeddedMemoType) {
+		return nil
+	}
+	id, err := strconv.Atoi(string(reference[len(embedType):]))
+	if err != nil || id <= 0 {
+		return nil
+	}
+	length := len(embedPrefix) + end + len(embedSuffix)
+	block.Advance(length)
+	if bytes.Equal(embedType, embeddedResourceType) {
+		return &EmbeddedResource{
+			ResourceID: id,
+			Segment:    text.NewSegment(segment.Start, segment.Start+length),
+		}
+	}
+	return &EmbeddedMemo{
+		MemoID:  id,
+		Segment: text.NewSegment(segment.Start, segment.Start+length),
+	}
+}
+
+type memoHTMLRenderer struct{}
+
+func (r *memoHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindTag, r.renderTag)
+	reg.Register(KindEmbeddedMemo, r.renderEmbeddedMemo)
+	reg.Register(KindEmbeddedResource, r.renderEmbeddedResource)
+}
+
+func (r *memoHTMLRenderer) renderTag(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		tag := node.(*Tag)
+		_, _ = w.WriteString(`<span class="tag" data-tag="`)
+		_, _ = w.Write(util.EscapeHTML([]byte(tag.Name)))
+		_, _ = w.WriteString(`">#`)
+		_, _ = w.Write(util.EscapeHTML([]byte(tag.Name)))
+		_, _ = w.WriteString(`</span>`)
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *memoHTMLRenderer) renderEmbeddedMemo(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		memoID := strconv.Itoa(node.(*EmbeddedMemo).MemoID)
+		_, _ = w.WriteString(`<a class="embedded-memo" data-memo-id="` + memoID + `" href="/m/` + memoID + `">memo:` + memoID + `</a>`)
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// renderEmbeddedResource renders a placeholder, the resource is resolved with the memo as its link needs the public ID.
+func (r *memoHTMLRenderer) renderEmbeddedResource(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		resourceID := strconv.Itoa(node.(*EmbeddedResource).ResourceID)
+		_, _ = w.WriteString(`<span class="embedded-resource" data-resource-id="` + resourceID + `">resource:` + resourceID + `</span>`)
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+type memoExtension struct{}
+
+func (e *memoExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(
+		// Embeds are tried before the images of the link parser.
+		util.Prioritized(&embedParser{}, 199),
+		util.Prioritized(&tagParser{}, 999),
+	))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&memoHTMLRenderer{}, 500),
+	))
+}
@@ -0,0 +1,148 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractTags(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{
+			content: "#work #life",
+			want:    []string{"life", "work"},
+		},
+		{
+			content: "a nested #work/projectA/, and #work again.",
+			want:    []string{"work", "work/projectA"},
+		},
+		{
+			content: "issue#1 is not a tag, # neither",
+			want:    []string{},
+		},
+		{
+			content: "# Heading\n\n- [ ] todo #task",
+			want:    []string{"task"},
+		},
+		{
+			content: "`#code` and\n\n```\n#block\n```\n",
+			want:    []string{},
+		},
+		{
+			content: "see https://example.com/#anchor and #标签 #🎉",
+			want:    []string{"标签", "🎉"},
+		},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.want, ExtractTags([]byte(test.content)), test.content)
+	}
+}
+
+func TestReplaceTags(t *testing.T) {
+	content := "#work #work/a #workout `#work`"
+	got := ReplaceTags([]byte(content), func(name string) (string, bool) {
+		if name == "work" {
+			return "job", true
+		}
+		if name == "work/a" {
+			return "job/a", true
+		}
+		return "", false
+	})
+	require.Equal(t, "#job #job/a #workout `#work`", string(got))
+}
+
+func TestExtractEmbeddedMemoIDs(t *testing.T) {
+	content := "![[memo:1]] ![[memo:2]]\n\n![[memo:1]] ![[memo:x]] ![image](a.png) `![[memo:3]]`"
+	require.Equal(t, []int{1, 2}, ExtractEmbeddedMemoIDs([]byte(content)))
+}
+
+func TestExtractEmbeddedResourceIDs(t *testing.T) {
+	content := "![[resource:2]] ![[memo:1]] ![[resource:2]] ![[resource:]] ![[file:3]]"
+	require.Equal(t, []int{2}, ExtractEmbeddedResourceIDs([]byte(content)))
+	require.Equal(t, []int{1}, ExtractEmbeddedMemoIDs([]byte(content)))
+}
+
+func TestExtractLinks(t *testing.T) {
+	content := "[memos](https://usememos.com) and https://example.com"
+	require.Equal(t, []string{"https://usememos.com", "https://example.com"}, ExtractLinks([]byte(content)))
+}
+
+func TestIsValidTagName(t *testing.T) {
+	require.True(t, IsValidTagName("work"))
+	require.True(t, IsValidTagName("work/projectA"))
+	require.False(t, IsValidTagName(""))
+	require.False(t, IsValidTagName("work/"))
+	require.False(t, IsValidTagName("work//a"))
+	require.False(t, IsValidTagName("work a"))
+	require.False(t, IsValidTagName("work#a"))
+}
+
+func TestRenderHTML(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{
+			content: "hello #work\nworld",
+			want:    "<p>hello <span class=\"tag\" data-tag=\"work\">#work</span><br>\nworld</p>\n",
+		},
+		{
+			content: "- [x] done\n- [ ] todo",
+			want:    "<ul>\n<li><input checked=\"\" disabled=\"\" type=\"checkbox\"> done</li>\n<li><input disabled=\"\" type=\"checkbox\"> todo</li>\n</ul>\n",
+		},
+		{
+			content: "![[memo:1]]",
+			want:    "<p><a class=\"embedded-memo\" data-memo-id=\"1\" href=\"/m/1\">memo:1</a></p>\n",
+		},
+		{
+			content: "![[resource:2]]",
+			want:    "<p><span class=\"embedded-resource\" data-resource-id=\"2\">resource:2</span></p>\n",
+		},
+		{
+			content: "<script>alert(1)</script>\n\n[x](javascript:alert(1))",
+			want:    "<!-- raw HTML omitted -->\n<p><a href=\"\">x</a></p>\n",
+		},
+	}
+	for _, test := range tests {
+		html, err := RenderHTML([]byte(test.content))
+		require.NoError(t, err)
+		require.Equal(t, test.want, html, test.content)
+	}
+}
+
+func TestExtractTasks(t *testing.T) {
+	content := "- [ ] buy milk #home\n- [x] write\n  the report\n  - [X] nested\n\n`- [ ] code`\n\n1. [ ] ordered"
+	taskList := ExtractTasks([]byte(content))
+	require.Len(t, taskList, 4)
+	want := []struct {
+		text    string
+		checked bool
+	}{
+		{"buy milk #home", false},
+		{"write the report", true},
+		{"nested", true},
+		{"ordered", false},
+	}
+	for i, task := range taskList {
+		require.Equal(t, i, task.Index)
+		require.Equal(t, want[i].text, task.Text)
+		require.Equal(t, want[i].checked, task.Checked)
+	}
+}
+
+func TestToggleTask(t *testing.T) {
+	content := "- [ ] one\n- [x] two\n"
+	got, err := ToggleTask([]byte(content), 0, true)
+	require.NoError(t, err)
+	require.Equal(t, "- [x] one\n- [x] two\n", string(got))
+	got, err = ToggleTask(got, 1, false)
+	require.NoError(t, err)
+	require.Equal(t, "- [x] one\n- [ ] two\n", string(got))
+	require.Equal(t, content, "- [ ] one\n- [x] two\n")
+	_, err = ToggleTask([]byte(content), 2, true)
+	require.Error(t, err)
+}
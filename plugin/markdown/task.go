@@ -0,0 +1,73 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Task is a task list item, e.g. - [ ] todo.
+type Task struct {
+	// Index is the position of the task among the tasks of the content, starting from 0.
+	Index int
+	// Text is the Markdown source of the task without the check box, the lines are joined by spaces.
+	Text    string
+	Checked bool
+	// Segment is the source of the check box, e.g. [x].
+	Segment text.Segment
+}
+
+// ExtractTasks returns the task list items in order of appearance, including the nested ones.
+func ExtractTasks(source []byte) []*Task {
+	taskList := []*Task{}
+	walk(Parse(source), func(node ast.Node) {
+		checkBox, ok := node.(*east.TaskCheckBox)
+		if !ok {
+			return
+		}
+		// The check box is parsed at the start of the first line of the item.
+		lines := checkBox.Parent().Lines()
+		if lines.Len() == 0 {
+			return
+		}
+		first := lines.At(0)
+		segment := text.NewSegment(first.Start, first.Start+3)
+		if segment.Stop > len(source) || source[segment.Start] != '[' || source[segment.Start+2] != ']' {
+			return
+		}
+
+		textList := [][]byte{bytes.TrimSpace(source[segment.Stop:first.Stop])}
+		for i := 1; i < lines.Len(); i++ {
+			line := lines.At(i)
+			textList = append(textList, bytes.TrimSpace(line.Value(source)))
+		}
+		taskList = append(taskList, &Task{
+			Index:   len(taskList),
+			Text:    string(bytes.TrimSpace(bytes.Join(textList, []byte(" ")))),
+			Checked: checkBox.IsChecked,
+			Segment: segment,
+		})
+	})
+	return taskList
+}
+
+// ToggleTask checks or unchecks the task of the index, the rest of the content is kept as is.
+func ToggleTask(source []byte, index int, checked bool) ([]byte, error) {
+	for _, task := range ExtractTasks(source) {
+		if task.Index != index {
+			continue
+		}
+		result := make([]byte, len(source))
+		copy(result, source)
+		mark := byte(' ')
+		if checked {
+			mark = 'x'
+		}
+		result[task.Segment.Start+1] = mark
+		return result, nil
+	}
+	return nil, fmt.Errorf("task not found: %d", index)
+}
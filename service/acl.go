@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"uamemos/api"
+	"uamemos/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// findRolePermissionList returns the permissions granted to the role.
+// Unknown roles, e.g. a deleted custom role, are granted nothing.
+func (s *Service) findRolePermissionList(ctx context.Context, role api.Role) ([]api.Permission, error) {
+	if permissionList, ok := api.BuiltinRolePermissionList(role); ok {
+		return permissionList, nil
+	}
+	customRole, err := s.Store.FindCustomRole(ctx, &api.CustomRoleFind{
+		Name: &role,
+	})
+	if err != nil {
+		if common.ErrorCode(err) == common.NotFound {
+			return []api.Permission{}, nil
+		}
+		return nil, err
+	}
+	return customRole.PermissionList, nil
+}
+
+// hasPermission reports whether the user is granted every given permission.
+func (s *Service) hasPermission(ctx context.Context, user *api.User, permissions ...api.Permission) (bool, error) {
+	if user == nil {
+		return false, nil
+	}
+	permissionList, err := s.findRolePermissionList(ctx, user.Role)
+	if err != nil {
+		return false, err
+	}
+	for _, permission := range permissions {
+		granted := false
+		for _, p := range permissionList {
+			if p == permission {
+				granted = true
+				break
+			}
+		}
+		if !granted {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// authorize returns the session user when it is granted every given permission. Without permissions,
+// any signed in user is authorized. Otherwise the error response is written and ok is false.
+func (s *Service) authorize(ctx *gin.Context, permissions ...api.Permission) (user *api.User, ok bool) {
+	_userID, ok := ctx.Get(getUserIDContextKey())
+	userID, _ok := _userID.(int)
+	if !ok || !_ok {
+		ctx.String(http.StatusUnauthorized, "Missing user in session")
+		return nil, false
+	}
+
+	user, err := s.Store.FindUser(ctx, &api.UserFind{
+		ID: &userID,
+	})
+	if err != nil && common.ErrorCode(err) != common.NotFound {
+		ctx.String(http.StatusInternalServerError, "Failed to find user")
+		return nil, false
+	}
+	if user == nil {
+		ctx.String(http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	granted, err := s.hasPermission(ctx, user, permissions...)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "Failed to find role permissions")
+		return nil, false
+	}
+	if !granted {
+		ctx.String(http.StatusForbidden, "Access forbidden for current session user")
+		return nil, false
+	}
+	return user, true
+}
+
+// authorizePermissionList checks that the current user is granted every permission of the list,
+// so that nobody hands out more permissions than its own. Otherwise the error response is written.
+func (s *Service) authorizePermissionList(ctx *gin.Context, currentUser *api.User, permissionList []api.Permission) bool {
+	grantedList, err := s.findRolePermissionList(ctx, currentUser.Role)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "Failed to find role permissions")
+		return false
+	}
+	if missingList := api.MissingPermissionList(grantedList, permissionList); len(missingList) != 0 {
+		ctx.String(http.StatusForbidden, fmt.Sprintf("Could not grant permission %s, the current user is not granted it", missingList[0]))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,532 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"uamemos/api"
+	"uamemos/common"
+	"uamemos/common/log"
+	"uamemos/plugin/activitypub"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxActivityPubOutboxItemCount is the number of latest memos in an outbox.
+	maxActivityPubOutboxItemCount = 20
+	// maxActivityPubInboxBodySize limits the activities posted to an inbox.
+	maxActivityPubInboxBodySize = 1 << 20
+)
+
+func (s *Service) registerActivityPubRoutes(g *gin.Engine) {
+	g.GET("/.well-known/webfinger", func(ctx *gin.Context) {
+		baseURL, ok := s.getActivityPubBaseURL(ctx)
+		if !ok {
+			return
+		}
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Invalid external URL")
+			return
+		}
+
+		resource := ctx.Query("resource")
+		username, host, ok := strings.Cut(strings.TrimPrefix(resource, "acct:"), "@")
+		if !strings.HasPrefix(resource, "acct:") || !ok || username == "" {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("Invalid resource: %s", resource))
+			return
+		}
+		if !strings.EqualFold(host, u.Host) {
+			ctx.String(http.StatusNotFound, fmt.Sprintf("Resource not found: %s", resource))
+			return
+		}
+		normalStatus := api.Normal
+		user, err := s.Store.FindUser(ctx, &api.UserFind{
+			Name:      &username,
+			RowStatus: &normalStatus,
+		})
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Resource not found: %s", resource))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to find user")
+			return
+		}
+
+		actorURL := getActivityPubActorURL(baseURL, user.ID)
+		writeActivityPubJSON(ctx, activitypub.WebFingerContentType, &activitypub.WebFinger{
+			Subject: fmt.Sprintf("acct:%s@%s", user.Name, u.Host),
+			Aliases: []string{actorURL, fmt.Sprintf("%s/u/%d", baseURL, user.ID)},
+			Links: []activitypub.WebFingerLink{
+				{Rel: "self", Type: activitypub.ContentType, Href: actorURL},
+				{Rel: "http://webfinger.net/rel/profile-page", Type: "text/html", Href: fmt.Sprintf("%s/u/%d", baseURL, user.ID)},
+			},
+		})
+	})
+
+	g.GET("/ap/users/:id", func(ctx *gin.Context) {
+		user, ok := s.findActivityPubUser(ctx)
+		if !ok {
+			return
+		}
+		baseURL, ok := s.getActivityPubBaseURL(ctx)
+		if !ok {
+			return
+		}
+		userKeyPair, err := s.getUserKeyPair(ctx, user.ID)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find user key pair")
+			return
+		}
+
+		actorURL := getActivityPubActorURL(baseURL, user.ID)
+		name := user.Nickname
+		if name == "" {
+			name = user.Name
+		}
+		writeActivityPubJSON(ctx, activitypub.ContentType, &activitypub.Actor{
+			Context:           []string{activitypub.ActivityStreamsContext, activitypub.SecurityContext},
+			ID:                actorURL,
+			Type:              "Person",
+			PreferredUsername: user.Name,
+			Name:              name,
+			URL:               fmt.Sprintf("%s/u/%d", baseURL, user.ID),
+			Inbox:             actorURL + "/inbox",
+			Outbox:            actorURL + "/outbox",
+			Followers:         actorURL + "/followers",
+			PublicKey: &activitypub.PublicKey{
+				ID:           getActivityPubKeyID(actorURL),
+				Owner:        actorURL,
+				PublicKeyPem: userKeyPair.PublicKey,
+			},
+		})
+	})
+
+	g.GET("/ap/users/:id/outbox", func(ctx *gin.Context) {
+		user, ok := s.findActivityPubUser(ctx)
+		if !ok {
+			return
+		}
+		baseURL, ok := s.getActivityPubBaseURL(ctx)
+		if !ok {
+			return
+		}
+
+		// The outbox lists the latest notes, pinning doesn't apply to it.
+		normalStatus := api.Normal
+		limit := maxActivityPubOutboxItemCount
+		pinnedFirst := false
+		memoList, err := s.Store.FindMemoList(ctx, &api.MemoFind{
+			RowStatus:      &normalStatus,
+			CreatorID:      &user.ID,
+			VisibilityList: []api.Visibility{api.Public},
+			Sort:           api.MemoSortCreated,
+			PinnedFirst:    &pinnedFirst,
+			Limit:          &limit,
+		})
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find memo list")
+			return
+		}
+
+		orderedItems := []any{}
+		for _, memo := range memoList {
+			note, err := convertMemoToNote(baseURL, memo)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to convert memo")
+				return
+			}
+			orderedItems = append(orderedItems, newActivityPubNoteActivity(activitypub.TypeCreate, note, memo))
+		}
+		writeActivityPubJSON(ctx, activitypub.ContentType, &activitypub.OrderedCollection{
+			Context:      activitypub.ActivityStreamsContext,
+			ID:           getActivityPubActorURL(baseURL, user.ID) + "/outbox",
+			Type:         "OrderedCollection",
+			TotalItems:   len(orderedItems),
+			OrderedItems: orderedItems,
+		})
+	})
+
+	g.GET("/ap/users/:id/followers", func(ctx *gin.Context) {
+		user, ok := s.findActivityPubUser(ctx)
+		if !ok {
+			return
+		}
+		baseURL, ok := s.getActivityPubBaseURL(ctx)
+		if !ok {
+			return
+		}
+
+		// Only the number of followers is published, the remote actors are kept private.
+		followerCount, err := s.Store.CountActivityPubFollower(ctx, &api.ActivityPubFollowerFind{
+			UserID: &user.ID,
+		})
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to count followers")
+			return
+		}
+		writeActivityPubJSON(ctx, activitypub.ContentType, &activitypub.OrderedCollection{
+			Context:    activitypub.ActivityStreamsContext,
+			ID:         getActivityPubActorURL(baseURL, user.ID) + "/followers",
+			Type:       "OrderedCollection",
+			TotalItems: followerCount,
+		})
+	})
+
+	g.POST("/ap/users/:id/inbox", func(ctx *gin.Context) {
+		user, ok := s.findActivityPubUser(ctx)
+		if !ok {
+			return
+		}
+		baseURL, ok := s.getActivityPubBaseURL(ctx)
+		if !ok {
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxActivityPubInboxBodySize))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		keyID, err := activitypub.VerifyRequest(ctx, ctx.Request, body, s.apClient.FetchPublicKey)
+		if err != nil {
+			ctx.String(http.StatusUnauthorized, fmt.Sprintf("Invalid signature: %v", err))
+			return
+		}
+		activity := &activitypub.Activity{}
+		if err := json.Unmarshal(body, activity); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted activity")
+			return
+		}
+		// The actor of the activity must be the owner of the signing key.
+		if keyURL, err := url.Parse(keyID); err != nil || strings.TrimSuffix(keyID, "#"+keyURL.Fragment) != activity.Actor {
+			ctx.String(http.StatusUnauthorized, "Activity actor does not match the signature")
+			return
+		}
+
+		actorURL := getActivityPubActorURL(baseURL, user.ID)
+		switch activity.Type {
+		case activitypub.TypeFollow:
+			if activity.ObjectID() != actorURL {
+				ctx.String(http.StatusBadRequest, fmt.Sprintf("Follow object is not %s", actorURL))
+				return
+			}
+			if err := s.acceptActivityPubFollow(ctx, user, actorURL, activity); err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to accept follow")
+				return
+			}
+		case activitypub.TypeUndo:
+			follow, err := activity.ObjectActivity()
+			if err != nil || follow.Type != activitypub.TypeFollow {
+				// Only follows can be undone, the other undone activities are ignored.
+				break
+			}
+			if follow.Actor != activity.Actor || follow.ObjectID() != actorURL {
+				ctx.String(http.StatusBadRequest, "Undo object is not a follow of the actor")
+				return
+			}
+			if err := s.Store.DeleteActivityPubFollower(ctx, &api.ActivityPubFollowerDelete{
+				UserID: user.ID,
+				Actor:  activity.Actor,
+			}); err != nil && common.ErrorCode(err) != common.NotFound {
+				ctx.String(http.StatusInternalServerError, "Failed to delete follower")
+				return
+			}
+		}
+		ctx.Status(http.StatusAccepted)
+	})
+
+	g.GET("/ap/memos/:id", func(ctx *gin.Context) {
+		memoID, err := strconv.Atoi(ctx.Param("id"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("id")))
+			return
+		}
+		memo, err := s.Store.FindMemo(ctx, &api.MemoFind{
+			ID: &memoID,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			ctx.String(http.StatusInternalServerError, "Failed to find memo")
+			return
+		}
+		if memo == nil || !isFederatedMemo(memo) {
+			ctx.String(http.StatusNotFound, fmt.Sprintf("Memo ID not found: %d", memoID))
+			return
+		}
+		baseURL, ok := s.getActivityPubBaseURL(ctx)
+		if !ok {
+			return
+		}
+
+		note, err := convertMemoToNote(baseURL, memo)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to convert memo")
+			return
+		}
+		note.Context = activitypub.ActivityStreamsContext
+		writeActivityPubJSON(ctx, activitypub.ContentType, note)
+	})
+}
+
+// findActivityPubUser finds the normal user of the actor in the path.
+func (s *Service) findActivityPubUser(ctx *gin.Context) (*api.User, bool) {
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("id")))
+		return nil, false
+	}
+	normalStatus := api.Normal
+	user, err := s.Store.FindUser(ctx, &api.UserFind{
+		ID:        &userID,
+		RowStatus: &normalStatus,
+	})
+	if err != nil {
+		if common.ErrorCode(err) == common.NotFound {
+			ctx.String(http.StatusNotFound, fmt.Sprintf("User ID not found: %d", userID))
+			return nil, false
+		}
+		ctx.String(http.StatusInternalServerError, "Failed to find user")
+		return nil, false
+	}
+	return user, true
+}
+
+func (s *Service) getActivityPubBaseURL(ctx *gin.Context) (string, bool) {
+	customizedProfile, err := s.getSystemCustomizedProfile(ctx)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "Failed to find customized profile")
+		return "", false
+	}
+	return getBaseURL(ctx, customizedProfile), true
+}
+
+// getUserKeyPair finds the key pair of the user, it is generated on first use.
+func (s *Service) getUserKeyPair(ctx context.Context, userID int) (*api.UserKeyPair, error) {
+	userKeyPair, err := s.Store.FindUserKeyPair(ctx, &api.UserKeyPairFind{
+		UserID: userID,
+	})
+	if err == nil {
+		return userKeyPair, nil
+	}
+	if common.ErrorCode(err) != common.NotFound {
+		return nil, err
+	}
+
+	privateKey, publicKey, err := activitypub.GenerateKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	return s.Store.CreateUserKeyPair(ctx, &api.UserKeyPairCreate{
+		UserID:     userID,
+		PublicKey:  publicKey,
+		PrivateKey: privateKey,
+	})
+}
+
+func (s *Service) getUserPrivateKey(ctx context.Context, userID int) (*rsa.PrivateKey, error) {
+	userKeyPair, err := s.getUserKeyPair(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return activitypub.ParsePrivateKey(userKeyPair.PrivateKey)
+}
+
+// acceptActivityPubFollow stores the remote follower and replies an Accept to its inbox.
+func (s *Service) acceptActivityPubFollow(ctx context.Context, user *api.User, actorURL string, follow *activitypub.Activity) error {
+	remoteActor, err := s.apClient.FetchActor(ctx, follow.Actor)
+	if err != nil {
+		return err
+	}
+	follower, err := s.Store.UpsertActivityPubFollower(ctx, &api.ActivityPubFollowerUpsert{
+		UserID: user.ID,
+		Actor:  remoteActor.ID,
+		Inbox:  remoteActor.Inbox,
+	})
+	if err != nil {
+		return err
+	}
+
+	privateKey, err := s.getUserPrivateKey(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	follow.Context = nil
+	s.enqueueActivityPubDelivery(&activitypub.Delivery{
+		Inbox:      follower.Inbox,
+		KeyID:      getActivityPubKeyID(actorURL),
+		PrivateKey: privateKey,
+		Activity: &activitypub.Activity{
+			Context: activitypub.ActivityStreamsContext,
+			ID:      fmt.Sprintf("%s#accepts/follows/%d", actorURL, follower.ID),
+			Type:    activitypub.TypeAccept,
+			Actor:   actorURL,
+			Object:  follow,
+		},
+	})
+	return nil
+}
+
+// federateMemo delivers the change of a memo to the remote followers of its creator.
+// The previous memo is nil for a created memo, and the memo is nil for a deleted one.
+// Federation is best effort, failures are logged rather than failing the memo request.
+func (s *Service) federateMemo(ctx *gin.Context, previous, memo *api.Memo) {
+	wasFederated := previous != nil && isFederatedMemo(previous)
+	isFederated := memo != nil && isFederatedMemo(memo)
+	activityType := ""
+	switch {
+	case !wasFederated && isFederated:
+		activityType = activitypub.TypeCreate
+	case wasFederated && isFederated:
+		activityType = activitypub.TypeUpdate
+	case wasFederated && !isFederated:
+		activityType = activitypub.TypeDelete
+	default:
+		return
+	}
+	if err := s.deliverMemoActivity(ctx, activityType, previous, memo); err != nil {
+		log.Warn("Failed to federate memo", zap.String("type", activityType), zap.Error(err))
+	}
+}
+
+func (s *Service) deliverMemoActivity(ctx *gin.Context, activityType string, previous, memo *api.Memo) error {
+	if memo == nil {
+		memo = previous
+	}
+	followerList, err := s.Store.FindActivityPubFollowerList(ctx, &api.ActivityPubFollowerFind{
+		UserID: &memo.CreatorID,
+	})
+	if err != nil || len(followerList) == 0 {
+		return err
+	}
+	customizedProfile, err := s.getSystemCustomizedProfile(ctx)
+	if err != nil {
+		return err
+	}
+	privateKey, err := s.getUserPrivateKey(ctx, memo.CreatorID)
+	if err != nil {
+		return err
+	}
+
+	baseURL := getBaseURL(ctx, customizedProfile)
+	var activity *activitypub.Activity
+	if activityType == activitypub.TypeDelete {
+		noteURL := getActivityPubNoteURL(baseURL, memo.ID)
+		activity = &activitypub.Activity{
+			ID:     noteURL + "#delete",
+			Type:   activitypub.TypeDelete,
+			Actor:  getActivityPubActorURL(baseURL, memo.CreatorID),
+			To:     []string{activitypub.PublicCollection},
+			Object: map[string]any{"id": noteURL, "type": "Tombstone"},
+		}
+	} else {
+		note, err := convertMemoToNote(baseURL, memo)
+		if err != nil {
+			return err
+		}
+		activity = newActivityPubNoteActivity(activityType, note, memo)
+	}
+	activity.Context = activitypub.ActivityStreamsContext
+
+	keyID := getActivityPubKeyID(getActivityPubActorURL(baseURL, memo.CreatorID))
+	inboxSet := map[string]bool{}
+	for _, follower := range followerList {
+		if inboxSet[follower.Inbox] {
+			continue
+		}
+		inboxSet[follower.Inbox] = true
+		s.enqueueActivityPubDelivery(&activitypub.Delivery{
+			Inbox:      follower.Inbox,
+			KeyID:      keyID,
+			PrivateKey: privateKey,
+			Activity:   activity,
+		})
+	}
+	return nil
+}
+
+func (s *Service) enqueueActivityPubDelivery(delivery *activitypub.Delivery) {
+	if !s.apDeliveryQueue.Enqueue(delivery) {
+		log.Warn("ActivityPub delivery queue is full", zap.String("inbox", delivery.Inbox))
+	}
+}
+
+// isFederatedMemo reports whether the memo is published to ActivityPub, only normal public memos are.
+func isFederatedMemo(memo *api.Memo) bool {
+	return memo.Visibility == api.Public && memo.RowStatus == api.Normal && memo.ParentID == 0
+}
+
+func convertMemoToNote(baseURL string, memo *api.Memo) (*activitypub.Note, error) {
+	content, err := renderMemoContentHTML(memo.Content)
+	if err != nil {
+		return nil, err
+	}
+	for _, resource := range memo.ResourceList {
+		content += fmt.Sprintf(`<p><a href="%s">%s</a></p>`, getResourceURL(baseURL, resource), html.EscapeString(resource.Filename))
+	}
+	actorURL := getActivityPubActorURL(baseURL, memo.CreatorID)
+	note := &activitypub.Note{
+		ID:           getActivityPubNoteURL(baseURL, memo.ID),
+		Type:         "Note",
+		AttributedTo: actorURL,
+		Content:      content,
+		URL:          fmt.Sprintf("%s/m/%d", baseURL, memo.ID),
+		Published:    time.Unix(memo.CreatedTs, 0).UTC().Format(time.RFC3339),
+		To:           []string{activitypub.PublicCollection},
+		Cc:           []string{actorURL + "/followers"},
+	}
+	if memo.UpdatedTs > memo.CreatedTs {
+		note.Updated = time.Unix(memo.UpdatedTs, 0).UTC().Format(time.RFC3339)
+	}
+	return note, nil
+}
+
+// newActivityPubNoteActivity wraps the note in a Create or Update activity, each update of a memo is a distinct activity.
+func newActivityPubNoteActivity(activityType string, note *activitypub.Note, memo *api.Memo) *activitypub.Activity {
+	activityID := note.ID + "/activity"
+	if activityType == activitypub.TypeUpdate {
+		activityID = fmt.Sprintf("%s#updates/%d", activityID, memo.UpdatedTs)
+	}
+	return &activitypub.Activity{
+		ID:        activityID,
+		Type:      activityType,
+		Actor:     note.AttributedTo,
+		Published: note.Published,
+		To:        note.To,
+		Cc:        note.Cc,
+		Object:    note,
+	}
+}
+
+func getActivityPubActorURL(baseURL string, userID int) string {
+	return fmt.Sprintf("%s/ap/users/%d", baseURL, userID)
+}
+
+func getActivityPubKeyID(actorURL string) string {
+	return actorURL + "#main-key"
+}
+
+func getActivityPubNoteURL(baseURL string, memoID int) string {
+	return fmt.Sprintf("%s/ap/memos/%d", baseURL, memoID)
+}
+
+func writeActivityPubJSON(ctx *gin.Context, contentType string, data any) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(data); err != nil {
+		ctx.String(http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+	ctx.Data(http.StatusOK, contentType+"; charset=utf-8", buf.Bytes())
+}
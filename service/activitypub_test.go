@@ -0,0 +1,22 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivityPubOutboxIgnoresPinned(t *testing.T) {
+	ts := newTestServer(t)
+	host := ts.newClient()
+	ts.requireStatus(host, http.StatusOK, http.MethodPost, "/api/auth/signup", map[string]string{"name": "host", "pass": "secret"})
+	ts.createPinnedOldMemo(host)
+
+	for _, client := range []*http.Client{host, ts.newClient()} {
+		body := ts.requireStatus(client, http.StatusOK, http.MethodGet, "/ap/users/1/outbox", nil)
+		newIndex, oldIndex := strings.Index(body, "new memo"), strings.Index(body, "old memo")
+		require.True(t, newIndex >= 0 && oldIndex > newIndex, body)
+	}
+}
@@ -4,8 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"regexp"
 	"uamemos/api"
 	"uamemos/common"
+	"uamemos/plugin/idp"
+	"uamemos/plugin/idp/ldap"
+	"uamemos/plugin/idp/oauth2"
+	"uamemos/plugin/idp/oidc"
+	"uamemos/store"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
@@ -53,6 +59,184 @@ func (s *Service) registerAuthRoutes(rg *gin.RouterGroup, secret string) {
 		ctx.JSON(http.StatusOK, composeResponse(user))
 	})
 
+	rg.POST("/auth/signin/sso", func(ctx *gin.Context) {
+		signin := &api.SSOSignIn{}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(signin); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted signin request")
+			return
+		}
+
+		identityProvider, err := s.Store.GetIdentityProvider(ctx, &store.FindIdentityProviderMessage{
+			ID: &signin.IdentityProviderID,
+		})
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Identity provider not found: %d", signin.IdentityProviderID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to find identity provider")
+			return
+		}
+
+		var userInfo *idp.IdentityProviderUserInfo
+		if identityProvider.Type == store.IdentityProviderOAuth2 {
+			oauth2IdentityProvider, err := oauth2.NewIdentityProvider(identityProvider.Config.OAuth2Config)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to create identity provider instance")
+				return
+			}
+			token, err := oauth2IdentityProvider.ExchangeToken(ctx, signin.RedirectURI, signin.Code)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to exchange token")
+				return
+			}
+			userInfo, err = oauth2IdentityProvider.UserInfo(token)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to get user info")
+				return
+			}
+		} else if identityProvider.Type == store.IdentityProviderLDAP {
+			ldapIdentityProvider, err := ldap.NewIdentityProvider(identityProvider.Config.LDAPConfig)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to create identity provider instance")
+				return
+			}
+			userInfo, err = ldapIdentityProvider.Authenticate(signin.Name, signin.Pass)
+			if err != nil {
+				if errors.Is(err, ldap.ErrInvalidCredentials) {
+					ctx.String(http.StatusUnauthorized, "Incorrect login credentials, please try again")
+					return
+				}
+				ctx.String(http.StatusInternalServerError, "Failed to authenticate with identity provider")
+				return
+			}
+		} else if identityProvider.Type == store.IdentityProviderOIDC {
+			authRequest := s.consumeIdentityProviderAuthRequest(signin.State)
+			if authRequest == nil || authRequest.identityProviderID != identityProvider.ID || authRequest.redirectURI != signin.RedirectURI {
+				ctx.String(http.StatusUnauthorized, "Invalid or expired sign-in state, please try again")
+				return
+			}
+			oidcIdentityProvider, err := oidc.NewIdentityProvider(ctx, identityProvider.Config.OIDCConfig)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to create identity provider instance")
+				return
+			}
+			token, err := oidcIdentityProvider.ExchangeToken(ctx, authRequest.redirectURI, signin.Code, authRequest.codeVerifier)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to exchange token")
+				return
+			}
+			userInfo, err = oidcIdentityProvider.UserInfo(ctx, token, authRequest.nonce)
+			if err != nil {
+				ctx.String(http.StatusUnauthorized, "Failed to verify id token")
+				return
+			}
+		} else {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("Unsupported identity provider type: %s", identityProvider.Type))
+			return
+		}
+
+		identifierFilter := identityProvider.IdentifierFilter
+		if identifierFilter != "" {
+			identifierFilterRegex, err := regexp.Compile(identifierFilter)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to compile identifier filter")
+				return
+			}
+			if !identifierFilterRegex.MatchString(userInfo.Identifier) {
+				ctx.String(http.StatusUnauthorized, "Access denied, identifier does not match the filter.")
+				return
+			}
+		}
+
+		subject := userInfo.Subject
+		if subject == "" {
+			subject = userInfo.Identifier
+		}
+		var user *api.User
+		userIdentityProvider, err := s.Store.GetUserIdentityProvider(ctx, &store.FindUserIdentityProviderMessage{
+			IdentityProviderID: identityProvider.ID,
+			Subject:            subject,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			ctx.String(http.StatusInternalServerError, "Failed to find user identity provider")
+			return
+		}
+		if userIdentityProvider != nil {
+			user, err = s.Store.FindUser(ctx, &api.UserFind{
+				ID: &userIdentityProvider.UserID,
+			})
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to find user")
+				return
+			}
+		} else {
+			// Only the users linked to the identity provider sign in through it,
+			// an account of the same name is never taken over.
+			if _, err := s.Store.FindUser(ctx, &api.UserFind{
+				Name: &userInfo.Identifier,
+			}); err == nil {
+				ctx.String(http.StatusConflict, fmt.Sprintf("Username %s belongs to an account not linked to this identity provider", userInfo.Identifier))
+				return
+			} else if common.ErrorCode(err) != common.NotFound {
+				ctx.String(http.StatusInternalServerError, "Failed to find user")
+				return
+			}
+
+			// Auto-provision the user on first sign in. The random password is never shown,
+			// the user keeps signing in through the identity provider.
+			password, err := common.RandomString(20)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to generate random password")
+				return
+			}
+			passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to generate password hash")
+				return
+			}
+			userCreate := &api.UserCreate{
+				Name:         userInfo.Identifier,
+				Role:         api.NormalUser,
+				Nickname:     userInfo.DisplayName,
+				Email:        userInfo.Email,
+				Password:     password,
+				PasswordHash: string(passwordHash),
+				OpenID:       common.GenUUID(),
+			}
+			if err := userCreate.Validate(); err != nil {
+				ctx.String(http.StatusBadRequest, "Invalid user create format")
+				return
+			}
+			user, err = s.Store.CreateIdentityProviderUser(ctx, userCreate, &store.UserIdentityProviderMessage{
+				IdentityProviderID: identityProvider.ID,
+				Subject:            subject,
+			})
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to create user")
+				return
+			}
+			if err := s.createUserAuthSignUpActivity(ctx, user); err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to create activity")
+				return
+			}
+		}
+		if user.RowStatus == api.Archived {
+			ctx.String(http.StatusForbidden, fmt.Sprintf("User has been archived with username %s", userInfo.Identifier))
+			return
+		}
+
+		if err := GenerateTokensAndSetCookies(ctx, user, secret); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to generate tokens")
+			return
+		}
+		if err := s.createUserAuthSignInActivity(ctx, user); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to create activity")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(user))
+	})
+
 	rg.POST("/auth/signup", func(ctx *gin.Context) {
 		signup := &api.SignUp{}
 		if err := json.NewDecoder(ctx.Request.Body).Decode(&signup); err != nil {
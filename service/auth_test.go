@@ -0,0 +1,92 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeOAuth2Server is an OAuth2 provider whose access token is the code, and whose user is named after the token.
+func newFakeOAuth2Server(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": r.Form.Get("code"), "token_type": "bearer"})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		login := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"login": login})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSSOSignInLinkedUser(t *testing.T) {
+	ts := newTestServer(t)
+	provider := newFakeOAuth2Server(t)
+	host := ts.newClient()
+	ts.requireStatus(host, http.StatusOK, http.MethodPost, "/api/auth/signup", map[string]string{"name": "host", "pass": "secret"})
+
+	createIdentityProvider := func(name string) int {
+		body := ts.requireStatus(host, http.StatusOK, http.MethodPost, "/api/idp", map[string]any{
+			"name": name,
+			"type": "OAUTH2",
+			"config": map[string]any{
+				"oauth2Config": map[string]any{
+					"clientId":     "client",
+					"clientSecret": "secret",
+					"authUrl":      provider.URL + "/authorize",
+					"tokenUrl":     provider.URL + "/token",
+					"userInfoUrl":  provider.URL + "/userinfo",
+					"fieldMapping": map[string]string{"identifier": "login"},
+				},
+			},
+		})
+		result := struct {
+			Data struct {
+				ID int `json:"id"`
+			} `json:"data"`
+		}{}
+		require.NoError(t, json.Unmarshal([]byte(body), &result))
+		return result.Data.ID
+	}
+	signIn := func(identityProviderID int, login string) (int, string) {
+		return ts.request(ts.newClient(), http.MethodPost, "/api/auth/signin/sso", map[string]any{
+			"identityProviderId": identityProviderID,
+			"code":               login,
+			"redirectUri":        "http://localhost/auth/callback",
+		})
+	}
+	firstID := createIdentityProvider("first")
+	secondID := createIdentityProvider("second")
+
+	// The local host account is not taken over by a subject of the same name.
+	code, body := signIn(firstID, "host")
+	require.Equal(t, http.StatusConflict, code, body)
+
+	// A new subject is provisioned and linked, then signs in again to the same user.
+	code, body = signIn(firstID, "alice")
+	require.Equal(t, http.StatusOK, code, body)
+	require.Contains(t, body, `"username":"alice"`)
+	userID := strings.Split(strings.TrimPrefix(body, `{"data":{"id":`), ",")[0]
+	code, body = signIn(firstID, "alice")
+	require.Equal(t, http.StatusOK, code, body)
+	require.True(t, strings.HasPrefix(body, fmt.Sprintf(`{"data":{"id":%s,`, userID)), body)
+
+	// The user is linked to the first identity provider only.
+	code, body = signIn(secondID, "alice")
+	require.Equal(t, http.StatusConflict, code, body)
+
+	// Deleting the identity provider removes its links.
+	ts.requireStatus(host, http.StatusOK, http.MethodDelete, fmt.Sprintf("/api/idp/%d", firstID), nil)
+	code, body = signIn(secondID, "alice")
+	require.Equal(t, http.StatusConflict, code, body)
+}
@@ -0,0 +1,393 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"uamemos/api"
+	"uamemos/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Service) registerCollectionRoutes(rg *gin.RouterGroup) {
+	rg.POST("/collection", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
+			return
+		}
+		collectionCreate := &api.CollectionCreate{
+			Visibility: api.Private,
+		}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(collectionCreate); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted post collection request")
+			return
+		}
+		if err := collectionCreate.Validate(); err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		if !s.validateCollectionMemoIDList(ctx, collectionCreate.MemoIDList, user.ID) {
+			return
+		}
+
+		collectionCreate.CreatorID = user.ID
+		collection, err := s.Store.CreateCollection(ctx, collectionCreate)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to create collection")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(collection))
+	})
+
+	rg.PATCH("/collection/:collectionId", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
+			return
+		}
+		collection, ok := s.findEditableCollection(ctx, user)
+		if !ok {
+			return
+		}
+
+		currentTs := time.Now().Unix()
+		collectionPatch := &api.CollectionPatch{
+			UpdatedTs: &currentTs,
+		}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(collectionPatch); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted patch collection request")
+			return
+		}
+		if err := collectionPatch.Validate(); err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		if !s.validateCollectionMemoIDList(ctx, collectionPatch.MemoIDList, user.ID) {
+			return
+		}
+
+		collectionPatch.ID = collection.ID
+		collection, err := s.Store.PatchCollection(ctx, collectionPatch)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to patch collection")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(collection))
+	})
+
+	// The collections of a user are listed with creatorId, those of the current user without it.
+	rg.GET("/collection", func(ctx *gin.Context) {
+		viewerID := findViewerID(ctx)
+		collectionFind := &api.CollectionFind{}
+		if creatorID, err := strconv.Atoi(ctx.Query("creatorId")); err == nil {
+			collectionFind.CreatorID = &creatorID
+		} else if viewerID != nil {
+			collectionFind.CreatorID = viewerID
+		} else {
+			ctx.String(http.StatusBadRequest, "Missing user id to find collection")
+			return
+		}
+		if viewerID == nil {
+			collectionFind.VisibilityList = []api.Visibility{api.Public}
+		} else if *collectionFind.CreatorID != *viewerID {
+			collectionFind.VisibilityList = []api.Visibility{api.Public, api.Protected}
+		}
+		if memoID, err := strconv.Atoi(ctx.Query("memoId")); err == nil {
+			collectionFind.MemoID = &memoID
+		}
+
+		list, err := s.Store.FindCollectionList(ctx, collectionFind)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to fetch collection list")
+			return
+		}
+		for _, collection := range list {
+			if _, err := s.findCollectionMemoList(ctx, collection, viewerID); err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to find collection memo list")
+				return
+			}
+		}
+		ctx.JSON(http.StatusOK, composeResponse(list))
+	})
+
+	rg.GET("/collection/:collectionId", func(ctx *gin.Context) {
+		viewerID := findViewerID(ctx)
+		collection, ok := s.findViewableCollection(ctx, viewerID)
+		if !ok {
+			return
+		}
+		if _, err := s.findCollectionMemoList(ctx, collection, viewerID); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find collection memo list")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(collection))
+	})
+
+	// The collection page is public for a public collection, the memos are filtered by their own visibility.
+	rg.GET("/collection/:collectionId/memo", func(ctx *gin.Context) {
+		viewerID := findViewerID(ctx)
+		collection, ok := s.findViewableCollection(ctx, viewerID)
+		if !ok {
+			return
+		}
+		memoList, err := s.findCollectionMemoList(ctx, collection, viewerID)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find collection memo list")
+			return
+		}
+		if err := s.composeMemoListEmbedList(ctx, memoList); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to compose memo embed list")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(&api.CollectionPage{
+			Collection: collection,
+			MemoList:   memoList,
+		}))
+	})
+
+	rg.GET("/collection/:collectionId/export", func(ctx *gin.Context) {
+		viewerID := findViewerID(ctx)
+		collection, ok := s.findViewableCollection(ctx, viewerID)
+		if !ok {
+			return
+		}
+		memoList, err := s.findCollectionMemoList(ctx, collection, viewerID)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find collection memo list")
+			return
+		}
+		location, err := s.findUserLocation(ctx, collection.CreatorID)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find user setting")
+			return
+		}
+
+		filename := url.PathEscape(collection.Name + ".md")
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", filename))
+		ctx.Data(http.StatusOK, "text/markdown; charset=utf-8", exportCollectionMarkdown(collection, memoList, location))
+	})
+
+	rg.DELETE("/collection/:collectionId", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
+			return
+		}
+		collection, ok := s.findEditableCollection(ctx, user)
+		if !ok {
+			return
+		}
+
+		if err := s.Store.DeleteCollection(ctx, &api.CollectionDelete{ID: collection.ID}); err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Collection ID not found: %d", collection.ID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to delete collection")
+			return
+		}
+		ctx.JSON(http.StatusOK, true)
+	})
+
+	rg.POST("/collection/:collectionId/memo", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
+			return
+		}
+		collection, ok := s.findEditableCollection(ctx, user)
+		if !ok {
+			return
+		}
+		collectionMemoUpsert := &api.CollectionMemoUpsert{}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(collectionMemoUpsert); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted post collection memo request")
+			return
+		}
+		if !s.validateCollectionMemoIDList(ctx, []int{collectionMemoUpsert.MemoID}, user.ID) {
+			return
+		}
+
+		collectionMemoUpsert.CollectionID = collection.ID
+		collection, err := s.Store.UpsertCollectionMemo(ctx, collectionMemoUpsert)
+		if err != nil {
+			if common.ErrorCode(err) == common.Invalid {
+				ctx.String(http.StatusBadRequest, err.Error())
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to upsert collection memo")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(collection))
+	})
+
+	rg.DELETE("/collection/:collectionId/memo/:memoId", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
+			return
+		}
+		collection, ok := s.findEditableCollection(ctx, user)
+		if !ok {
+			return
+		}
+		memoID, err := strconv.Atoi(ctx.Param("memoId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("memoId")))
+			return
+		}
+
+		if err := s.Store.DeleteCollectionMemo(ctx, &api.CollectionMemoDelete{
+			CollectionID: collection.ID,
+			MemoID:       memoID,
+		}); err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Memo ID not found in collection: %d", memoID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to delete collection memo")
+			return
+		}
+		ctx.JSON(http.StatusOK, true)
+	})
+}
+
+// findViewerID returns the ID of the signed-in user, or nil for an anonymous request.
+func findViewerID(ctx *gin.Context) *int {
+	if _userID, ok := ctx.Get(getUserIDContextKey()); ok {
+		if userID, ok := _userID.(int); ok {
+			return &userID
+		}
+	}
+	return nil
+}
+
+// findCollection finds the collection of the request path, or writes the error response.
+func (s *Service) findCollection(ctx *gin.Context) (*api.Collection, bool) {
+	collectionID, err := strconv.Atoi(ctx.Param("collectionId"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("collectionId")))
+		return nil, false
+	}
+	collection, err := s.Store.FindCollection(ctx, &api.CollectionFind{
+		ID: &collectionID,
+	})
+	if err != nil {
+		if common.ErrorCode(err) == common.NotFound {
+			ctx.String(http.StatusNotFound, fmt.Sprintf("Collection ID not found: %d", collectionID))
+			return nil, false
+		}
+		ctx.String(http.StatusInternalServerError, "Failed to find collection")
+		return nil, false
+	}
+	return collection, true
+}
+
+// findViewableCollection finds the collection of the request path that the viewer can see, or writes the error response.
+func (s *Service) findViewableCollection(ctx *gin.Context, viewerID *int) (*api.Collection, bool) {
+	collection, ok := s.findCollection(ctx)
+	if !ok {
+		return nil, false
+	}
+	canView := collection.Visibility == api.Public ||
+		(collection.Visibility == api.Protected && viewerID != nil) ||
+		(viewerID != nil && collection.CreatorID == *viewerID)
+	if !canView {
+		ctx.String(http.StatusForbidden, "Access forbidden for current session user")
+		return nil, false
+	}
+	return collection, true
+}
+
+// findEditableCollection finds the collection of the request path that the user created, or writes the error response.
+func (s *Service) findEditableCollection(ctx *gin.Context, user *api.User) (*api.Collection, bool) {
+	collection, ok := s.findCollection(ctx)
+	if !ok {
+		return nil, false
+	}
+	if collection.CreatorID != user.ID {
+		ctx.String(http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+	return collection, true
+}
+
+// validateCollectionMemoIDList checks that the user can see the memos added to a collection, or writes the error response.
+func (s *Service) validateCollectionMemoIDList(ctx *gin.Context, memoIDList []int, userID int) bool {
+	for _, memoID := range memoIDList {
+		memo, err := s.Store.FindMemo(ctx, &api.MemoFind{
+			ID: &memoID,
+		})
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusBadRequest, fmt.Sprintf("Memo ID not found: %d", memoID))
+				return false
+			}
+			ctx.String(http.StatusInternalServerError, fmt.Sprintf("Failed to find memo by ID: %v", memoID))
+			return false
+		}
+		canView, err := s.canViewMemo(ctx, memo, &userID)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find user group member")
+			return false
+		}
+		if !canView {
+			ctx.String(http.StatusForbidden, fmt.Sprintf("Access forbidden to memo: %d", memoID))
+			return false
+		}
+	}
+	return true
+}
+
+// findCollectionMemoList finds the normal memos of the collection that the viewer can see, in the order of the collection.
+// The memo ID list of the collection is narrowed to these memos unless the viewer is the creator.
+func (s *Service) findCollectionMemoList(ctx context.Context, collection *api.Collection, viewerID *int) ([]*api.Memo, error) {
+	memoList := []*api.Memo{}
+	for _, memoID := range collection.MemoIDList {
+		memoID := memoID
+		memo, err := s.Store.FindMemo(ctx, &api.MemoFind{
+			ID: &memoID,
+		})
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				continue
+			}
+			return nil, err
+		}
+		if memo.RowStatus != api.Normal {
+			continue
+		}
+		canView, err := s.canViewMemo(ctx, memo, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		if canView {
+			memoList = append(memoList, memo)
+		}
+	}
+	if viewerID == nil || *viewerID != collection.CreatorID {
+		collection.MemoIDList = []int{}
+		for _, memo := range memoList {
+			collection.MemoIDList = append(collection.MemoIDList, memo.ID)
+		}
+	}
+	return memoList, nil
+}
+
+// exportCollectionMarkdown writes the collection as a single Markdown document, a section per memo headed by its created time.
+func exportCollectionMarkdown(collection *api.Collection, memoList []*api.Memo, location *time.Location) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# " + collection.Name + "\n")
+	if description := strings.TrimSpace(collection.Description); description != "" {
+		buf.WriteString("\n" + description + "\n")
+	}
+	for _, memo := range memoList {
+		buf.WriteString("\n## " + time.Unix(memo.CreatedTs, 0).In(location).Format("2006-01-02 15:04") + "\n\n")
+		buf.WriteString(strings.TrimSpace(memo.Content) + "\n")
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,433 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"uamemos/api"
+	"uamemos/common"
+	"uamemos/plugin/idp/oidc"
+	"uamemos/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Service) registerIdentityProviderRoutes(rg *gin.RouterGroup) {
+	rg.POST("/idp", func(ctx *gin.Context) {
+		if _, ok := s.authorize(ctx, api.PermissionSettingManage); !ok {
+			return
+		}
+
+		identityProviderCreate := &api.IdentityProviderCreate{}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(identityProviderCreate); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted post identity provider request")
+			return
+		}
+		if identityProviderCreate.Config == nil {
+			ctx.String(http.StatusBadRequest, "Identity provider config shouldn't be empty")
+			return
+		}
+
+		identityProviderMessage, err := s.Store.CreateIdentityProvider(ctx, &store.IdentityProviderMessage{
+			Name:             identityProviderCreate.Name,
+			Type:             store.IdentityProviderType(identityProviderCreate.Type),
+			IdentifierFilter: identityProviderCreate.IdentifierFilter,
+			Config:           convertIdentityProviderConfigToStore(identityProviderCreate.Config),
+		})
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to create identity provider")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(convertIdentityProviderFromStore(identityProviderMessage)))
+	})
+
+	rg.PATCH("/idp/:idpId", func(ctx *gin.Context) {
+		if _, ok := s.authorize(ctx, api.PermissionSettingManage); !ok {
+			return
+		}
+
+		identityProviderID, err := strconv.Atoi(ctx.Param("idpId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("idpId")))
+			return
+		}
+
+		identityProviderPatch := &api.IdentityProviderPatch{
+			ID: identityProviderID,
+		}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(identityProviderPatch); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted patch identity provider request")
+			return
+		}
+
+		identityProviderMessage, err := s.Store.UpdateIdentityProvider(ctx, &store.UpdateIdentityProviderMessage{
+			ID:               identityProviderPatch.ID,
+			Type:             store.IdentityProviderType(identityProviderPatch.Type),
+			Name:             identityProviderPatch.Name,
+			IdentifierFilter: identityProviderPatch.IdentifierFilter,
+			Config:           convertIdentityProviderConfigToStore(identityProviderPatch.Config),
+		})
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to patch identity provider")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(convertIdentityProviderFromStore(identityProviderMessage)))
+	})
+
+	rg.GET("/idp", func(ctx *gin.Context) {
+		identityProviderMessageList, err := s.Store.ListIdentityProviders(ctx, &store.FindIdentityProviderMessage{})
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find identity provider list")
+			return
+		}
+
+		canManageSetting := false
+		_userID, ok := ctx.Get(getUserIDContextKey())
+		userID, _ok := _userID.(int)
+		if ok && _ok {
+			user, err := s.Store.FindUser(ctx, &api.UserFind{
+				ID: &userID,
+			})
+			if err != nil && common.ErrorCode(err) != common.NotFound {
+				ctx.String(http.StatusInternalServerError, "Failed to find user")
+				return
+			}
+			canManageSetting, err = s.hasPermission(ctx, user, api.PermissionSettingManage)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to find role permissions")
+				return
+			}
+		}
+
+		identityProviderList := []*api.IdentityProvider{}
+		for _, identityProviderMessage := range identityProviderMessageList {
+			identityProvider := convertIdentityProviderFromStore(identityProviderMessage)
+			// data desensitize
+			if !canManageSetting {
+				identityProvider.Config = desensitizeIdentityProviderConfig(identityProvider.Config)
+			}
+			identityProviderList = append(identityProviderList, identityProvider)
+		}
+		ctx.JSON(http.StatusOK, composeResponse(identityProviderList))
+	})
+
+	rg.GET("/idp/:idpId", func(ctx *gin.Context) {
+		// We should only show identity provider detail to users who manage settings.
+		if _, ok := s.authorize(ctx, api.PermissionSettingManage); !ok {
+			return
+		}
+
+		identityProviderID, err := strconv.Atoi(ctx.Param("idpId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("idpId")))
+			return
+		}
+		identityProviderMessage, err := s.Store.GetIdentityProvider(ctx, &store.FindIdentityProviderMessage{
+			ID: &identityProviderID,
+		})
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Identity provider not found: %d", identityProviderID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to find identity provider")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(convertIdentityProviderFromStore(identityProviderMessage)))
+	})
+
+	rg.GET("/idp/:idpId/authorize", func(ctx *gin.Context) {
+		identityProviderID, err := strconv.Atoi(ctx.Param("idpId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("idpId")))
+			return
+		}
+		redirectURI := ctx.Query("redirectUri")
+		if redirectURI == "" {
+			ctx.String(http.StatusBadRequest, "Redirect uri shouldn't be empty")
+			return
+		}
+
+		identityProviderMessage, err := s.Store.GetIdentityProvider(ctx, &store.FindIdentityProviderMessage{
+			ID: &identityProviderID,
+		})
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Identity provider not found: %d", identityProviderID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to find identity provider")
+			return
+		}
+		if identityProviderMessage.Type != store.IdentityProviderOIDC {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("Unsupported identity provider type: %s", identityProviderMessage.Type))
+			return
+		}
+
+		oidcIdentityProvider, err := oidc.NewIdentityProvider(ctx, identityProviderMessage.Config.OIDCConfig)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to create identity provider instance")
+			return
+		}
+		authRequest, err := s.createIdentityProviderAuthRequest(identityProviderID, redirectURI)
+		if err != nil {
+			if errors.Is(err, errTooManyIdentityProviderAuthRequests) {
+				ctx.String(http.StatusTooManyRequests, "Too many pending sign-ins, please try again later")
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to create authorization request")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(&api.IdentityProviderAuthorization{
+			AuthURL: oidcIdentityProvider.AuthCodeURL(redirectURI, authRequest.state, authRequest.nonce, authRequest.codeVerifier),
+			State:   authRequest.state,
+		}))
+	})
+
+	rg.DELETE("/idp/:idpId", func(ctx *gin.Context) {
+		if _, ok := s.authorize(ctx, api.PermissionSettingManage); !ok {
+			return
+		}
+
+		identityProviderID, err := strconv.Atoi(ctx.Param("idpId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("idpId")))
+			return
+		}
+
+		if err = s.Store.DeleteIdentityProvider(ctx, &store.DeleteIdentityProviderMessage{ID: identityProviderID}); err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Identity provider not found: %d", identityProviderID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to delete identity provider")
+			return
+		}
+		ctx.JSON(http.StatusOK, true)
+	})
+}
+
+func convertIdentityProviderFromStore(identityProviderMessage *store.IdentityProviderMessage) *api.IdentityProvider {
+	identityProvider := &api.IdentityProvider{
+		ID:               identityProviderMessage.ID,
+		Name:             identityProviderMessage.Name,
+		Type:             api.IdentityProviderType(identityProviderMessage.Type),
+		IdentifierFilter: identityProviderMessage.IdentifierFilter,
+		Config:           &api.IdentityProviderConfig{},
+	}
+	config := identityProviderMessage.Config
+	if config == nil {
+		return identityProvider
+	}
+	if v := config.OAuth2Config; v != nil {
+		identityProvider.Config.OAuth2Config = &api.IdentityProviderOAuth2Config{
+			ClientID:     v.ClientID,
+			ClientSecret: v.ClientSecret,
+			AuthURL:      v.AuthURL,
+			TokenURL:     v.TokenURL,
+			UserInfoURL:  v.UserInfoURL,
+			Scopes:       v.Scopes,
+			FieldMapping: convertFieldMappingFromStore(v.FieldMapping),
+		}
+	}
+	if v := config.LDAPConfig; v != nil {
+		identityProvider.Config.LDAPConfig = &api.IdentityProviderLDAPConfig{
+			URL:                v.URL,
+			StartTLS:           v.StartTLS,
+			InsecureSkipVerify: v.InsecureSkipVerify,
+			BindDN:             v.BindDN,
+			BindPassword:       v.BindPassword,
+			BaseDN:             v.BaseDN,
+			UserFilter:         v.UserFilter,
+			FieldMapping:       convertFieldMappingFromStore(v.FieldMapping),
+		}
+	}
+	if v := config.OIDCConfig; v != nil {
+		identityProvider.Config.OIDCConfig = &api.IdentityProviderOIDCConfig{
+			Issuer:       v.Issuer,
+			ClientID:     v.ClientID,
+			ClientSecret: v.ClientSecret,
+			Scopes:       v.Scopes,
+			FieldMapping: convertFieldMappingFromStore(v.FieldMapping),
+		}
+	}
+	return identityProvider
+}
+
+func convertIdentityProviderConfigToStore(config *api.IdentityProviderConfig) *store.IdentityProviderConfig {
+	if config == nil {
+		return nil
+	}
+	identityProviderConfig := &store.IdentityProviderConfig{}
+	if v := config.OAuth2Config; v != nil {
+		identityProviderConfig.OAuth2Config = &store.IdentityProviderOAuth2Config{
+			ClientID:     v.ClientID,
+			ClientSecret: v.ClientSecret,
+			AuthURL:      v.AuthURL,
+			TokenURL:     v.TokenURL,
+			UserInfoURL:  v.UserInfoURL,
+			Scopes:       v.Scopes,
+			FieldMapping: convertFieldMappingToStore(v.FieldMapping),
+		}
+	}
+	if v := config.LDAPConfig; v != nil {
+		identityProviderConfig.LDAPConfig = &store.IdentityProviderLDAPConfig{
+			URL:                v.URL,
+			StartTLS:           v.StartTLS,
+			InsecureSkipVerify: v.InsecureSkipVerify,
+			BindDN:             v.BindDN,
+			BindPassword:       v.BindPassword,
+			BaseDN:             v.BaseDN,
+			UserFilter:         v.UserFilter,
+			FieldMapping:       convertFieldMappingToStore(v.FieldMapping),
+		}
+	}
+	if v := config.OIDCConfig; v != nil {
+		identityProviderConfig.OIDCConfig = &store.IdentityProviderOIDCConfig{
+			Issuer:       v.Issuer,
+			ClientID:     v.ClientID,
+			ClientSecret: v.ClientSecret,
+			Scopes:       v.Scopes,
+			FieldMapping: convertFieldMappingToStore(v.FieldMapping),
+		}
+	}
+	return identityProviderConfig
+}
+
+func convertFieldMappingFromStore(fieldMapping *store.FieldMapping) *api.FieldMapping {
+	if fieldMapping == nil {
+		return nil
+	}
+	return &api.FieldMapping{
+		Identifier:  fieldMapping.Identifier,
+		DisplayName: fieldMapping.DisplayName,
+		Email:       fieldMapping.Email,
+	}
+}
+
+func convertFieldMappingToStore(fieldMapping *api.FieldMapping) *store.FieldMapping {
+	if fieldMapping == nil {
+		return nil
+	}
+	return &store.FieldMapping{
+		Identifier:  fieldMapping.Identifier,
+		DisplayName: fieldMapping.DisplayName,
+		Email:       fieldMapping.Email,
+	}
+}
+
+// desensitizeIdentityProviderConfig keeps only what the sign-in page needs to start a flow.
+func desensitizeIdentityProviderConfig(config *api.IdentityProviderConfig) *api.IdentityProviderConfig {
+	desensitized := &api.IdentityProviderConfig{}
+	if v := config.OAuth2Config; v != nil {
+		desensitized.OAuth2Config = &api.IdentityProviderOAuth2Config{
+			ClientID: v.ClientID,
+			AuthURL:  v.AuthURL,
+			Scopes:   v.Scopes,
+		}
+	}
+	if v := config.LDAPConfig; v != nil {
+		desensitized.LDAPConfig = &api.IdentityProviderLDAPConfig{}
+	}
+	if v := config.OIDCConfig; v != nil {
+		desensitized.OIDCConfig = &api.IdentityProviderOIDCConfig{
+			Issuer:   v.Issuer,
+			ClientID: v.ClientID,
+			Scopes:   v.Scopes,
+		}
+	}
+	return desensitized
+}
+
+const (
+	// identityProviderAuthRequestTTL bounds how long a user may stay on the provider's consent page.
+	identityProviderAuthRequestTTL = 10 * time.Minute
+	// maxIdentityProviderAuthRequests bounds the memory held by the pending sign-ins, which anyone can start.
+	maxIdentityProviderAuthRequests = 10000
+	// identityProviderAuthRequestPruneInterval is how often the expired sign-ins are dropped.
+	identityProviderAuthRequestPruneInterval = time.Minute
+)
+
+var errTooManyIdentityProviderAuthRequests = errors.New("too many pending identity provider auth requests")
+
+// identityProviderAuthRequest is the server side state of a pending OpenID Connect sign-in.
+type identityProviderAuthRequest struct {
+	state              string
+	nonce              string
+	codeVerifier       string
+	identityProviderID int
+	redirectURI        string
+	expiresAt          time.Time
+}
+
+func (s *Service) createIdentityProviderAuthRequest(identityProviderID int, redirectURI string) (*identityProviderAuthRequest, error) {
+	state, err := common.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := common.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	codeVerifier, err := oidc.GenerateCodeVerifier()
+	if err != nil {
+		return nil, err
+	}
+
+	authRequest := &identityProviderAuthRequest{
+		state:              state,
+		nonce:              nonce,
+		codeVerifier:       codeVerifier,
+		identityProviderID: identityProviderID,
+		redirectURI:        redirectURI,
+		expiresAt:          time.Now().Add(identityProviderAuthRequestTTL),
+	}
+	s.idpAuthRequestMutex.Lock()
+	defer s.idpAuthRequestMutex.Unlock()
+	if len(s.idpAuthRequests) >= maxIdentityProviderAuthRequests {
+		return nil, errTooManyIdentityProviderAuthRequests
+	}
+	s.idpAuthRequests[state] = authRequest
+	return authRequest, nil
+}
+
+// consumeIdentityProviderAuthRequest returns the pending request of the given state, each state can only be used once.
+func (s *Service) consumeIdentityProviderAuthRequest(state string) *identityProviderAuthRequest {
+	s.idpAuthRequestMutex.Lock()
+	authRequest, ok := s.idpAuthRequests[state]
+	delete(s.idpAuthRequests, state)
+	s.idpAuthRequestMutex.Unlock()
+	if !ok || time.Now().After(authRequest.expiresAt) {
+		return nil
+	}
+	return authRequest
+}
+
+// pruneIdentityProviderAuthRequests drops the requests that were never completed until the context is done.
+func (s *Service) pruneIdentityProviderAuthRequests(ctx context.Context) {
+	ticker := time.NewTicker(identityProviderAuthRequestPruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.deleteExpiredIdentityProviderAuthRequests(now)
+		}
+	}
+}
+
+func (s *Service) deleteExpiredIdentityProviderAuthRequests(now time.Time) {
+	s.idpAuthRequestMutex.Lock()
+	defer s.idpAuthRequestMutex.Unlock()
+	for state, authRequest := range s.idpAuthRequests {
+		if now.After(authRequest.expiresAt) {
+			delete(s.idpAuthRequests, state)
+		}
+	}
+}
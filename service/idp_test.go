@@ -0,0 +1,34 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityProviderAuthRequestLimit(t *testing.T) {
+	s := &Service{idpAuthRequests: map[string]*identityProviderAuthRequest{}}
+	var first *identityProviderAuthRequest
+	for i := 0; i < maxIdentityProviderAuthRequests; i++ {
+		authRequest, err := s.createIdentityProviderAuthRequest(1, "http://localhost/auth/callback")
+		require.NoError(t, err)
+		if first == nil {
+			first = authRequest
+		}
+	}
+	_, err := s.createIdentityProviderAuthRequest(1, "http://localhost/auth/callback")
+	require.ErrorIs(t, err, errTooManyIdentityProviderAuthRequests)
+
+	// A consumed request makes room for a new one, and can't be consumed again.
+	require.Equal(t, first, s.consumeIdentityProviderAuthRequest(first.state))
+	require.Nil(t, s.consumeIdentityProviderAuthRequest(first.state))
+	_, err = s.createIdentityProviderAuthRequest(1, "http://localhost/auth/callback")
+	require.NoError(t, err)
+
+	// The expired requests are dropped.
+	s.deleteExpiredIdentityProviderAuthRequests(time.Now())
+	require.Len(t, s.idpAuthRequests, maxIdentityProviderAuthRequests)
+	s.deleteExpiredIdentityProviderAuthRequests(time.Now().Add(identityProviderAuthRequestTTL + time.Second))
+	require.Empty(t, s.idpAuthRequests)
+}
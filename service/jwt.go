@@ -9,6 +9,7 @@ import (
 	"uamemos/api"
 	"uamemos/common"
 	"uamemos/service/auth"
+	"uamemos/store"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
@@ -101,7 +102,7 @@ func JWTMiddleware(server *Service, ctx *gin.Context, secret string) {
 		return
 	}
 
-	if common.HasPrefixes(path, "/api/ping", "/api/idp", "/api/user/:id") && method == http.MethodGet {
+	if common.HasPrefixes(path, "/api/ping", "/api/user/:id") && method == http.MethodGet {
 		ctx.Next()
 		return
 	}
@@ -114,8 +115,9 @@ func JWTMiddleware(server *Service, ctx *gin.Context, secret string) {
 			ctx.Next()
 			return
 		}
-		// When the request is not authenticated, we allow the user to access the memo endpoints for those public memos.
-		if common.HasPrefixes(path, "/api/status", "/api/memo") && method == http.MethodGet {
+		// When the request is not authenticated, we allow the user to access the memo endpoints for those public memos,
+		// the memo share links, the public collections, and the identity provider list for the sign-in page.
+		if common.HasPrefixes(path, "/api/status", "/api/memo", "/api/share", "/api/collection", "/api/idp") && method == http.MethodGet {
 			ctx.Next()
 			return
 		}
@@ -237,5 +239,6 @@ func JWTMiddleware(server *Service, ctx *gin.Context, secret string) {
 
 	// Stores userID into context.
 	ctx.Set(getUserIDContextKey(), userID)
+	ctx.Request = ctx.Request.WithContext(store.WithViewerID(ctx.Request.Context(), userID))
 	ctx.Next()
 }
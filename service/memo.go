@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -13,17 +14,24 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	// defaultMemoPageLimit is the page size of cursor paginated memo lists.
+	defaultMemoPageLimit = 20
+	// maxMemoPageLimit is the largest page size a client can ask for.
+	maxMemoPageLimit = 100
 )
 
 func (s *Service) registerMemoRoutes(rg *gin.RouterGroup) {
 	rg.POST("/memo", func(ctx *gin.Context) {
 
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
 			return
 		}
+		userID := user.ID
 
 		memoCreate := &api.MemoCreate{}
 		if err := json.NewDecoder(ctx.Request.Body).Decode(memoCreate); err != nil {
@@ -71,27 +79,71 @@ func (s *Service) registerMemoRoutes(rg *gin.RouterGroup) {
 				return
 			}
 			if disablePublicMemos {
-				// Allow if the user is an admin.
-				user, err := s.Store.FindUser(ctx, &api.UserFind{
-					ID: &userID,
-				})
+				// Only enforce private for those who can't publish.
+				// Admins should know what they're doing.
+				canPublish, err := s.hasPermission(ctx, user, api.PermissionMemoPublish)
 				if err != nil {
-					ctx.String(http.StatusInternalServerError, "Failed to find user")
+					ctx.String(http.StatusInternalServerError, "Failed to find role permissions")
 					return
 				}
-				// Only enforce private if you're a regular user.
-				// Admins should know what they're doing.
-				if user.Role == "USER" {
+				if !canPublish {
 					memoCreate.Visibility = api.Private
 				}
 			}
 		}
 
+		if memoCreate.TemplateID != nil {
+			content, err := s.expandMemoTemplate(ctx, user, *memoCreate.TemplateID)
+			if err != nil {
+				if common.ErrorCode(err) == common.NotFound {
+					ctx.String(http.StatusBadRequest, fmt.Sprintf("Memo template not found: %d", *memoCreate.TemplateID))
+					return
+				}
+				ctx.String(http.StatusInternalServerError, "Failed to expand memo template")
+				return
+			}
+			if memoCreate.Content != "" {
+				content += "\n\n" + memoCreate.Content
+			}
+			memoCreate.Content = content
+		}
+
 		if len(memoCreate.Content) > api.MaxContentLength {
 			ctx.String(http.StatusBadRequest, "Content size overflow, up to 1MB")
 			return
 		}
 
+		if memoCreate.Visibility == api.Group {
+			if memoCreate.GroupID == 0 {
+				groupID, err := s.findUserMemoVisibilityGroupID(ctx, userID)
+				if err != nil {
+					ctx.String(http.StatusInternalServerError, "Failed to find user setting")
+					return
+				}
+				memoCreate.GroupID = groupID
+			}
+			if err := s.validateMemoGroup(ctx, memoCreate.GroupID, userID); err != nil {
+				ctx.String(http.StatusBadRequest, err.Error())
+				return
+			}
+		} else {
+			memoCreate.GroupID = 0
+		}
+
+		if memoCreate.Location != nil {
+			if err := memoCreate.Location.Validate(); err != nil {
+				ctx.String(http.StatusBadRequest, err.Error())
+				return
+			}
+		} else {
+			location, err := s.findResourceListLocation(ctx, memoCreate.ResourceIDList, userID)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to find resource")
+				return
+			}
+			memoCreate.Location = location
+		}
+
 		memoCreate.CreatorID = userID
 		memo, err := s.Store.CreateMemo(ctx, memoCreate)
 		if err != nil {
@@ -102,6 +154,10 @@ func (s *Service) registerMemoRoutes(rg *gin.RouterGroup) {
 			ctx.String(http.StatusInternalServerError, "Failed to create activity")
 			return
 		}
+		if err := s.createMentionNotificationList(ctx, memo, ""); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to create notification")
+			return
+		}
 
 		for _, resourceID := range memoCreate.ResourceIDList {
 			if _, err := s.Store.UpsertMemoResource(ctx, &api.MemoResourceUpsert{
@@ -118,17 +174,21 @@ func (s *Service) registerMemoRoutes(rg *gin.RouterGroup) {
 			ctx.String(http.StatusInternalServerError, "Failed to compose memo")
 			return
 		}
+		s.federateMemo(ctx, nil, memo)
+		if err := s.composeMemoEmbedList(ctx, memo); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to compose memo embed list")
+			return
+		}
 		ctx.JSON(http.StatusOK, composeResponse(memo))
 	})
 
 	rg.PATCH("/memo/:memoId", func(ctx *gin.Context) {
 
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
 			return
 		}
+		userID := user.ID
 
 		memoID, err := strconv.Atoi(ctx.Param("memoId"))
 		if err != nil {
@@ -157,17 +217,80 @@ func (s *Service) registerMemoRoutes(rg *gin.RouterGroup) {
 			ctx.String(http.StatusBadRequest, "Malformatted patch memo request")
 			return
 		}
+		// The If-Match header takes precedence over the expected version of the body.
+		expectedVersion, err := parseMemoIfMatch(ctx)
+		if err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		if expectedVersion != nil {
+			memoPatch.ExpectedVersion = expectedVersion
+		}
 
 		if memoPatch.Content != nil && len(*memoPatch.Content) > api.MaxContentLength {
 			ctx.String(http.StatusBadRequest, "Content size overflow, up to 1MB")
 			return
 		}
 
+		visibility := memo.Visibility
+		if memoPatch.Visibility != nil {
+			visibility = *memoPatch.Visibility
+		}
+		if memo.ParentID != 0 {
+			if memoPatch.Visibility != nil || memoPatch.GroupID != nil {
+				ctx.String(http.StatusBadRequest, "Comment visibility is inherited from its memo")
+				return
+			}
+		} else if visibility == api.Group {
+			groupID := memo.GroupID
+			if memoPatch.GroupID != nil {
+				groupID = *memoPatch.GroupID
+			}
+			if groupID == 0 {
+				groupID, err = s.findUserMemoVisibilityGroupID(ctx, userID)
+				if err != nil {
+					ctx.String(http.StatusInternalServerError, "Failed to find user setting")
+					return
+				}
+			}
+			if err := s.validateMemoGroup(ctx, groupID, userID); err != nil {
+				ctx.String(http.StatusBadRequest, err.Error())
+				return
+			}
+			memoPatch.GroupID = &groupID
+		} else {
+			groupID := 0
+			memoPatch.GroupID = &groupID
+		}
+
+		if memoPatch.Location != nil {
+			if err := memoPatch.Location.Validate(); err != nil {
+				ctx.String(http.StatusBadRequest, err.Error())
+				return
+			}
+		} else if memo.Location == nil && !memoPatch.ClearLocation {
+			location, err := s.findResourceListLocation(ctx, memoPatch.ResourceIDList, userID)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to find resource")
+				return
+			}
+			memoPatch.Location = location
+		}
+
+		previousMemo := memo
 		memo, err = s.Store.PatchMemo(ctx, memoPatch)
 		if err != nil {
+			if common.ErrorCode(err) == common.Conflict {
+				s.writeMemoConflict(ctx, memoID)
+				return
+			}
 			ctx.String(http.StatusInternalServerError, "Failed to patch memo")
 			return
 		}
+		if err := s.createMentionNotificationList(ctx, memo, previousMemo.Content); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to create notification")
+			return
+		}
 
 		for _, resourceID := range memoPatch.ResourceIDList {
 			if _, err := s.Store.UpsertMemoResource(ctx, &api.MemoResourceUpsert{
@@ -184,6 +307,12 @@ func (s *Service) registerMemoRoutes(rg *gin.RouterGroup) {
 			ctx.String(http.StatusInternalServerError, "Failed to compose memo")
 			return
 		}
+		s.federateMemo(ctx, previousMemo, memo)
+		if err := s.composeMemoEmbedList(ctx, memo); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to compose memo embed list")
+			return
+		}
+		ctx.Header("ETag", memoETag(memo))
 		ctx.JSON(http.StatusOK, composeResponse(memo))
 	})
 
@@ -203,10 +332,11 @@ func (s *Service) registerMemoRoutes(rg *gin.RouterGroup) {
 			}
 			memoFind.VisibilityList = []api.Visibility{api.Public}
 		} else {
+			memoFind.ViewerID = &currentUserID
 			if memoFind.CreatorID == nil {
 				memoFind.CreatorID = &currentUserID
 			} else {
-				memoFind.VisibilityList = []api.Visibility{api.Public, api.Protected}
+				memoFind.VisibilityList = []api.Visibility{api.Public, api.Protected, api.Group}
 			}
 		}
 
@@ -221,8 +351,7 @@ func (s *Service) registerMemoRoutes(rg *gin.RouterGroup) {
 		}
 		tag := ctx.Query("tag")
 		if tag != "" {
-			contentSearch := "#" + tag
-			memoFind.ContentSearch = &contentSearch
+			memoFind.Tag = &tag
 		}
 		visibilityListStr := ctx.Query("visibility")
 		if visibilityListStr != "" {
@@ -230,21 +359,40 @@ func (s *Service) registerMemoRoutes(rg *gin.RouterGroup) {
 			for _, visibility := range strings.Split(visibilityListStr, ",") {
 				visibilityList = append(visibilityList, api.Visibility(visibility))
 			}
-			memoFind.VisibilityList = visibilityList
+			memoFind.VisibilityList = filterVisibilityList(visibilityList, memoFind.VisibilityList)
+			if len(memoFind.VisibilityList) == 0 {
+				ctx.JSON(http.StatusOK, composeResponse(&api.MemoPage{MemoList: []*api.Memo{}}))
+				return
+			}
 		}
-		if limit, err := strconv.Atoi(ctx.Query("limit")); err == nil {
-			memoFind.Limit = &limit
+		if filter := ctx.Query("filter"); filter != "" {
+			memoFilter, err := api.ParseMemoFilter(filter)
+			if err != nil {
+				ctx.String(http.StatusBadRequest, err.Error())
+				return
+			}
+			memoFind.Filter = memoFilter
+		}
+		if bbox := ctx.Query("bbox"); bbox != "" {
+			boundingBox, err := api.ParseBoundingBox(bbox)
+			if err != nil {
+				ctx.String(http.StatusBadRequest, err.Error())
+				return
+			}
+			memoFind.BoundingBox = boundingBox
 		}
-		if offset, err := strconv.Atoi(ctx.Query("offset")); err == nil {
-			memoFind.Offset = &offset
+		limit, err := parseMemoPageQuery(ctx, memoFind)
+		if err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
 		}
 
-		list, err := s.Store.FindMemoList(ctx, memoFind)
+		memoPage, err := s.findMemoPage(ctx, memoFind, limit)
 		if err != nil {
 			ctx.String(http.StatusInternalServerError, "Failed to fetch memo list")
 			return
 		}
-		ctx.JSON(http.StatusOK, composeResponse(list))
+		ctx.JSON(http.StatusOK, composeResponse(memoPage))
 	})
 
 	rg.GET("/memo/:memoId", func(ctx *gin.Context) {
@@ -280,22 +428,62 @@ func (s *Service) registerMemoRoutes(rg *gin.RouterGroup) {
 				ctx.String(http.StatusForbidden, "this memo is protected, missing user in session")
 				return
 			}
+		} else if memo.Visibility == api.Group {
+			if !ok || !_ok {
+				ctx.String(http.StatusForbidden, "this memo is visible to its group, missing user in session")
+				return
+			}
+			canView, err := s.canViewMemo(ctx, memo, &userID)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to find user group member")
+				return
+			}
+			if !canView {
+				ctx.String(http.StatusForbidden, "this memo is visible to its group only")
+				return
+			}
+		}
+		if err := s.composeMemoEmbedList(ctx, memo); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to compose memo embed list")
+			return
 		}
+		ctx.Header("ETag", memoETag(memo))
 		ctx.JSON(http.StatusOK, composeResponse(memo))
 	})
 
-	rg.POST("/memo/:memoId/organizer", func(ctx *gin.Context) {
+	rg.GET("/memo/:memoId/html", func(ctx *gin.Context) {
+		var viewerID *int
+		if _userID, ok := ctx.Get(getUserIDContextKey()); ok {
+			if userID, ok := _userID.(int); ok {
+				viewerID = &userID
+			}
+		}
 
-		memoID, err := strconv.Atoi(ctx.Param("memoId"))
+		memo, ok := s.findViewableMemo(ctx, viewerID)
+		if !ok {
+			return
+		}
+
+		html, err := renderMemoContentHTML(memo.Content)
 		if err != nil {
-			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("memoId")))
+			ctx.String(http.StatusInternalServerError, "Failed to render memo content")
 			return
 		}
+		ctx.JSON(http.StatusOK, composeResponse(&api.MemoHTML{
+			MemoID:    memo.ID,
+			UpdatedTs: memo.UpdatedTs,
+			HTML:      html,
+		}))
+	})
 
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
+	rg.POST("/memo/:memoId/organizer", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
+			return
+		}
+		// The organizer is per user, any viewer of the memo can pin, bookmark or read it later.
+		memo, ok := s.findViewableMemo(ctx, &user.ID)
+		if !ok {
 			return
 		}
 		memoOrganizerUpsert := &api.MemoOrganizerUpsert{}
@@ -303,27 +491,55 @@ func (s *Service) registerMemoRoutes(rg *gin.RouterGroup) {
 			ctx.String(http.StatusBadRequest, "Malformatted post memo organizer request")
 			return
 		}
-		memoOrganizerUpsert.MemoID = memoID
-		memoOrganizerUpsert.UserID = userID
+		memoOrganizerUpsert.MemoID = memo.ID
+		memoOrganizerUpsert.UserID = user.ID
 
-		err = s.Store.UpsertMemoOrganizer(ctx, memoOrganizerUpsert)
-		if err != nil {
+		if err := s.Store.UpsertMemoOrganizer(ctx, memoOrganizerUpsert); err != nil {
 			ctx.String(http.StatusInternalServerError, "Failed to upsert memo organizer")
 			return
 		}
+		if err := s.Store.ComposeMemoOrganizer(ctx, memo); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to compose memo organizer")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(memo))
+	})
 
-		memo, err := s.Store.FindMemo(ctx, &api.MemoFind{
-			ID: &memoID,
-		})
-		if err != nil {
+	rg.POST("/memo/organizer/pinned-order", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
+			return
+		}
+		memoPinnedOrder := &api.MemoPinnedOrder{}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(memoPinnedOrder); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted post memo pinned order request")
+			return
+		}
+		if err := memoPinnedOrder.Validate(); err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		memoPinnedOrder.UserID = user.ID
+
+		if err := s.Store.OrderPinnedMemo(ctx, memoPinnedOrder); err != nil {
 			if common.ErrorCode(err) == common.NotFound {
-				ctx.String(http.StatusNotFound, fmt.Sprintf("Memo ID not found: %d", memoID))
+				ctx.String(http.StatusBadRequest, err.Error())
 				return
 			}
-			ctx.String(http.StatusInternalServerError, fmt.Sprintf("Failed to find memo by ID: %v", memoID))
+			ctx.String(http.StatusInternalServerError, "Failed to order pinned memos")
 			return
 		}
-		ctx.JSON(http.StatusOK, composeResponse(memo))
+		ctx.JSON(http.StatusOK, true)
+	})
+
+	rg.GET("/memo/bookmark", func(ctx *gin.Context) {
+		bookmarked := true
+		s.serveMemoOrganizerPage(ctx, &api.MemoFind{Bookmarked: &bookmarked})
+	})
+
+	rg.GET("/memo/read-later", func(ctx *gin.Context) {
+		readLater := true
+		s.serveMemoOrganizerPage(ctx, &api.MemoFind{ReadLater: &readLater})
 	})
 
 	rg.POST("/memo/:memoId/resource", func(ctx *gin.Context) {
@@ -334,12 +550,11 @@ func (s *Service) registerMemoRoutes(rg *gin.RouterGroup) {
 			return
 		}
 
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
 			return
 		}
+		userID := user.ID
 		memoResourceUpsert := &api.MemoResourceUpsert{}
 		if err := json.NewDecoder(ctx.Request.Body).Decode(memoResourceUpsert); err != nil {
 			ctx.String(http.StatusBadRequest, "Malformatted post memo resource request")
@@ -391,31 +606,11 @@ func (s *Service) registerMemoRoutes(rg *gin.RouterGroup) {
 	})
 
 	rg.GET("/memo/stats", func(ctx *gin.Context) {
-
-		normalStatus := api.Normal
-		memoFind := &api.MemoFind{
-			RowStatus: &normalStatus,
-		}
-		if creatorID, err := strconv.Atoi(ctx.Query("creatorId")); err == nil {
-			memoFind.CreatorID = &creatorID
-		}
-		if memoFind.CreatorID == nil {
-			ctx.String(http.StatusBadRequest, "Missing user id to find memo")
+		memoFind, ok := parseMemoStatsFind(ctx)
+		if !ok {
 			return
 		}
 
-		_currentUserID, ok := ctx.Get(getUserIDContextKey())
-		currentUserID, _ok := _currentUserID.(int)
-		if !ok || !_ok {
-			memoFind.VisibilityList = []api.Visibility{api.Public}
-		} else {
-			if *memoFind.CreatorID != currentUserID {
-				memoFind.VisibilityList = []api.Visibility{api.Public, api.Protected}
-			} else {
-				memoFind.VisibilityList = []api.Visibility{api.Public, api.Protected, api.Private}
-			}
-		}
-
 		list, err := s.Store.FindMemoList(ctx, memoFind)
 		if err != nil {
 			ctx.String(http.StatusInternalServerError, "Failed to fetch memo list")
@@ -434,11 +629,12 @@ func (s *Service) registerMemoRoutes(rg *gin.RouterGroup) {
 		memoFind := &api.MemoFind{}
 
 		_userID, ok := ctx.Get(getUserIDContextKey())
-		_, _ok := _userID.(int)
+		userID, _ok := _userID.(int)
 		if !ok || !_ok {
 			memoFind.VisibilityList = []api.Visibility{api.Public}
 		} else {
-			memoFind.VisibilityList = []api.Visibility{api.Public, api.Protected}
+			memoFind.ViewerID = &userID
+			memoFind.VisibilityList = []api.Visibility{api.Public, api.Protected, api.Group}
 		}
 
 		pinnedStr := ctx.Query("pinned")
@@ -448,8 +644,7 @@ func (s *Service) registerMemoRoutes(rg *gin.RouterGroup) {
 		}
 		tag := ctx.Query("tag")
 		if tag != "" {
-			contentSearch := "#" + tag + " "
-			memoFind.ContentSearch = &contentSearch
+			memoFind.Tag = &tag
 		}
 		visibilityListStr := ctx.Query("visibility")
 		if visibilityListStr != "" {
@@ -457,35 +652,75 @@ func (s *Service) registerMemoRoutes(rg *gin.RouterGroup) {
 			for _, visibility := range strings.Split(visibilityListStr, ",") {
 				visibilityList = append(visibilityList, api.Visibility(visibility))
 			}
-			memoFind.VisibilityList = visibilityList
+			memoFind.VisibilityList = filterVisibilityList(visibilityList, memoFind.VisibilityList)
+			if len(memoFind.VisibilityList) == 0 {
+				ctx.JSON(http.StatusOK, composeResponse(&api.MemoPage{MemoList: []*api.Memo{}}))
+				return
+			}
 		}
-		if limit, err := strconv.Atoi(ctx.Query("limit")); err == nil {
-			memoFind.Limit = &limit
+		if filter := ctx.Query("filter"); filter != "" {
+			memoFilter, err := api.ParseMemoFilter(filter)
+			if err != nil {
+				ctx.String(http.StatusBadRequest, err.Error())
+				return
+			}
+			memoFind.Filter = memoFilter
 		}
-		if offset, err := strconv.Atoi(ctx.Query("offset")); err == nil {
-			memoFind.Offset = &offset
+		limit, err := parseMemoPageQuery(ctx, memoFind)
+		if err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
 		}
 
 		// Only fetch normal status memos.
 		normalStatus := api.Normal
 		memoFind.RowStatus = &normalStatus
 
-		list, err := s.Store.FindMemoList(ctx, memoFind)
+		memoPage, err := s.findMemoPage(ctx, memoFind, limit)
 		if err != nil {
 			ctx.String(http.StatusInternalServerError, "Failed to fetch all memo list")
 			return
 		}
-		ctx.JSON(http.StatusOK, composeResponse(list))
+		ctx.JSON(http.StatusOK, composeResponse(memoPage))
+	})
+
+	rg.GET("/memo/timeline", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+
+		normalStatus := api.Normal
+		memoFind := &api.MemoFind{
+			RowStatus:      &normalStatus,
+			TimelineUserID: &user.ID,
+			ViewerID:       &user.ID,
+			VisibilityList: []api.Visibility{api.Public, api.Protected, api.Group},
+		}
+		// The timeline is ordered by time only, pinning is personal to the creators.
+		pinnedFirst := false
+		memoFind.PinnedFirst = &pinnedFirst
+		limit, err := parseMemoPageQuery(ctx, memoFind)
+		if err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		memoPage, err := s.findMemoPage(ctx, memoFind, limit)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to fetch timeline memo list")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(memoPage))
 	})
 
 	rg.DELETE("/memo/:memoId", func(ctx *gin.Context) {
 
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
 			return
 		}
+		userID := user.ID
 		memoID, err := strconv.Atoi(ctx.Param("memoId"))
 		if err != nil {
 			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("memoId")))
@@ -515,17 +750,17 @@ func (s *Service) registerMemoRoutes(rg *gin.RouterGroup) {
 			ctx.String(http.StatusInternalServerError, fmt.Sprintf("Failed to delete memo ID: %v", memoID))
 			return
 		}
+		s.federateMemo(ctx, memo, nil)
 		ctx.JSON(http.StatusOK, true)
 	})
 
 	rg.DELETE("/memo/:memoId/resource/:resourceId", func(ctx *gin.Context) {
 
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
 			return
 		}
+		userID := user.ID
 		memoID, err := strconv.Atoi(ctx.Param("memoId"))
 		if err != nil {
 			ctx.String(http.StatusBadRequest, fmt.Sprintf("Memo ID is not a number: %s", ctx.Param("memoId")))
@@ -561,6 +796,207 @@ func (s *Service) registerMemoRoutes(rg *gin.RouterGroup) {
 	})
 }
 
+// canViewMemo reports whether the viewer can see the memo, viewerID is nil when not signed in.
+func (s *Service) canViewMemo(ctx context.Context, memo *api.Memo, viewerID *int) (bool, error) {
+	switch memo.Visibility {
+	case api.Public:
+		return true, nil
+	case api.Protected:
+		return viewerID != nil, nil
+	case api.Group:
+		if viewerID == nil {
+			return false, nil
+		}
+		if memo.CreatorID == *viewerID {
+			return true, nil
+		}
+		return s.isUserGroupMember(ctx, memo.GroupID, *viewerID)
+	default:
+		return viewerID != nil && memo.CreatorID == *viewerID, nil
+	}
+}
+
+// filterVisibilityList keeps the requested visibilities which are allowed, an empty allowed list allows all.
+func filterVisibilityList(requested, allowed []api.Visibility) []api.Visibility {
+	if len(allowed) == 0 {
+		return requested
+	}
+	list := []api.Visibility{}
+	for _, visibility := range requested {
+		if slices.Contains(allowed, visibility) {
+			list = append(list, visibility)
+		}
+	}
+	return list
+}
+
+// serveMemoOrganizerPage writes a page of the memos organized by the current user, which are still visible to the user.
+func (s *Service) serveMemoOrganizerPage(ctx *gin.Context, memoFind *api.MemoFind) {
+	user, ok := s.authorize(ctx)
+	if !ok {
+		return
+	}
+	normalStatus := api.Normal
+	pinnedFirst := false
+	memoFind.ViewerID = &user.ID
+	memoFind.IncludeViewerMemos = true
+	memoFind.VisibilityList = []api.Visibility{api.Public, api.Protected, api.Group}
+	memoFind.RowStatus = &normalStatus
+	memoFind.PinnedFirst = &pinnedFirst
+	limit, err := parseMemoPageQuery(ctx, memoFind)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	memoPage, err := s.findMemoPage(ctx, memoFind, limit)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "Failed to fetch memo list")
+		return
+	}
+	ctx.JSON(http.StatusOK, composeResponse(memoPage))
+}
+
+// memoETag is the entity tag of the memo, its quoted version.
+func memoETag(memo *api.Memo) string {
+	return strconv.Quote(strconv.Itoa(memo.Version))
+}
+
+// parseMemoIfMatch parses the If-Match header into the expected version of the memo, nil without the header or for *.
+func parseMemoIfMatch(ctx *gin.Context) (*int, error) {
+	ifMatch := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return nil, nil
+	}
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`))
+	if err != nil {
+		return nil, fmt.Errorf("invalid If-Match header: %s", ifMatch)
+	}
+	return &version, nil
+}
+
+// writeMemoConflict writes the conflict response of a failed precondition with the current version of the memo.
+func (s *Service) writeMemoConflict(ctx *gin.Context, memoID int) {
+	memo, err := s.Store.FindMemo(ctx, &api.MemoFind{
+		ID: &memoID,
+	})
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, fmt.Sprintf("Failed to find memo by ID: %v", memoID))
+		return
+	}
+	if err := s.composeMemoEmbedList(ctx, memo); err != nil {
+		ctx.String(http.StatusInternalServerError, "Failed to compose memo embed list")
+		return
+	}
+	ctx.Header("ETag", memoETag(memo))
+	ctx.JSON(http.StatusConflict, composeResponse(memo))
+}
+
+// parseMemoPageQuery sets the sort and the cursor of the find from the query, and returns the page size.
+// A PinnedFirst already set by the caller is not overridden by the query.
+func parseMemoPageQuery(ctx *gin.Context, memoFind *api.MemoFind) (int, error) {
+	memoFind.Sort = api.MemoSortCreated
+	if sort := ctx.Query("sort"); sort != "" {
+		memoFind.Sort = api.MemoSort(sort)
+		if memoFind.Sort != api.MemoSortCreated && memoFind.Sort != api.MemoSortUpdated {
+			return 0, fmt.Errorf("invalid sort: %s", sort)
+		}
+	}
+	if memoFind.PinnedFirst == nil {
+		pinnedFirst := ctx.Query("pinnedFirst") != "false"
+		memoFind.PinnedFirst = &pinnedFirst
+	}
+	if cursorStr := ctx.Query("cursor"); cursorStr != "" {
+		cursor, err := api.ParseMemoCursor(cursorStr)
+		if err != nil {
+			return 0, err
+		}
+		if cursor.Sort != memoFind.Sort || (cursor.Pinned != nil) != *memoFind.PinnedFirst {
+			return 0, fmt.Errorf("cursor does not match the sort of the list")
+		}
+		memoFind.Cursor = cursor
+	}
+	// The offset is kept for the clients paging by offset, it can't be combined with a cursor.
+	if offsetStr := ctx.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return 0, fmt.Errorf("invalid offset: %s", offsetStr)
+		}
+		if memoFind.Cursor != nil {
+			return 0, fmt.Errorf("offset can't be used with a cursor")
+		}
+		memoFind.Offset = &offset
+	}
+
+	limit := defaultMemoPageLimit
+	if v, err := strconv.Atoi(ctx.Query("limit")); err == nil && v > 0 {
+		limit = common.Min(v, maxMemoPageLimit)
+	}
+	return limit, nil
+}
+
+// findMemoPage finds a page of memos after the cursor of the find, and the total of all the pages.
+func (s *Service) findMemoPage(ctx context.Context, memoFind *api.MemoFind, limit int) (*api.MemoPage, error) {
+	// Find one more memo to know whether there is a next page.
+	findLimit := limit + 1
+	memoFind.Limit = &findLimit
+	list, err := s.Store.FindMemoList(ctx, memoFind)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.Store.CountMemo(ctx, memoFind)
+	if err != nil {
+		return nil, err
+	}
+
+	memoPage := &api.MemoPage{
+		MemoList: list,
+		Total:    total,
+	}
+	if len(list) > limit {
+		memoPage.MemoList = list[:limit]
+		pinnedFirst := memoFind.PinnedFirst == nil || *memoFind.PinnedFirst
+		memoPage.NextCursor = api.NewMemoCursor(memoPage.MemoList[limit-1], memoFind.Sort, pinnedFirst).String()
+	}
+	if err := s.composeMemoListEmbedList(ctx, memoPage.MemoList); err != nil {
+		return nil, err
+	}
+	return memoPage, nil
+}
+
+// validateMemoGroup checks that the user can share a GROUP visibility memo with the group.
+func (s *Service) validateMemoGroup(ctx context.Context, groupID, userID int) error {
+	if groupID == 0 {
+		return fmt.Errorf("group id is required for group visibility")
+	}
+	isMember, err := s.isUserGroupMember(ctx, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return fmt.Errorf("user is not a member of group %d", groupID)
+	}
+	return nil
+}
+
+// findUserMemoVisibilityGroupID returns the default group of the user's GROUP visibility memos, 0 if unset.
+func (s *Service) findUserMemoVisibilityGroupID(ctx context.Context, userID int) (int, error) {
+	userSetting, err := s.Store.FindUserSetting(ctx, &api.UserSettingFind{
+		UserID: userID,
+		Key:    api.UserSettingMemoVisibilityGroupKey,
+	})
+	if err != nil {
+		return 0, err
+	}
+	groupID := 0
+	if userSetting != nil {
+		if err := json.Unmarshal([]byte(userSetting.Value), &groupID); err != nil {
+			return 0, err
+		}
+	}
+	return groupID, nil
+}
+
 func (s *Service) createMemoCreateActivity(ctx *gin.Context, memo *api.Memo) error {
 
 	payload := api.ActivityMemoCreatePayload{
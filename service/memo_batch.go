@@ -0,0 +1,126 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"uamemos/api"
+	"uamemos/common"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+func (s *Service) registerMemoBatchRoutes(rg *gin.RouterGroup) {
+	rg.POST("/memo/batch", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
+			return
+		}
+
+		memoBatch := &api.MemoBatch{}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(memoBatch); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted post memo batch request")
+			return
+		}
+		if err := memoBatch.Validate(); err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("Invalid memo batch: %v", err))
+			return
+		}
+		if memoBatch.Filter != "" {
+			filter, err := api.ParseMemoFilter(memoBatch.Filter)
+			if err != nil {
+				ctx.String(http.StatusBadRequest, fmt.Sprintf("Invalid memo batch: %v", err))
+				return
+			}
+			memoBatch.ParsedFilter = filter
+		}
+		if memoBatch.Operation == api.MemoBatchVisibility {
+			if memoBatch.Visibility == api.Group {
+				if memoBatch.GroupID == 0 {
+					groupID, err := s.findUserMemoVisibilityGroupID(ctx, user.ID)
+					if err != nil {
+						ctx.String(http.StatusInternalServerError, "Failed to find user setting")
+						return
+					}
+					memoBatch.GroupID = groupID
+				}
+				if err := s.validateMemoGroup(ctx, memoBatch.GroupID, user.ID); err != nil {
+					ctx.String(http.StatusBadRequest, err.Error())
+					return
+				}
+			} else {
+				memoBatch.GroupID = 0
+			}
+		}
+
+		memoBatch.CreatorID = user.ID
+		memoBatch.UpdatedTs = time.Now().Unix()
+		previousMemoList, err := s.Store.BatchMemo(ctx, memoBatch)
+		if err != nil {
+			switch common.ErrorCode(err) {
+			case common.NotFound:
+				ctx.String(http.StatusNotFound, err.Error())
+			case common.NotAuthorized:
+				ctx.String(http.StatusUnauthorized, err.Error())
+			case common.Invalid:
+				ctx.String(http.StatusBadRequest, err.Error())
+			default:
+				ctx.String(http.StatusInternalServerError, "Failed to apply memo batch")
+			}
+			return
+		}
+
+		memoIDList := []int{}
+		for _, previousMemo := range previousMemoList {
+			memoIDList = append(memoIDList, previousMemo.ID)
+			switch memoBatch.Operation {
+			case api.MemoBatchDelete:
+				s.federateMemo(ctx, previousMemo, nil)
+			case api.MemoBatchArchive, api.MemoBatchRestore, api.MemoBatchVisibility:
+				memo, err := s.Store.FindMemo(ctx, &api.MemoFind{
+					ID: &previousMemo.ID,
+				})
+				if err != nil {
+					ctx.String(http.StatusInternalServerError, "Failed to find memo")
+					return
+				}
+				s.federateMemo(ctx, previousMemo, memo)
+			}
+		}
+		if err := s.createMemoBatchActivity(ctx, memoBatch, memoIDList); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to create activity")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(&api.MemoBatchResult{
+			Operation:  memoBatch.Operation,
+			MemoIDList: memoIDList,
+		}))
+	})
+}
+
+func (s *Service) createMemoBatchActivity(ctx *gin.Context, memoBatch *api.MemoBatch, memoIDList []int) error {
+	payload := api.ActivityMemoBatchPayload{
+		Operation:  string(memoBatch.Operation),
+		MemoIDList: memoIDList,
+	}
+	if memoBatch.Operation == api.MemoBatchVisibility {
+		payload.Visibility = memoBatch.Visibility.String()
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal activity payload")
+	}
+	activity, err := s.Store.CreateActivity(ctx, &api.ActivityCreate{
+		CreatorID: memoBatch.CreatorID,
+		Type:      api.ActivityMemoBatch,
+		Level:     api.ActivityInfo,
+		Payload:   string(payloadBytes),
+	})
+	if err != nil || activity == nil {
+		return errors.Wrap(err, "failed to create activity")
+	}
+	return err
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"uamemos/api"
+	"uamemos/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Service) registerMemoCommentRoutes(rg *gin.RouterGroup) {
+	rg.POST("/memo/:memoId/comment", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
+			return
+		}
+
+		memo, ok := s.findViewableMemo(ctx, &user.ID)
+		if !ok {
+			return
+		}
+
+		memoCreate := &api.MemoCreate{}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(memoCreate); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted post memo comment request")
+			return
+		}
+		if memoCreate.Content == "" {
+			ctx.String(http.StatusBadRequest, "Comment content shouldn't be empty")
+			return
+		}
+		if len(memoCreate.Content) > api.MaxContentLength {
+			ctx.String(http.StatusBadRequest, "Content size overflow, up to 1MB")
+			return
+		}
+
+		// Comments are visible to whoever can see the commented memo.
+		memoCreate.CreatorID = user.ID
+		memoCreate.CreatedTs = nil
+		memoCreate.ParentID = memo.ID
+		memoCreate.Visibility = memo.Visibility
+		memoCreate.GroupID = memo.GroupID
+		comment, err := s.Store.CreateMemo(ctx, memoCreate)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to create memo comment")
+			return
+		}
+		if err := s.createMemoCreateActivity(ctx, comment); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to create activity")
+			return
+		}
+		if err := s.createCommentNotification(ctx, memo, comment); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to create notification")
+			return
+		}
+		if err := s.createMentionNotificationList(ctx, comment, ""); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to create notification")
+			return
+		}
+
+		for _, resourceID := range memoCreate.ResourceIDList {
+			if _, err := s.Store.UpsertMemoResource(ctx, &api.MemoResourceUpsert{
+				MemoID:     comment.ID,
+				ResourceID: resourceID,
+			}); err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to upsert memo resource")
+				return
+			}
+		}
+
+		comment, err = s.Store.ComposeMemo(ctx, comment)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to compose memo")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(comment))
+	})
+
+	rg.GET("/memo/:memoId/comment", func(ctx *gin.Context) {
+		var viewerID *int
+		if _userID, ok := ctx.Get(getUserIDContextKey()); ok {
+			if userID, ok := _userID.(int); ok {
+				viewerID = &userID
+			}
+		}
+
+		memo, ok := s.findViewableMemo(ctx, viewerID)
+		if !ok {
+			return
+		}
+
+		normalStatus := api.Normal
+		memoFind := &api.MemoFind{
+			ParentID:  &memo.ID,
+			RowStatus: &normalStatus,
+		}
+		if limit, err := strconv.Atoi(ctx.Query("limit")); err == nil {
+			memoFind.Limit = &limit
+		}
+		if offset, err := strconv.Atoi(ctx.Query("offset")); err == nil {
+			memoFind.Offset = &offset
+		}
+
+		commentList, err := s.Store.FindMemoList(ctx, memoFind)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find memo comment list")
+			return
+		}
+		if err := s.composeMemoListEmbedList(ctx, commentList); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to compose memo embed list")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(commentList))
+	})
+
+	rg.DELETE("/memo/:memoId/comment/:commentId", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+		memoID, err := strconv.Atoi(ctx.Param("memoId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("memoId")))
+			return
+		}
+		commentID, err := strconv.Atoi(ctx.Param("commentId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("commentId")))
+			return
+		}
+
+		comment, err := s.Store.FindMemo(ctx, &api.MemoFind{
+			ID: &commentID,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			ctx.String(http.StatusInternalServerError, "Failed to find memo comment")
+			return
+		}
+		if comment == nil || comment.ParentID != memoID {
+			ctx.String(http.StatusNotFound, fmt.Sprintf("Comment ID not found: %d", commentID))
+			return
+		}
+
+		// The comment can be deleted by its creator and by the creator of the commented memo.
+		if comment.CreatorID != user.ID {
+			memo, err := s.Store.FindMemo(ctx, &api.MemoFind{
+				ID: &memoID,
+			})
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to find memo")
+				return
+			}
+			if memo.CreatorID != user.ID {
+				ctx.String(http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+		}
+
+		if err := s.Store.DeleteMemo(ctx, &api.MemoDelete{ID: commentID}); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to delete memo comment")
+			return
+		}
+		ctx.JSON(http.StatusOK, true)
+	})
+}
+
+// findViewableMemo finds the memo of the request path that the viewer can see, or writes the error response.
+func (s *Service) findViewableMemo(ctx *gin.Context, viewerID *int) (*api.Memo, bool) {
+	memoID, err := strconv.Atoi(ctx.Param("memoId"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("memoId")))
+		return nil, false
+	}
+
+	memo, err := s.Store.FindMemo(ctx, &api.MemoFind{
+		ID: &memoID,
+	})
+	if err != nil {
+		if common.ErrorCode(err) == common.NotFound {
+			ctx.String(http.StatusNotFound, fmt.Sprintf("Memo ID not found: %d", memoID))
+			return nil, false
+		}
+		ctx.String(http.StatusInternalServerError, fmt.Sprintf("Failed to find memo by ID: %v", memoID))
+		return nil, false
+	}
+	canView, err := s.canViewMemo(ctx, memo, viewerID)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "Failed to find user group member")
+		return nil, false
+	}
+	if !canView {
+		ctx.String(http.StatusForbidden, "Access forbidden for current session user")
+		return nil, false
+	}
+	return memo, true
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+
+	"uamemos/api"
+	"uamemos/common"
+	"uamemos/plugin/markdown"
+	"uamemos/store"
+)
+
+// composeMemoEmbedList resolves the embeds of the memo content for the viewer carried by the context.
+// Embedded memos are resolved one level deep, so that embeds never recurse.
+func (s *Service) composeMemoEmbedList(ctx context.Context, memo *api.Memo) error {
+	var viewerID *int
+	if userID, ok := store.ViewerIDFromContext(ctx); ok {
+		viewerID = &userID
+	}
+
+	memo.EmbedList = []*api.MemoEmbed{}
+	for _, memoID := range markdown.ExtractEmbeddedMemoIDs([]byte(memo.Content)) {
+		embeddedMemo, err := s.findEmbeddedMemo(ctx, memoID, viewerID)
+		if err != nil {
+			return err
+		}
+		memo.EmbedList = append(memo.EmbedList, &api.MemoEmbed{
+			MemoID: memoID,
+			Memo:   embeddedMemo,
+		})
+	}
+
+	memo.ResourceEmbedList = []*api.ResourceEmbed{}
+	for _, resourceID := range markdown.ExtractEmbeddedResourceIDs([]byte(memo.Content)) {
+		resourceID := resourceID
+		resourceEmbed := &api.ResourceEmbed{
+			ResourceID: resourceID,
+		}
+		resource, err := s.Store.FindResource(ctx, &api.ResourceFind{
+			ID: &resourceID,
+		})
+		if err != nil && common.ErrorCode(err) != common.NotFound {
+			return err
+		}
+		// A resource is shown with the memo of its creator, the resources of other users are never exposed by an embed.
+		if resource != nil && resource.CreatorID == memo.CreatorID {
+			resourceEmbed.Resource = resource
+		}
+		memo.ResourceEmbedList = append(memo.ResourceEmbedList, resourceEmbed)
+	}
+	return nil
+}
+
+// composeMemoListEmbedList resolves the embeds of every memo of the list.
+func (s *Service) composeMemoListEmbedList(ctx context.Context, memoList []*api.Memo) error {
+	for _, memo := range memoList {
+		if err := s.composeMemoEmbedList(ctx, memo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findEmbeddedMemo finds the embedded memo if the viewer can see it, nil otherwise.
+// An archived memo is only visible to its creator.
+func (s *Service) findEmbeddedMemo(ctx context.Context, memoID int, viewerID *int) (*api.Memo, error) {
+	memo, err := s.Store.FindMemo(ctx, &api.MemoFind{
+		ID: &memoID,
+	})
+	if err != nil {
+		if common.ErrorCode(err) == common.NotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if memo.RowStatus != api.Normal && (viewerID == nil || memo.CreatorID != *viewerID) {
+		return nil, nil
+	}
+	canView, err := s.canViewMemo(ctx, memo, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if !canView {
+		return nil, nil
+	}
+	return memo, nil
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"uamemos/api"
+	"uamemos/common"
+	"uamemos/common/log"
+	"uamemos/plugin/exif"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// The EXIF segment is at the head of a JPEG image, and is at most 64KB.
+	maxExifHeadSize = 128 << 10
+)
+
+func (s *Service) registerMemoLocationRoutes(rg *gin.RouterGroup) {
+	rg.GET("/memo/geojson", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+
+		rowStatus := api.Normal
+		hasLocation := true
+		memoFind := &api.MemoFind{
+			CreatorID:   &user.ID,
+			RowStatus:   &rowStatus,
+			HasLocation: &hasLocation,
+		}
+		if bbox := ctx.Query("bbox"); bbox != "" {
+			boundingBox, err := api.ParseBoundingBox(bbox)
+			if err != nil {
+				ctx.String(http.StatusBadRequest, err.Error())
+				return
+			}
+			memoFind.BoundingBox = boundingBox
+		}
+
+		memoList, err := s.Store.FindMemoList(ctx, memoFind)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to fetch memo list")
+			return
+		}
+		data, err := json.Marshal(api.NewMemoGeoJSON(memoList))
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to marshal GeoJSON")
+			return
+		}
+		ctx.Data(http.StatusOK, "application/geo+json", data)
+	})
+}
+
+// findResourceListLocation finds the location of the first geotagged resource of the user in the list, nil if there is none.
+func (s *Service) findResourceListLocation(ctx context.Context, resourceIDList []int, userID int) (*api.Location, error) {
+	for _, resourceID := range resourceIDList {
+		resourceID := resourceID
+		resource, err := s.Store.FindResource(ctx, &api.ResourceFind{
+			ID:        &resourceID,
+			CreatorID: &userID,
+		})
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				continue
+			}
+			return nil, err
+		}
+		if resource.Location != nil {
+			return resource.Location, nil
+		}
+	}
+	return nil, nil
+}
+
+// readImageLocation reads the EXIF GPS location from the head of a JPEG image, nil if the image has none.
+func readImageLocation(file io.ReaderAt, size int64) *api.Location {
+	head := make([]byte, common.Min(int(size), maxExifHeadSize))
+	n, err := file.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		log.Warn("failed to read image head", zap.Error(err))
+		return nil
+	}
+	location, err := exif.ExtractLocation(head[:n])
+	if err != nil {
+		if !errors.Is(err, exif.ErrNoLocation) {
+			log.Warn("failed to extract image location", zap.Error(err))
+		}
+		return nil
+	}
+	return &api.Location{
+		Latitude:  location.Latitude,
+		Longitude: location.Longitude,
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"uamemos/api"
+	"uamemos/common"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+func (s *Service) registerMemoReactionRoutes(rg *gin.RouterGroup) {
+	rg.POST("/memo/:memoId/reaction", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+		memo, ok := s.findViewableMemo(ctx, &user.ID)
+		if !ok {
+			return
+		}
+
+		memoReactionUpsert := &api.MemoReactionUpsert{}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(memoReactionUpsert); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted post memo reaction request")
+			return
+		}
+		memoReactionUpsert.MemoID = memo.ID
+		memoReactionUpsert.UserID = user.ID
+		if err := memoReactionUpsert.Validate(); err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		memoReaction, created, err := s.Store.UpsertMemoReaction(ctx, memoReactionUpsert)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to upsert memo reaction")
+			return
+		}
+		if created {
+			if err := s.createMemoReactionActivity(ctx, memoReaction); err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to create activity")
+				return
+			}
+			if err := s.createReactionNotification(ctx, memo, memoReaction); err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to create notification")
+				return
+			}
+		}
+		ctx.JSON(http.StatusOK, composeResponse(memoReaction))
+	})
+
+	rg.DELETE("/memo/:memoId/reaction/:reaction", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+		memo, ok := s.findViewableMemo(ctx, &user.ID)
+		if !ok {
+			return
+		}
+
+		if err := s.Store.DeleteMemoReaction(ctx, &api.MemoReactionDelete{
+			MemoID:   memo.ID,
+			UserID:   user.ID,
+			Reaction: ctx.Param("reaction"),
+		}); err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, "Memo reaction not found")
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to delete memo reaction")
+			return
+		}
+		ctx.JSON(http.StatusOK, true)
+	})
+}
+
+func (s *Service) createMemoReactionActivity(ctx *gin.Context, memoReaction *api.MemoReaction) error {
+	payload := api.ActivityMemoReactionPayload{
+		MemoID:   memoReaction.MemoID,
+		Reaction: memoReaction.Reaction,
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal activity payload")
+	}
+	activity, err := s.Store.CreateActivity(ctx, &api.ActivityCreate{
+		CreatorID: memoReaction.UserID,
+		Type:      api.ActivityMemoReaction,
+		Level:     api.ActivityInfo,
+		Payload:   string(payloadBytes),
+	})
+	if err != nil || activity == nil {
+		return errors.Wrap(err, "failed to create activity")
+	}
+	return err
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"uamemos/api"
+	"uamemos/common"
+	"uamemos/store"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// memoShareTokenLength is the length of the random share link token.
+	memoShareTokenLength = 32
+	// memoSharePasswordHeader carries the password of a protected share link.
+	memoSharePasswordHeader = "X-Share-Password"
+)
+
+func (s *Service) registerMemoShareRoutes(rg *gin.RouterGroup) {
+	rg.POST("/memo/:memoId/share", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+		memoID, err := strconv.Atoi(ctx.Param("memoId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("memoId")))
+			return
+		}
+
+		memo, err := s.Store.FindMemo(ctx, &api.MemoFind{
+			ID: &memoID,
+		})
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Memo ID not found: %d", memoID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to find memo")
+			return
+		}
+		if memo.CreatorID != user.ID {
+			ctx.String(http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		memoShareCreate := &api.MemoShareCreate{}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(memoShareCreate); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted post memo share request")
+			return
+		}
+		if err := memoShareCreate.Validate(time.Now().Unix()); err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		token, err := common.RandomString(memoShareTokenLength)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to generate share token")
+			return
+		}
+		if memoShareCreate.Password != "" {
+			passwordHash, err := bcrypt.GenerateFromPassword([]byte(memoShareCreate.Password), bcrypt.DefaultCost)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to generate password hash")
+				return
+			}
+			memoShareCreate.PasswordHash = string(passwordHash)
+		}
+		memoShareCreate.CreatorID = user.ID
+		memoShareCreate.MemoID = memoID
+		memoShareCreate.Token = token
+
+		memoShare, err := s.Store.CreateMemoShare(ctx, memoShareCreate)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to create memo share")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(memoShare))
+	})
+
+	rg.GET("/memo/share", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+
+		memoShareFind := &api.MemoShareFind{
+			CreatorID: &user.ID,
+		}
+		if memoID, err := strconv.Atoi(ctx.Query("memoId")); err == nil {
+			memoShareFind.MemoID = &memoID
+		}
+		memoShareList, err := s.Store.FindMemoShareList(ctx, memoShareFind)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find memo share list")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(memoShareList))
+	})
+
+	rg.DELETE("/memo/share/:shareId", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+		shareID, err := strconv.Atoi(ctx.Param("shareId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("shareId")))
+			return
+		}
+
+		memoShare, err := s.Store.FindMemoShare(ctx, &api.MemoShareFind{
+			ID: &shareID,
+		})
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Memo share ID not found: %d", shareID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to find memo share")
+			return
+		}
+		if memoShare.CreatorID != user.ID {
+			ctx.String(http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		if err := s.Store.DeleteMemoShare(ctx, &api.MemoShareDelete{ID: shareID}); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to delete memo share")
+			return
+		}
+		ctx.JSON(http.StatusOK, true)
+	})
+
+	// The share link is public, anyone knowing the token can view the memo.
+	rg.GET("/share/:token", func(ctx *gin.Context) {
+		token := ctx.Param("token")
+		memoShare, err := s.Store.FindMemoShare(ctx, &api.MemoShareFind{
+			Token: &token,
+		})
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, "Share link not found")
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to find memo share")
+			return
+		}
+		if memoShare.ExpiredTs != 0 && memoShare.ExpiredTs <= time.Now().Unix() {
+			ctx.String(http.StatusGone, "Share link has expired")
+			return
+		}
+		if memoShare.HasPassword {
+			password := ctx.GetHeader(memoSharePasswordHeader)
+			if password == "" {
+				ctx.String(http.StatusUnauthorized, "Share link is protected by a password")
+				return
+			}
+			if err := bcrypt.CompareHashAndPassword([]byte(memoShare.PasswordHash), []byte(password)); err != nil {
+				ctx.String(http.StatusUnauthorized, "Incorrect share link password")
+				return
+			}
+		}
+
+		memo, err := s.Store.FindMemo(ctx, &api.MemoFind{
+			ID: &memoShare.MemoID,
+		})
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, "Share link not found")
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to find memo")
+			return
+		}
+		if memo.RowStatus == api.Archived {
+			ctx.String(http.StatusNotFound, "Share link not found")
+			return
+		}
+
+		if _, err := s.Store.IncreaseMemoShareViewCount(ctx, memoShare.ID); err != nil {
+			if common.ErrorCode(err) == common.Invalid {
+				ctx.String(http.StatusGone, "Share link has reached its max view count")
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to count memo share view")
+			return
+		}
+
+		// The share link is viewed as an anonymous viewer, whoever opens it.
+		anonymousCtx := store.WithoutViewerID(ctx)
+		memo, err = s.Store.ComposeMemo(anonymousCtx, memo)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to compose memo")
+			return
+		}
+		if err := s.composeMemoEmbedList(anonymousCtx, memo); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to compose memo embed list")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(memo))
+	})
+}
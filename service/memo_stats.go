@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"uamemos/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Service) registerMemoStatsRoutes(rg *gin.RouterGroup) {
+	rg.GET("/memo/stats/summary", func(ctx *gin.Context) {
+		memoFind, ok := parseMemoStatsFind(ctx)
+		if !ok {
+			return
+		}
+		period, ok := parseMemoStatsPeriod(ctx)
+		if !ok {
+			return
+		}
+		location, ok := s.findMemoStatsLocation(ctx, *memoFind.CreatorID)
+		if !ok {
+			return
+		}
+
+		createdTsList, err := s.Store.FindMemoCreatedTsList(ctx, memoFind)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to fetch memo list")
+			return
+		}
+
+		memoStats := &api.MemoStats{
+			Timezone:   location.String(),
+			Period:     period,
+			MemoCount:  len(createdTsList),
+			BucketList: []*api.MemoStatsBucket{},
+		}
+		dayList := []int{}
+		for _, createdTs := range createdTsList {
+			createdTime := time.Unix(createdTs, 0).In(location)
+			date := formatMemoStatsDate(createdTime, period)
+			if length := len(memoStats.BucketList); length != 0 && memoStats.BucketList[length-1].Date == date {
+				memoStats.BucketList[length-1].Count++
+			} else {
+				memoStats.BucketList = append(memoStats.BucketList, &api.MemoStatsBucket{Date: date, Count: 1})
+			}
+			if day := dayNumber(createdTime); len(dayList) == 0 || dayList[len(dayList)-1] != day {
+				dayList = append(dayList, day)
+			}
+		}
+		memoStats.ActiveDayCount = len(dayList)
+		memoStats.CurrentStreak, memoStats.LongestStreak = countStreaks(dayList, dayNumber(time.Now().In(location)))
+		ctx.JSON(http.StatusOK, composeResponse(memoStats))
+	})
+
+	rg.GET("/memo/stats/tag", func(ctx *gin.Context) {
+		memoFind, ok := parseMemoStatsFind(ctx)
+		if !ok {
+			return
+		}
+		period, ok := parseMemoStatsPeriod(ctx)
+		if !ok {
+			return
+		}
+		location, ok := s.findMemoStatsLocation(ctx, *memoFind.CreatorID)
+		if !ok {
+			return
+		}
+
+		usageList, err := s.Store.FindMemoTagUsageList(ctx, memoFind)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to fetch memo tag list")
+			return
+		}
+
+		bucketList := []*api.MemoTagStatsBucket{}
+		tagCountMap := map[string]*api.MemoTagCount{}
+		for _, usage := range usageList {
+			date := formatMemoStatsDate(time.Unix(usage.MemoCreatedTs, 0).In(location), period)
+			if len(bucketList) == 0 || bucketList[len(bucketList)-1].Date != date {
+				bucketList = append(bucketList, &api.MemoTagStatsBucket{Date: date, TagCountList: []*api.MemoTagCount{}})
+				tagCountMap = map[string]*api.MemoTagCount{}
+			}
+			bucket := bucketList[len(bucketList)-1]
+			if tagCount, ok := tagCountMap[usage.Tag]; ok {
+				tagCount.Count++
+				continue
+			}
+			tagCount := &api.MemoTagCount{Tag: usage.Tag, Count: 1}
+			tagCountMap[usage.Tag] = tagCount
+			bucket.TagCountList = append(bucket.TagCountList, tagCount)
+		}
+		for _, bucket := range bucketList {
+			sort.Slice(bucket.TagCountList, func(i, j int) bool {
+				if bucket.TagCountList[i].Count != bucket.TagCountList[j].Count {
+					return bucket.TagCountList[i].Count > bucket.TagCountList[j].Count
+				}
+				return bucket.TagCountList[i].Tag < bucket.TagCountList[j].Tag
+			})
+		}
+		ctx.JSON(http.StatusOK, composeResponse(bucketList))
+	})
+
+	rg.GET("/memo/stats/on-this-day", func(ctx *gin.Context) {
+		memoFind, ok := parseMemoStatsFind(ctx)
+		if !ok {
+			return
+		}
+		location, ok := s.findMemoStatsLocation(ctx, *memoFind.CreatorID)
+		if !ok {
+			return
+		}
+		date := time.Now().In(location)
+		if dateStr := ctx.Query("date"); dateStr != "" {
+			var err error
+			date, err = time.ParseInLocation("2006-01-02", dateStr, location)
+			if err != nil {
+				ctx.String(http.StatusBadRequest, fmt.Sprintf("Invalid date: %s", dateStr))
+				return
+			}
+		}
+
+		createdTsList, err := s.Store.FindMemoCreatedTsList(ctx, memoFind)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to fetch memo list")
+			return
+		}
+		if len(createdTsList) == 0 {
+			ctx.JSON(http.StatusOK, composeResponse([]*api.Memo{}))
+			return
+		}
+
+		// The same day of every previous year since the first memo, Feb 29 only matches leap years.
+		filter := &api.MemoFilter{Operator: api.MemoFilterOr}
+		firstYear := time.Unix(createdTsList[0], 0).In(location).Year()
+		for year := date.Year() - 1; year >= firstYear; year-- {
+			day := time.Date(year, date.Month(), date.Day(), 0, 0, 0, 0, location)
+			if day.Day() != date.Day() {
+				continue
+			}
+			fromTs, toTs := day.Unix(), day.AddDate(0, 0, 1).Unix()
+			filter.Children = append(filter.Children, &api.MemoFilter{
+				Key:    api.MemoFilterCreated,
+				FromTs: &fromTs,
+				ToTs:   &toTs,
+			})
+		}
+		if len(filter.Children) == 0 {
+			ctx.JSON(http.StatusOK, composeResponse([]*api.Memo{}))
+			return
+		}
+
+		pinnedFirst := false
+		memoFind.Filter = filter
+		memoFind.Sort = api.MemoSortCreated
+		memoFind.PinnedFirst = &pinnedFirst
+		memoList, err := s.Store.FindMemoList(ctx, memoFind)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to fetch memo list")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(memoList))
+	})
+}
+
+// parseMemoStatsFind finds the normal memos of the creatorId query that the current user can see, or writes the error response.
+func parseMemoStatsFind(ctx *gin.Context) (*api.MemoFind, bool) {
+	normalStatus := api.Normal
+	memoFind := &api.MemoFind{
+		RowStatus: &normalStatus,
+	}
+	if creatorID, err := strconv.Atoi(ctx.Query("creatorId")); err == nil {
+		memoFind.CreatorID = &creatorID
+	}
+	if memoFind.CreatorID == nil {
+		ctx.String(http.StatusBadRequest, "Missing user id to find memo")
+		return nil, false
+	}
+
+	_currentUserID, ok := ctx.Get(getUserIDContextKey())
+	currentUserID, _ok := _currentUserID.(int)
+	if !ok || !_ok {
+		memoFind.VisibilityList = []api.Visibility{api.Public}
+	} else {
+		memoFind.ViewerID = &currentUserID
+		if *memoFind.CreatorID != currentUserID {
+			memoFind.VisibilityList = []api.Visibility{api.Public, api.Protected, api.Group}
+		} else {
+			memoFind.VisibilityList = []api.Visibility{api.Public, api.Protected, api.Private, api.Group}
+		}
+	}
+	return memoFind, true
+}
+
+func parseMemoStatsPeriod(ctx *gin.Context) (api.MemoStatsPeriod, bool) {
+	switch period := api.MemoStatsPeriod(ctx.DefaultQuery("period", string(api.MemoStatsDay))); period {
+	case api.MemoStatsDay, api.MemoStatsWeek, api.MemoStatsMonth:
+		return period, true
+	default:
+		ctx.String(http.StatusBadRequest, fmt.Sprintf("Invalid period: %s", period))
+		return "", false
+	}
+}
+
+// findMemoStatsLocation finds the time zone of the timezone query, of the creator's setting, or UTC.
+func (s *Service) findMemoStatsLocation(ctx *gin.Context, creatorID int) (*time.Location, bool) {
+	if timezone := ctx.Query("timezone"); timezone != "" {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("Invalid timezone: %s", timezone))
+			return nil, false
+		}
+		return location, true
+	}
+
+	location, err := s.findUserLocation(ctx, creatorID)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "Failed to find user setting")
+		return nil, false
+	}
+	return location, true
+}
+
+// findUserLocation finds the time zone of the user's setting, UTC when it isn't set.
+func (s *Service) findUserLocation(ctx context.Context, userID int) (*time.Location, error) {
+	userSetting, err := s.Store.FindUserSetting(ctx, &api.UserSettingFind{
+		UserID: userID,
+		Key:    api.UserSettingTimezoneKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if userSetting != nil {
+		timezone := ""
+		if err := json.Unmarshal([]byte(userSetting.Value), &timezone); err == nil {
+			if location, err := time.LoadLocation(timezone); err == nil {
+				return location, nil
+			}
+		}
+	}
+	return time.UTC, nil
+}
+
+// formatMemoStatsDate formats the first day of the period containing the time.
+func formatMemoStatsDate(t time.Time, period api.MemoStatsPeriod) string {
+	switch period {
+	case api.MemoStatsWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+	case api.MemoStatsMonth:
+		return t.Format("2006-01")
+	default:
+		return t.Format("2006-01-02")
+	}
+}
+
+// dayNumber numbers the calendar day of the time, consecutive days have consecutive numbers.
+func dayNumber(t time.Time) int {
+	return int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+// countStreaks counts the current and the longest runs of consecutive days in the ascending day list.
+func countStreaks(dayList []int, today int) (current int, longest int) {
+	run := 0
+	for i, day := range dayList {
+		if i > 0 && dayList[i-1] == day-1 {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+	if length := len(dayList); length != 0 && (dayList[length-1] == today || dayList[length-1] == today-1) {
+		current = run
+	}
+	return current, longest
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"uamemos/api"
+	"uamemos/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Service) registerMemoTemplateRoutes(rg *gin.RouterGroup) {
+	rg.POST("/memo/template", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
+			return
+		}
+
+		memoTemplateCreate := &api.MemoTemplateCreate{}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(memoTemplateCreate); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted post memo template request")
+			return
+		}
+		if memoTemplateCreate.Scope == "" {
+			memoTemplateCreate.Scope = api.MemoTemplateUser
+		}
+		if err := memoTemplateCreate.Validate(); err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("Invalid memo template: %v", err))
+			return
+		}
+		if memoTemplateCreate.Scope == api.MemoTemplateSystem {
+			canManageSetting, err := s.hasPermission(ctx, user, api.PermissionSettingManage)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to find role permissions")
+				return
+			}
+			if !canManageSetting {
+				ctx.String(http.StatusForbidden, "Access forbidden for current session user")
+				return
+			}
+		}
+
+		memoTemplateCreate.CreatorID = user.ID
+		memoTemplate, err := s.Store.CreateMemoTemplate(ctx, memoTemplateCreate)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to create memo template")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(memoTemplate))
+	})
+
+	rg.GET("/memo/template", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+
+		memoTemplateList, err := s.Store.FindMemoTemplateList(ctx, &api.MemoTemplateFind{
+			UserID: &user.ID,
+		})
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find memo template list")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(memoTemplateList))
+	})
+
+	rg.GET("/memo/template/:templateId", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+		memoTemplateID, err := strconv.Atoi(ctx.Param("templateId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("templateId")))
+			return
+		}
+
+		memoTemplate, err := s.Store.FindMemoTemplate(ctx, &api.MemoTemplateFind{
+			ID:     &memoTemplateID,
+			UserID: &user.ID,
+		})
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Memo template not found: %d", memoTemplateID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to find memo template")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(memoTemplate))
+	})
+
+	rg.PATCH("/memo/template/:templateId", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
+			return
+		}
+		memoTemplate, ok := s.findEditableMemoTemplate(ctx, user)
+		if !ok {
+			return
+		}
+
+		currentTs := time.Now().Unix()
+		memoTemplatePatch := &api.MemoTemplatePatch{
+			UpdatedTs: &currentTs,
+		}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(memoTemplatePatch); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted patch memo template request")
+			return
+		}
+		if err := memoTemplatePatch.Validate(); err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("Invalid memo template: %v", err))
+			return
+		}
+
+		memoTemplatePatch.ID = memoTemplate.ID
+		memoTemplate, err := s.Store.PatchMemoTemplate(ctx, memoTemplatePatch)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to patch memo template")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(memoTemplate))
+	})
+
+	rg.DELETE("/memo/template/:templateId", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
+			return
+		}
+		memoTemplate, ok := s.findEditableMemoTemplate(ctx, user)
+		if !ok {
+			return
+		}
+
+		if err := s.Store.DeleteMemoTemplate(ctx, &api.MemoTemplateDelete{
+			ID: memoTemplate.ID,
+		}); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to delete memo template")
+			return
+		}
+		ctx.JSON(http.StatusOK, true)
+	})
+}
+
+// findEditableMemoTemplate finds the template of the request path that the user can edit, or writes the error response.
+// The user templates are edited by their creator, the system ones by the users managing the settings.
+func (s *Service) findEditableMemoTemplate(ctx *gin.Context, user *api.User) (*api.MemoTemplate, bool) {
+	memoTemplateID, err := strconv.Atoi(ctx.Param("templateId"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("templateId")))
+		return nil, false
+	}
+
+	memoTemplate, err := s.Store.FindMemoTemplate(ctx, &api.MemoTemplateFind{
+		ID:     &memoTemplateID,
+		UserID: &user.ID,
+	})
+	if err != nil {
+		if common.ErrorCode(err) == common.NotFound {
+			ctx.String(http.StatusNotFound, fmt.Sprintf("Memo template not found: %d", memoTemplateID))
+			return nil, false
+		}
+		ctx.String(http.StatusInternalServerError, "Failed to find memo template")
+		return nil, false
+	}
+
+	if memoTemplate.Scope == api.MemoTemplateSystem {
+		canManageSetting, err := s.hasPermission(ctx, user, api.PermissionSettingManage)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find role permissions")
+			return nil, false
+		}
+		if !canManageSetting {
+			ctx.String(http.StatusForbidden, "Access forbidden for current session user")
+			return nil, false
+		}
+	} else if memoTemplate.CreatorID != user.ID {
+		ctx.String(http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+	return memoTemplate, true
+}
+
+// expandMemoTemplate expands a template the user can use at the current time in the user's time zone.
+func (s *Service) expandMemoTemplate(ctx context.Context, user *api.User, memoTemplateID int) (string, error) {
+	memoTemplate, err := s.Store.FindMemoTemplate(ctx, &api.MemoTemplateFind{
+		ID:     &memoTemplateID,
+		UserID: &user.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+	location, err := s.findUserLocation(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	return memoTemplate.Expand(time.Now().In(location), user), nil
+}
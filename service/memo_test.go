@@ -0,0 +1,44 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoListPinnedByCreator(t *testing.T) {
+	ts := newTestServer(t)
+	host := ts.newClient()
+	ts.requireStatus(host, http.StatusOK, http.MethodPost, "/api/auth/signup", map[string]string{"name": "host", "pass": "secret"})
+	ts.requireStatus(host, http.StatusOK, http.MethodPost, "/api/user", map[string]string{"username": "bob", "password": "secret", "role": "USER"})
+	bob := ts.newClient()
+	ts.requireStatus(bob, http.StatusOK, http.MethodPost, "/api/auth/signin", map[string]string{"name": "bob", "pass": "secret"})
+	ts.createPinnedOldMemo(host)
+
+	body := ts.requireStatus(host, http.StatusOK, http.MethodGet, "/api/memo?creatorId=1&pinned=false", nil)
+	page := struct {
+		Data struct {
+			MemoList []struct {
+				ID int `json:"id"`
+			} `json:"memoList"`
+		} `json:"data"`
+	}{}
+	require.NoError(t, json.Unmarshal([]byte(body), &page))
+	require.Len(t, page.Data.MemoList, 1)
+	// The pins of the viewer don't reorder another user's memos.
+	ts.requireStatus(bob, http.StatusOK, http.MethodPost, fmt.Sprintf("/api/memo/%d/organizer", page.Data.MemoList[0].ID), map[string]any{"pinned": true})
+
+	for _, client := range []*http.Client{host, bob, ts.newClient()} {
+		body := ts.requireStatus(client, http.StatusOK, http.MethodGet, "/api/memo?creatorId=1", nil)
+		newIndex, oldIndex := strings.Index(body, "new memo"), strings.Index(body, "old memo")
+		require.True(t, oldIndex >= 0 && newIndex > oldIndex, body)
+
+		body = ts.requireStatus(client, http.StatusOK, http.MethodGet, "/api/memo?creatorId=1&pinned=true", nil)
+		require.Contains(t, body, "old memo")
+		require.NotContains(t, body, "new memo")
+	}
+}
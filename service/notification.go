@@ -0,0 +1,178 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"uamemos/api"
+	"uamemos/common"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slices"
+)
+
+func (s *Service) registerNotificationRoutes(rg *gin.RouterGroup) {
+	rg.GET("/notification", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+
+		notificationFind := &api.NotificationFind{
+			ReceiverID: &user.ID,
+		}
+		if readStr := ctx.Query("read"); readStr != "" {
+			read := readStr == "true"
+			notificationFind.Read = &read
+		}
+		if limit, err := strconv.Atoi(ctx.Query("limit")); err == nil {
+			notificationFind.Limit = &limit
+		}
+		if offset, err := strconv.Atoi(ctx.Query("offset")); err == nil {
+			notificationFind.Offset = &offset
+		}
+
+		notificationList, err := s.Store.FindNotificationList(ctx, notificationFind)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find notification list")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(notificationList))
+	})
+
+	rg.PATCH("/notification/:notificationId", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+		notificationID, err := strconv.Atoi(ctx.Param("notificationId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("notificationId")))
+			return
+		}
+
+		notificationPatch := &api.NotificationPatch{}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(notificationPatch); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted patch notification request")
+			return
+		}
+		if notificationPatch.Read == nil {
+			ctx.String(http.StatusBadRequest, "Nothing to patch in the notification")
+			return
+		}
+		notificationPatch.ID = notificationID
+		notificationPatch.ReceiverID = user.ID
+
+		notification, err := s.Store.PatchNotification(ctx, notificationPatch)
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Notification ID not found: %d", notificationID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to patch notification")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(notification))
+	})
+
+	rg.POST("/notification/read-all", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+
+		if err := s.Store.ReadAllNotification(ctx, user.ID); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to mark notifications as read")
+			return
+		}
+		ctx.JSON(http.StatusOK, true)
+	})
+}
+
+// createCommentNotification notifies the creator of the commented memo.
+func (s *Service) createCommentNotification(ctx *gin.Context, memo *api.Memo, comment *api.Memo) error {
+	if memo.CreatorID == comment.CreatorID {
+		return nil
+	}
+	_, err := s.Store.CreateNotification(ctx, &api.NotificationCreate{
+		ReceiverID: memo.CreatorID,
+		SenderID:   comment.CreatorID,
+		Type:       api.NotificationComment,
+		MemoID:     comment.ID,
+	})
+	return err
+}
+
+// createReactionNotification notifies the creator of the reacted memo.
+func (s *Service) createReactionNotification(ctx *gin.Context, memo *api.Memo, memoReaction *api.MemoReaction) error {
+	if memo.CreatorID == memoReaction.UserID {
+		return nil
+	}
+	_, err := s.Store.CreateNotification(ctx, &api.NotificationCreate{
+		ReceiverID: memo.CreatorID,
+		SenderID:   memoReaction.UserID,
+		Type:       api.NotificationReaction,
+		MemoID:     memo.ID,
+		Reaction:   memoReaction.Reaction,
+	})
+	return err
+}
+
+// createMentionNotificationList notifies the users newly mentioned in the memo, who can see it.
+// The previous content is empty for a created memo.
+func (s *Service) createMentionNotificationList(ctx *gin.Context, memo *api.Memo, previousContent string) error {
+	previousUsernameList := findMentionedUsernameListFromMemoContent(previousContent)
+	for _, username := range findMentionedUsernameListFromMemoContent(memo.Content) {
+		if slices.Contains(previousUsernameList, username) {
+			continue
+		}
+		username := username
+		user, err := s.Store.FindUser(ctx, &api.UserFind{
+			Name: &username,
+		})
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				continue
+			}
+			return err
+		}
+		if user.ID == memo.CreatorID {
+			continue
+		}
+		canView, err := s.canViewMemo(ctx, memo, &user.ID)
+		if err != nil {
+			return err
+		}
+		if !canView {
+			continue
+		}
+		if _, err := s.Store.CreateNotification(ctx, &api.NotificationCreate{
+			ReceiverID: user.ID,
+			SenderID:   memo.CreatorID,
+			Type:       api.NotificationMention,
+			MemoID:     memo.ID,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var mentionRegexp = regexp.MustCompile(`(?:^|\s)@([^\s@]+)`)
+
+func findMentionedUsernameListFromMemoContent(memoContent string) []string {
+	usernameList := []string{}
+	matches := mentionRegexp.FindAllStringSubmatch(memoContent, -1)
+	for _, v := range matches {
+		// Ignore the punctuation following a mention, like "@alice, hi".
+		username := strings.TrimRight(v[1], ".,:;!?)]}'\"")
+		if username != "" && !slices.Contains(usernameList, username) {
+			usernameList = append(usernameList, username)
+		}
+	}
+	return usernameList
+}
@@ -34,12 +34,11 @@ var fileKeyPattern = regexp.MustCompile(`\{[a-z]{1,9}\}`)
 func (s *Service) registerResourceRoutes(rg *gin.RouterGroup) {
 	rg.POST("/resource", func(ctx *gin.Context) {
 
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
+		user, ok := s.authorize(ctx, api.PermissionResourceUpload)
+		if !ok {
 			return
 		}
+		userID := user.ID
 
 		resourceCreate := &api.ResourceCreate{}
 		if err := json.NewDecoder(ctx.Request.Body).Decode(resourceCreate); err != nil {
@@ -68,12 +67,11 @@ func (s *Service) registerResourceRoutes(rg *gin.RouterGroup) {
 
 	rg.POST("/resource/blob", func(ctx *gin.Context) {
 
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
+		user, ok := s.authorize(ctx, api.PermissionResourceUpload)
+		if !ok {
 			return
 		}
+		userID := user.ID
 
 		if err := ctx.Request.ParseMultipartForm(maxFileSize); err != nil {
 			ctx.String(http.StatusBadRequest, "Upload file overload max size")
@@ -99,6 +97,11 @@ func (s *Service) registerResourceRoutes(rg *gin.RouterGroup) {
 		}
 		defer sourceFile.Close()
 
+		var location *api.Location
+		if filetype == "image/jpeg" {
+			location = readImageLocation(sourceFile, size)
+		}
+
 		var resourceCreate *api.ResourceCreate
 		systemSettingStorageServiceID, err := s.Store.FindSystemSetting(ctx, &api.SystemSettingFind{Name: api.SystemSettingStorageServiceIDName})
 		if err != nil && common.ErrorCode(err) != common.NotFound {
@@ -215,6 +218,7 @@ func (s *Service) registerResourceRoutes(rg *gin.RouterGroup) {
 			}
 		}
 
+		resourceCreate.Location = location
 		publicID := common.GenUUID()
 		resourceCreate.PublicID = publicID
 		resource, err := s.Store.CreateResource(ctx, resourceCreate)
@@ -231,12 +235,11 @@ func (s *Service) registerResourceRoutes(rg *gin.RouterGroup) {
 
 	rg.GET("/resource", func(ctx *gin.Context) {
 
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
+		user, ok := s.authorize(ctx)
+		if !ok {
 			return
 		}
+		userID := user.ID
 		resourceFind := &api.ResourceFind{
 			CreatorID: &userID,
 		}
@@ -257,12 +260,11 @@ func (s *Service) registerResourceRoutes(rg *gin.RouterGroup) {
 
 	rg.PATCH("/resource/:resourceId", func(ctx *gin.Context) {
 
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
+		user, ok := s.authorize(ctx, api.PermissionResourceUpload)
+		if !ok {
 			return
 		}
+		userID := user.ID
 
 		resourceID, err := strconv.Atoi(ctx.Param("resourceId"))
 		if err != nil {
@@ -308,12 +310,11 @@ func (s *Service) registerResourceRoutes(rg *gin.RouterGroup) {
 
 	rg.DELETE("/resource/:resourceId", func(ctx *gin.Context) {
 
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
+		user, ok := s.authorize(ctx)
+		if !ok {
 			return
 		}
+		userID := user.ID
 
 		resourceID, err := strconv.Atoi(ctx.Param("resourceId"))
 		if err != nil {
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"uamemos/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceLocationCreatorOnly(t *testing.T) {
+	ts := newTestServer(t)
+	host := ts.newClient()
+	ts.requireStatus(host, http.StatusOK, http.MethodPost, "/api/auth/signup", map[string]string{"name": "host", "pass": "secret"})
+	ts.requireStatus(host, http.StatusOK, http.MethodPost, "/api/user", map[string]string{"username": "bob", "password": "secret", "role": "USER"})
+	bob := ts.newClient()
+	ts.requireStatus(bob, http.StatusOK, http.MethodPost, "/api/auth/signin", map[string]string{"name": "bob", "pass": "secret"})
+
+	location := &api.Location{Latitude: 48.8584, Longitude: 2.2945}
+	resource, err := ts.service.Store.CreateResource(context.Background(), &api.ResourceCreate{
+		CreatorID:    1,
+		Filename:     "photo.jpg",
+		ExternalLink: "https://example.com/photo.jpg",
+		Type:         "image/jpeg",
+		Location:     location,
+	})
+	require.NoError(t, err)
+	require.Nil(t, resource.CreatorLocation)
+	body := ts.requireStatus(host, http.StatusOK, http.MethodPost, "/api/memo", map[string]any{
+		"content":        "photo",
+		"visibility":     "PUBLIC",
+		"resourceIdList": []int{resource.ID},
+	})
+	memo := struct {
+		Data struct {
+			ID int `json:"id"`
+			// The memo keeps the location of its resources.
+			Location     *api.Location                `json:"location"`
+			ResourceList []map[string]json.RawMessage `json:"resourceList"`
+		} `json:"data"`
+	}{}
+	require.NoError(t, json.Unmarshal([]byte(body), &memo))
+	require.Equal(t, location, memo.Data.Location)
+	require.Contains(t, memo.Data.ResourceList[0], "location")
+
+	body = ts.requireStatus(host, http.StatusOK, http.MethodGet, "/api/resource", nil)
+	require.Contains(t, body, `"location":{"latitude":48.8584,"longitude":2.2945,`)
+
+	// Other users and anonymous viewers don't see the location of the resource.
+	for _, client := range []*http.Client{bob, ts.newClient()} {
+		body := ts.requireStatus(client, http.StatusOK, http.MethodGet, fmt.Sprintf("/api/memo/%d", memo.Data.ID), nil)
+		memo.Data.ResourceList = nil
+		require.NoError(t, json.Unmarshal([]byte(body), &memo))
+		require.Len(t, memo.Data.ResourceList, 1)
+		require.NotContains(t, memo.Data.ResourceList[0], "location")
+	}
+}
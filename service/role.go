@@ -0,0 +1,153 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"uamemos/api"
+	"uamemos/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Service) registerRoleRoutes(rg *gin.RouterGroup) {
+	rg.GET("/role", func(ctx *gin.Context) {
+		if _, ok := s.authorize(ctx); !ok {
+			return
+		}
+
+		customRoleList, err := s.Store.FindCustomRoleList(ctx, &api.CustomRoleFind{})
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find role list")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(append(api.BuiltinRoleList(), customRoleList...)))
+	})
+
+	rg.GET("/role/permission", func(ctx *gin.Context) {
+		if _, ok := s.authorize(ctx); !ok {
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(api.PermissionList))
+	})
+
+	rg.POST("/role", func(ctx *gin.Context) {
+		currentUser, ok := s.authorize(ctx, api.PermissionSettingManage)
+		if !ok {
+			return
+		}
+
+		customRoleCreate := &api.CustomRoleCreate{}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(customRoleCreate); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted post role request")
+			return
+		}
+		if err := customRoleCreate.Validate(); err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		if !s.authorizePermissionList(ctx, currentUser, customRoleCreate.PermissionList) {
+			return
+		}
+		if _, err := s.Store.FindCustomRole(ctx, &api.CustomRoleFind{Name: &customRoleCreate.Name}); err == nil {
+			ctx.String(http.StatusConflict, fmt.Sprintf("Role already exists: %s", customRoleCreate.Name))
+			return
+		} else if common.ErrorCode(err) != common.NotFound {
+			ctx.String(http.StatusInternalServerError, "Failed to find role")
+			return
+		}
+
+		customRole, err := s.Store.CreateCustomRole(ctx, customRoleCreate)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to create role")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(customRole))
+	})
+
+	rg.PATCH("/role/:name", func(ctx *gin.Context) {
+		currentUser, ok := s.authorize(ctx, api.PermissionSettingManage)
+		if !ok {
+			return
+		}
+
+		name := api.Role(ctx.Param("name"))
+		if name.IsBuiltin() {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("Built-in role can't be changed: %s", name))
+			return
+		}
+		customRole, err := s.Store.FindCustomRole(ctx, &api.CustomRoleFind{Name: &name})
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Role not found: %s", name))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to find role")
+			return
+		}
+		if name == currentUser.Role {
+			ctx.String(http.StatusForbidden, "Could not change the role of the current user")
+			return
+		}
+
+		currentTs := time.Now().Unix()
+		customRolePatch := &api.CustomRolePatch{
+			UpdatedTs: &currentTs,
+		}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(customRolePatch); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted patch role request")
+			return
+		}
+		customRolePatch.Name = name
+		if err := customRolePatch.Validate(); err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		// Both the current and the new permissions must be granted to the current user.
+		if !s.authorizePermissionList(ctx, currentUser, append(customRole.PermissionList, customRolePatch.PermissionList...)) {
+			return
+		}
+
+		customRole, err = s.Store.PatchCustomRole(ctx, customRolePatch)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to patch role")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(customRole))
+	})
+
+	rg.DELETE("/role/:name", func(ctx *gin.Context) {
+		if _, ok := s.authorize(ctx, api.PermissionSettingManage); !ok {
+			return
+		}
+
+		name := api.Role(ctx.Param("name"))
+		if name.IsBuiltin() {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("Built-in role can't be deleted: %s", name))
+			return
+		}
+		userList, err := s.Store.FindUserList(ctx, &api.UserFind{
+			Role: &name,
+		})
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find user list")
+			return
+		}
+		if len(userList) > 0 {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("Role %s is still assigned to %d user(s)", name, len(userList)))
+			return
+		}
+
+		if err := s.Store.DeleteCustomRole(ctx, &api.CustomRoleDelete{Name: name}); err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Role not found: %s", name))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to delete role")
+			return
+		}
+		ctx.JSON(http.StatusOK, true)
+	})
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"testing"
+
+	"uamemos/service/profile"
+	"uamemos/service/version"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+// testServer is a service on a temporary database, its clients keep their own session cookies.
+type testServer struct {
+	t       *testing.T
+	service *Service
+	server  *httptest.Server
+}
+
+func newTestServer(t *testing.T) *testServer {
+	dataDir := t.TempDir()
+	s, err := NewService(context.Background(), &profile.Profile{
+		Mode:    "dev",
+		Data:    dataDir,
+		DSN:     fmt.Sprintf("%s/uamemos_dev.db", dataDir),
+		Version: version.GetCurrentVersion("dev"),
+	})
+	require.NoError(t, err)
+	server := httptest.NewServer(s.g)
+	t.Cleanup(func() {
+		server.Close()
+		s.db.Close()
+	})
+	return &testServer{t: t, service: s, server: server}
+}
+
+func (ts *testServer) newClient() *http.Client {
+	jar, err := cookiejar.New(nil)
+	require.NoError(ts.t, err)
+	return &http.Client{Jar: jar}
+}
+
+// request sends the JSON body and returns the status code and the response body.
+func (ts *testServer) request(client *http.Client, method, path string, body any) (int, string) {
+	data, err := json.Marshal(body)
+	require.NoError(ts.t, err)
+	req, err := http.NewRequest(method, ts.server.URL+path, bytes.NewReader(data))
+	require.NoError(ts.t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	require.NoError(ts.t, err)
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	require.NoError(ts.t, err)
+	return resp.StatusCode, string(respBody)
+}
+
+func (ts *testServer) requireStatus(client *http.Client, status int, method, path string, body any) string {
+	code, respBody := ts.request(client, method, path, body)
+	require.Equal(ts.t, status, code, "%s %s: %s", method, path, respBody)
+	return respBody
+}
+
+func TestRolePermissionEscalation(t *testing.T) {
+	ts := newTestServer(t)
+	host := ts.newClient()
+	ts.requireStatus(host, http.StatusOK, http.MethodPost, "/api/auth/signup", map[string]string{"name": "host", "pass": "secret"})
+	ts.requireStatus(host, http.StatusOK, http.MethodPost, "/api/role", map[string]any{"name": "MGR", "permissionList": []string{"setting.manage"}})
+	ts.requireStatus(host, http.StatusOK, http.MethodPost, "/api/role", map[string]any{"name": "EDITOR", "permissionList": []string{"memo.write"}})
+	ts.requireStatus(host, http.StatusOK, http.MethodPost, "/api/role", map[string]any{"name": "OPS", "permissionList": []string{"setting.manage", "user.manage"}})
+	ts.requireStatus(host, http.StatusOK, http.MethodPost, "/api/user", map[string]string{"username": "bob", "password": "secret", "role": "MGR"})
+
+	bob := ts.newClient()
+	ts.requireStatus(bob, http.StatusOK, http.MethodPost, "/api/auth/signin", map[string]string{"name": "bob", "pass": "secret"})
+
+	// The current user's own role can't be changed, even within its permissions.
+	ts.requireStatus(bob, http.StatusForbidden, http.MethodPatch, "/api/role/MGR", map[string]any{
+		"permissionList": []string{"setting.manage", "user.manage", "storage.manage", "memo.write"},
+	})
+	ts.requireStatus(bob, http.StatusForbidden, http.MethodPatch, "/api/role/MGR", map[string]any{"description": "mine"})
+	// Permissions beyond the current user's can't be granted.
+	ts.requireStatus(bob, http.StatusForbidden, http.MethodPost, "/api/role", map[string]any{"name": "ESCALATED", "permissionList": []string{"user.manage"}})
+	ts.requireStatus(bob, http.StatusForbidden, http.MethodPatch, "/api/role/EDITOR", map[string]any{"permissionList": []string{"memo.write", "user.manage"}})
+	// A role with permissions beyond the current user's can't be changed.
+	ts.requireStatus(bob, http.StatusForbidden, http.MethodPatch, "/api/role/OPS", map[string]any{"permissionList": []string{"setting.manage"}})
+
+	// Granting the current user's permissions is allowed.
+	ts.requireStatus(bob, http.StatusOK, http.MethodPost, "/api/role", map[string]any{"name": "SETTINGS", "permissionList": []string{"setting.manage"}})
+	ts.requireStatus(bob, http.StatusOK, http.MethodPatch, "/api/role/SETTINGS", map[string]any{"description": "settings only", "permissionList": []string{"setting.manage"}})
+
+	body := ts.requireStatus(host, http.StatusOK, http.MethodGet, "/api/role", nil)
+	require.Contains(t, body, `"name":"MGR","description":"","builtin":false,"permissionList":["setting.manage"]`)
+	require.NotContains(t, body, "ESCALATED")
+
+	// The host is granted every permission.
+	ts.requireStatus(host, http.StatusOK, http.MethodPatch, "/api/role/MGR", map[string]any{"permissionList": []string{"setting.manage", "user.manage"}})
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"uamemos/api"
+	"uamemos/common"
+	"uamemos/plugin/markdown"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/feeds"
+)
+
+const (
+	// maxRSSItemCount is the number of latest memos in a feed.
+	maxRSSItemCount = 100
+	// maxRSSItemTitleLength is the max runes of an item title taken from the memo content.
+	maxRSSItemTitleLength = 64
+)
+
+// feedFormat is the format of a generated feed.
+type feedFormat string
+
+const (
+	feedFormatRSS  feedFormat = "rss"
+	feedFormatAtom feedFormat = "atom"
+)
+
+func (s *Service) registerRSSRoutes(g *gin.Engine) {
+	g.GET("/explore/rss.xml", func(ctx *gin.Context) {
+		s.serveMemoFeed(ctx, nil, feedFormatRSS)
+	})
+
+	g.GET("/explore/atom.xml", func(ctx *gin.Context) {
+		s.serveMemoFeed(ctx, nil, feedFormatAtom)
+	})
+
+	g.GET("/u/:id/rss.xml", func(ctx *gin.Context) {
+		if user, ok := s.findFeedUser(ctx); ok {
+			s.serveMemoFeed(ctx, user, feedFormatRSS)
+		}
+	})
+
+	g.GET("/u/:id/atom.xml", func(ctx *gin.Context) {
+		if user, ok := s.findFeedUser(ctx); ok {
+			s.serveMemoFeed(ctx, user, feedFormatAtom)
+		}
+	})
+}
+
+func (s *Service) findFeedUser(ctx *gin.Context) (*api.User, bool) {
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("id")))
+		return nil, false
+	}
+	user, err := s.Store.FindUser(ctx, &api.UserFind{
+		ID: &userID,
+	})
+	if err != nil {
+		if common.ErrorCode(err) == common.NotFound {
+			ctx.String(http.StatusNotFound, fmt.Sprintf("User ID not found: %d", userID))
+			return nil, false
+		}
+		ctx.String(http.StatusInternalServerError, "Failed to find user")
+		return nil, false
+	}
+	return user, true
+}
+
+// serveMemoFeed writes the feed of the latest public memos, of the user if not nil.
+func (s *Service) serveMemoFeed(ctx *gin.Context, user *api.User, format feedFormat) {
+	customizedProfile, err := s.getSystemCustomizedProfile(ctx)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "Failed to find customized profile")
+		return
+	}
+
+	// A feed lists the latest memos, pinning doesn't apply to it.
+	normalStatus := api.Normal
+	limit := maxRSSItemCount
+	pinnedFirst := false
+	memoFind := &api.MemoFind{
+		RowStatus:      &normalStatus,
+		VisibilityList: []api.Visibility{api.Public},
+		Sort:           api.MemoSortCreated,
+		PinnedFirst:    &pinnedFirst,
+		Limit:          &limit,
+	}
+	if user != nil {
+		memoFind.CreatorID = &user.ID
+	}
+	memoList, err := s.Store.FindMemoList(ctx, memoFind)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "Failed to find memo list")
+		return
+	}
+
+	baseURL := getBaseURL(ctx, customizedProfile)
+	feed, err := generateMemoFeed(baseURL, customizedProfile, user, memoList)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "Failed to generate feed")
+		return
+	}
+
+	var body string
+	contentType := "application/rss+xml; charset=utf-8"
+	if format == feedFormatAtom {
+		body, err = feed.ToAtom()
+		contentType = "application/atom+xml; charset=utf-8"
+	} else {
+		body, err = feed.ToRss()
+	}
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "Failed to generate feed")
+		return
+	}
+
+	hash := sha256.Sum256([]byte(body))
+	etag := `"` + hex.EncodeToString(hash[:16]) + `"`
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", "public, max-age=300")
+	if !feed.Updated.IsZero() {
+		ctx.Header("Last-Modified", feed.Updated.UTC().Format(http.TimeFormat))
+	}
+	if isFeedNotModified(ctx, etag, feed.Updated) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	ctx.Data(http.StatusOK, contentType, []byte(body))
+}
+
+// isFeedNotModified checks the conditional request headers, If-None-Match takes precedence over If-Modified-Since.
+func isFeedNotModified(ctx *gin.Context, etag string, updated time.Time) bool {
+	if ifNoneMatch := ctx.GetHeader("If-None-Match"); ifNoneMatch != "" {
+		for _, v := range strings.Split(ifNoneMatch, ",") {
+			v = strings.TrimSpace(v)
+			if v == "*" || strings.TrimPrefix(v, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if ifModifiedSince := ctx.GetHeader("If-Modified-Since"); ifModifiedSince != "" && !updated.IsZero() {
+		if t, err := http.ParseTime(ifModifiedSince); err == nil {
+			return !updated.Truncate(time.Second).After(t)
+		}
+	}
+	return false
+}
+
+// getBaseURL prefers the external URL of the customized profile, and falls back to the request host.
+func getBaseURL(ctx *gin.Context, customizedProfile *api.CustomizedProfile) string {
+	if customizedProfile.ExternalURL != "" {
+		return strings.TrimSuffix(customizedProfile.ExternalURL, "/")
+	}
+	scheme := "http"
+	if ctx.Request.TLS != nil || ctx.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, ctx.Request.Host)
+}
+
+func generateMemoFeed(baseURL string, customizedProfile *api.CustomizedProfile, user *api.User, memoList []*api.Memo) (*feeds.Feed, error) {
+	feed := &feeds.Feed{
+		Title:       customizedProfile.Name,
+		Link:        &feeds.Link{Href: baseURL + "/explore"},
+		Description: customizedProfile.Description,
+	}
+	if user != nil {
+		name := user.Nickname
+		if name == "" {
+			name = user.Name
+		}
+		feed.Title = fmt.Sprintf("%s - %s", name, customizedProfile.Name)
+		feed.Link = &feeds.Link{Href: fmt.Sprintf("%s/u/%d", baseURL, user.ID)}
+	}
+
+	feed.Items = []*feeds.Item{}
+	for _, memo := range memoList {
+		description, err := renderMemoContentHTML(memo.Content)
+		if err != nil {
+			return nil, err
+		}
+		link := fmt.Sprintf("%s/m/%d", baseURL, memo.ID)
+		item := &feeds.Item{
+			Id:          link,
+			Title:       getMemoFeedItemTitle(memo),
+			Link:        &feeds.Link{Href: link},
+			Author:      &feeds.Author{Name: memo.CreatorName},
+			Description: description,
+			Content:     description,
+			Created:     time.Unix(memo.CreatedTs, 0),
+			Updated:     time.Unix(memo.UpdatedTs, 0),
+		}
+		// Feeds have one enclosure per item, the first resource is enclosed and the others are linked in the content.
+		for i, resource := range memo.ResourceList {
+			resourceURL := getResourceURL(baseURL, resource)
+			if i == 0 {
+				item.Enclosure = &feeds.Enclosure{
+					Url:    resourceURL,
+					Length: strconv.FormatInt(resource.Size, 10),
+					Type:   resource.Type,
+				}
+				continue
+			}
+			item.Content += fmt.Sprintf(`<p><a href="%s">%s</a></p>`, resourceURL, html.EscapeString(resource.Filename))
+		}
+		if item.Updated.After(feed.Updated) {
+			feed.Updated = item.Updated
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	// The feed dates only depend on the memos, so that the same memos always generate the same feed.
+	feed.Created = feed.Updated
+	return feed, nil
+}
+
+// getMemoFeedItemTitle takes the first line of the memo content as the title.
+func getMemoFeedItemTitle(memo *api.Memo) string {
+	title := strings.TrimSpace(strings.SplitN(strings.TrimSpace(memo.Content), "\n", 2)[0])
+	title = strings.TrimLeft(title, "# ")
+	if title == "" {
+		return fmt.Sprintf("Memo #%d", memo.ID)
+	}
+	if utf8.RuneCountInString(title) > maxRSSItemTitleLength {
+		title = string([]rune(title)[:maxRSSItemTitleLength]) + "..."
+	}
+	return title
+}
+
+func getResourceURL(baseURL string, resource *api.Resource) string {
+	if resource.ExternalLink != "" {
+		return resource.ExternalLink
+	}
+	return fmt.Sprintf("%s/o/r/%d/%s/%s", baseURL, resource.ID, url.PathEscape(resource.PublicID), url.PathEscape(resource.Filename))
+}
+
+// renderMemoContentHTML renders the Markdown content, raw HTML in the content is omitted.
+func renderMemoContentHTML(content string) (string, error) {
+	return markdown.RenderHTML([]byte(content))
+}
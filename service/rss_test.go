@@ -0,0 +1,46 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// createPinnedOldMemo creates an old and a new public memo of the client's user, and pins the old one.
+func (ts *testServer) createPinnedOldMemo(client *http.Client) {
+	memoIDList := []int{}
+	for i, content := range []string{"old memo", "new memo"} {
+		body := ts.requireStatus(client, http.StatusOK, http.MethodPost, "/api/memo", map[string]any{
+			"content":    content,
+			"visibility": "PUBLIC",
+			"createdTs":  1690000000 + i*3600,
+		})
+		result := struct {
+			Data struct {
+				ID int `json:"id"`
+			} `json:"data"`
+		}{}
+		require.NoError(ts.t, json.Unmarshal([]byte(body), &result))
+		memoIDList = append(memoIDList, result.Data.ID)
+	}
+	ts.requireStatus(client, http.StatusOK, http.MethodPost, fmt.Sprintf("/api/memo/%d/organizer", memoIDList[0]), map[string]any{"pinned": true})
+}
+
+func TestMemoFeedIgnoresPinned(t *testing.T) {
+	ts := newTestServer(t)
+	host := ts.newClient()
+	ts.requireStatus(host, http.StatusOK, http.MethodPost, "/api/auth/signup", map[string]string{"name": "host", "pass": "secret"})
+	ts.createPinnedOldMemo(host)
+
+	for _, path := range []string{"/u/1/rss.xml", "/explore/atom.xml"} {
+		for _, client := range []*http.Client{host, ts.newClient()} {
+			body := ts.requireStatus(client, http.StatusOK, http.MethodGet, path, nil)
+			newIndex, oldIndex := strings.Index(body, "new memo"), strings.Index(body, "old memo")
+			require.True(t, newIndex >= 0 && oldIndex > newIndex, "%s: %s", path, body)
+		}
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 	"uamemos/api"
+	"uamemos/plugin/activitypub"
 	"uamemos/service/profile"
 	"uamemos/store"
 	"uamemos/store/db"
@@ -29,6 +31,14 @@ type Service struct {
 	ID      string
 	Profile *profile.Profile
 	Store   *store.Store
+
+	// idpAuthRequests holds the pending OpenID Connect sign-ins keyed by state.
+	idpAuthRequestMutex sync.Mutex
+	idpAuthRequests     map[string]*identityProviderAuthRequest
+
+	// apClient and apDeliveryQueue federate public memos through ActivityPub.
+	apClient        *activitypub.Client
+	apDeliveryQueue *activitypub.DeliveryQueue
 }
 
 func timeoutMiddleware() gin.HandlerFunc {
@@ -46,6 +56,8 @@ func timeoutMiddleware() gin.HandlerFunc {
 func NewService(ctx context.Context, profile *profile.Profile) (*Service, error) {
 	gin.SetMode(gin.ReleaseMode)
 	g := gin.Default()
+	// Handlers pass the gin context to the store, which reads the viewer from the request context.
+	g.ContextWithFallback = true
 
 	db := db.NewDB(profile)
 	if err := db.Open(ctx); err != nil {
@@ -56,7 +68,11 @@ func NewService(ctx context.Context, profile *profile.Profile) (*Service, error)
 		g:       g,
 		db:      db.DBInstance,
 		Profile: profile,
+
+		idpAuthRequests: map[string]*identityProviderAuthRequest{},
 	}
+	s.apClient = activitypub.NewClient(10 * time.Second)
+	s.apDeliveryQueue = activitypub.NewDeliveryQueue(s.apClient, 1024)
 
 	storeInstance := store.New(db.DBInstance, profile)
 	s.Store = storeInstance
@@ -90,6 +106,9 @@ func NewService(ctx context.Context, profile *profile.Profile) (*Service, error)
 		}
 	}
 
+	s.registerRSSRoutes(g)
+	s.registerActivityPubRoutes(g)
+
 	apiGroup := g.Group("/api")
 	apiGroup.Use(func(ctx *gin.Context) {
 		JWTMiddleware(s, ctx, secret)
@@ -97,11 +116,25 @@ func NewService(ctx context.Context, profile *profile.Profile) (*Service, error)
 	s.registerSystemRoutes(apiGroup)
 	s.registerAuthRoutes(apiGroup, secret)
 	s.registerUserRoutes(apiGroup)
+	s.registerUserFollowRoutes(apiGroup)
 	s.registerMemoRoutes(apiGroup)
+	s.registerMemoStatsRoutes(apiGroup)
+	s.registerMemoTemplateRoutes(apiGroup)
+	s.registerMemoBatchRoutes(apiGroup)
+	s.registerMemoLocationRoutes(apiGroup)
+	s.registerCollectionRoutes(apiGroup)
+	s.registerMemoShareRoutes(apiGroup)
+	s.registerMemoCommentRoutes(apiGroup)
+	s.registerMemoReactionRoutes(apiGroup)
+	s.registerNotificationRoutes(apiGroup)
 	s.registerTagRoutes(apiGroup)
+	s.registerTaskRoutes(apiGroup)
 	s.registerShortcutRoutes(apiGroup)
 	s.registerResourceRoutes(apiGroup)
 	s.registerStorageRoutes(apiGroup)
+	s.registerIdentityProviderRoutes(apiGroup)
+	s.registerRoleRoutes(apiGroup)
+	s.registerUserGroupRoutes(apiGroup)
 
 	return s, nil
 }
@@ -110,6 +143,8 @@ func (s *Service) Start(ctx context.Context) error {
 	if err := s.createServerStartActivity(ctx); err != nil {
 		return errors.Wrap(err, "failed to create activity")
 	}
+	go s.apDeliveryQueue.Run(ctx)
+	go s.pruneIdentityProviderAuthRequests(ctx)
 	server := &http.Server{
 		Addr:    fmt.Sprint(":", s.Profile.Port),
 		Handler: s.g,
@@ -16,17 +16,20 @@ import (
 
 func (s *Service) registerShortcutRoutes(rg *gin.RouterGroup) {
 	rg.POST("/shortcut", func(ctx *gin.Context) {
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
+		user, ok := s.authorize(ctx)
+		if !ok {
 			return
 		}
+		userID := user.ID
 		shortcutCreate := &api.ShortcutCreate{}
 		if err := json.NewDecoder(ctx.Request.Body).Decode(shortcutCreate); err != nil {
 			ctx.String(http.StatusBadRequest, "Malformatted post shortcut request")
 			return
 		}
+		if _, err := api.ParseShortcutPayload(shortcutCreate.Payload); err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("Invalid shortcut payload: %v", err))
+			return
+		}
 
 		shortcutCreate.CreatorID = userID
 		shortcut, err := s.Store.CreateShortcut(ctx, shortcutCreate)
@@ -42,12 +45,11 @@ func (s *Service) registerShortcutRoutes(rg *gin.RouterGroup) {
 	})
 
 	rg.PATCH("/shortcut/:shortcutId", func(ctx *gin.Context) {
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
+		user, ok := s.authorize(ctx)
+		if !ok {
 			return
 		}
+		userID := user.ID
 		shortcutID, err := strconv.Atoi(ctx.Param("shortcutId"))
 		if err != nil {
 			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("shortcutId")))
@@ -75,6 +77,12 @@ func (s *Service) registerShortcutRoutes(rg *gin.RouterGroup) {
 			ctx.String(http.StatusBadRequest, "Malformatted patch shortcut request")
 			return
 		}
+		if shortcutPatch.Payload != nil {
+			if _, err := api.ParseShortcutPayload(*shortcutPatch.Payload); err != nil {
+				ctx.String(http.StatusBadRequest, fmt.Sprintf("Invalid shortcut payload: %v", err))
+				return
+			}
+		}
 
 		shortcutPatch.ID = shortcutID
 		shortcut, err = s.Store.PatchShortcut(ctx, shortcutPatch)
@@ -122,13 +130,66 @@ func (s *Service) registerShortcutRoutes(rg *gin.RouterGroup) {
 		ctx.JSON(http.StatusOK, composeResponse(shortcut))
 	})
 
+	rg.GET("/shortcut/:shortcutId/memos", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+		shortcutID, err := strconv.Atoi(ctx.Param("shortcutId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("shortcutId")))
+			return
+		}
+
+		shortcut, err := s.Store.FindShortcut(ctx, &api.ShortcutFind{
+			ID: &shortcutID,
+		})
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Shortcut ID not found: %d", shortcutID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to find shortcut")
+			return
+		}
+		if shortcut.CreatorID != user.ID {
+			ctx.String(http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		// A shortcut filters the memos of its creator, the same way as the memo list of the creator.
+		normalStatus := api.Normal
+		memoFind := &api.MemoFind{
+			CreatorID: &user.ID,
+			ViewerID:  &user.ID,
+			RowStatus: &normalStatus,
+		}
+		memoFilter, err := api.ParseShortcutPayload(shortcut.Payload)
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("Invalid shortcut payload: %v", err))
+			return
+		}
+		memoFind.Filter = memoFilter
+		limit, err := parseMemoPageQuery(ctx, memoFind)
+		if err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		memoPage, err := s.findMemoPage(ctx, memoFind, limit)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to fetch shortcut memo list")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(memoPage))
+	})
+
 	rg.DELETE("/shortcut/:shortcutId", func(ctx *gin.Context) {
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
+		user, ok := s.authorize(ctx)
+		if !ok {
 			return
 		}
+		userID := user.ID
 		shortcutID, err := strconv.Atoi(ctx.Param("shortcutId"))
 		if err != nil {
 			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("shortcutId")))
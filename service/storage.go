@@ -14,22 +14,7 @@ import (
 
 func (s *Service) registerStorageRoutes(rg *gin.RouterGroup) {
 	rg.POST("/storage", func(ctx *gin.Context) {
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
-			return
-		}
-
-		user, err := s.Store.FindUser(ctx, &api.UserFind{
-			ID: &userID,
-		})
-		if err != nil {
-			ctx.String(http.StatusInternalServerError, "Failed to find user")
-			return
-		}
-		if user == nil || user.Role != api.Host {
-			ctx.String(http.StatusUnauthorized, "Unauthorized")
+		if _, ok := s.authorize(ctx, api.PermissionStorageManage); !ok {
 			return
 		}
 
@@ -48,22 +33,7 @@ func (s *Service) registerStorageRoutes(rg *gin.RouterGroup) {
 	})
 
 	rg.PATCH("/storage/:storageId", func(ctx *gin.Context) {
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
-			return
-		}
-
-		user, err := s.Store.FindUser(ctx, &api.UserFind{
-			ID: &userID,
-		})
-		if err != nil {
-			ctx.String(http.StatusInternalServerError, "Failed to find user")
-			return
-		}
-		if user == nil || user.Role != api.Host {
-			ctx.String(http.StatusUnauthorized, "Unauthorized")
+		if _, ok := s.authorize(ctx, api.PermissionStorageManage); !ok {
 			return
 		}
 
@@ -90,23 +60,8 @@ func (s *Service) registerStorageRoutes(rg *gin.RouterGroup) {
 	})
 
 	rg.GET("/storage", func(ctx *gin.Context) {
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
-			return
-		}
-
-		user, err := s.Store.FindUser(ctx, &api.UserFind{
-			ID: &userID,
-		})
-		if err != nil {
-			ctx.String(http.StatusInternalServerError, "Failed to find user")
-			return
-		}
-		// We should only show storage list to host user.
-		if user == nil || user.Role != api.Host {
-			ctx.String(http.StatusUnauthorized, "Unauthorized")
+		// We should only show storage list to users who manage storage.
+		if _, ok := s.authorize(ctx, api.PermissionStorageManage); !ok {
 			return
 		}
 
@@ -119,22 +74,7 @@ func (s *Service) registerStorageRoutes(rg *gin.RouterGroup) {
 	})
 
 	rg.DELETE("/storage/:storageId", func(ctx *gin.Context) {
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
-			return
-		}
-
-		user, err := s.Store.FindUser(ctx, &api.UserFind{
-			ID: &userID,
-		})
-		if err != nil {
-			ctx.String(http.StatusInternalServerError, "Failed to find user")
-			return
-		}
-		if user == nil || user.Role != api.Host {
-			ctx.String(http.StatusUnauthorized, "Unauthorized")
+		if _, ok := s.authorize(ctx, api.PermissionStorageManage); !ok {
 			return
 		}
 
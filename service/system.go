@@ -98,11 +98,16 @@ func (s *Service) registerSystemRoutes(rg *gin.RouterGroup) {
 			user, err := s.Store.FindUser(ctx, &api.UserFind{
 				ID: &userID,
 			})
-			if err != nil {
+			if err != nil && common.ErrorCode(err) != common.NotFound {
 				ctx.String(http.StatusInternalServerError, "Failed to find user")
 				return
 			}
-			if user != nil && user.Role == api.Host {
+			canManageSetting, err := s.hasPermission(ctx, user, api.PermissionSettingManage)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to find role permissions")
+				return
+			}
+			if canManageSetting {
 				fi, err := os.Stat(s.Profile.DSN)
 				if err != nil {
 					ctx.String(http.StatusInternalServerError, "Failed to read database fileinfo")
@@ -114,22 +119,7 @@ func (s *Service) registerSystemRoutes(rg *gin.RouterGroup) {
 		ctx.JSON(http.StatusOK, composeResponse(systemStatus))
 	})
 	rg.POST("/system/setting", func(ctx *gin.Context) {
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
-			return
-		}
-
-		user, err := s.Store.FindUser(ctx, &api.UserFind{
-			ID: &userID,
-		})
-		if err != nil {
-			ctx.String(http.StatusInternalServerError, "Failed to find user")
-			return
-		}
-		if user == nil || user.Role != api.Host {
-			ctx.String(http.StatusUnauthorized, "Unauthorized")
+		if _, ok := s.authorize(ctx, api.PermissionSettingManage); !ok {
 			return
 		}
 
@@ -152,22 +142,7 @@ func (s *Service) registerSystemRoutes(rg *gin.RouterGroup) {
 	})
 
 	rg.GET("/system/setting", func(ctx *gin.Context) {
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
-			return
-		}
-
-		user, err := s.Store.FindUser(ctx, &api.UserFind{
-			ID: &userID,
-		})
-		if err != nil {
-			ctx.String(http.StatusInternalServerError, "Failed to find user")
-			return
-		}
-		if user == nil || user.Role != api.Host {
-			ctx.String(http.StatusUnauthorized, "Unauthorized")
+		if _, ok := s.authorize(ctx, api.PermissionSettingManage); !ok {
 			return
 		}
 
@@ -180,22 +155,7 @@ func (s *Service) registerSystemRoutes(rg *gin.RouterGroup) {
 	})
 
 	rg.POST("/system/vacuum", func(ctx *gin.Context) {
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
-			return
-		}
-
-		user, err := s.Store.FindUser(ctx, &api.UserFind{
-			ID: &userID,
-		})
-		if err != nil {
-			ctx.String(http.StatusInternalServerError, "Failed to find user")
-			return
-		}
-		if user == nil || user.Role != api.Host {
-			ctx.String(http.StatusUnauthorized, "Unauthorized")
+		if _, ok := s.authorize(ctx, api.PermissionSettingManage); !ok {
 			return
 		}
 
@@ -245,3 +205,27 @@ func (s *Service) getSystemSecretSessionName(ctx context.Context) (string, error
 	}
 	return secretSessionNameValue.Value, nil
 }
+
+// getSystemCustomizedProfile returns the customized profile, with the defaults when it's not set.
+func (s *Service) getSystemCustomizedProfile(ctx context.Context) (*api.CustomizedProfile, error) {
+	customizedProfile := &api.CustomizedProfile{
+		Name:        "uamemos",
+		LogoURL:     "",
+		Description: "",
+		Locale:      "zh",
+		Appearance:  "system",
+		ExternalURL: "",
+	}
+	systemSetting, err := s.Store.FindSystemSetting(ctx, &api.SystemSettingFind{
+		Name: api.SystemSettingCustomizedProfileName,
+	})
+	if err != nil && common.ErrorCode(err) != common.NotFound {
+		return nil, err
+	}
+	if systemSetting != nil {
+		if err := json.Unmarshal([]byte(systemSetting.Value), customizedProfile); err != nil {
+			return nil, err
+		}
+	}
+	return customizedProfile, nil
+}
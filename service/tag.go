@@ -1,28 +1,30 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"regexp"
 	"sort"
+	"strings"
 
 	"uamemos/api"
 	"uamemos/common"
+	"uamemos/common/log"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 	"golang.org/x/exp/slices"
 )
 
 func (s *Service) registerTagRoutes(rg *gin.RouterGroup) {
 	rg.POST("/tag", func(ctx *gin.Context) {
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
 			return
 		}
+		userID := user.ID
 
 		tagUpsert := &api.TagUpsert{}
 		if err := json.NewDecoder(ctx.Request.Body).Decode(tagUpsert); err != nil {
@@ -65,11 +67,7 @@ func (s *Service) registerTagRoutes(rg *gin.RouterGroup) {
 			return
 		}
 
-		tagNameList := []string{}
-		for _, tag := range tagList {
-			tagNameList = append(tagNameList, tag.Name)
-		}
-		ctx.JSON(http.StatusOK, composeResponse(tagNameList))
+		ctx.JSON(http.StatusOK, composeResponse(tagList))
 	})
 
 	rg.GET("/tag/suggestion", func(ctx *gin.Context) {
@@ -109,7 +107,7 @@ func (s *Service) registerTagRoutes(rg *gin.RouterGroup) {
 
 		tagMapSet := make(map[string]bool)
 		for _, memo := range memoList {
-			for _, tag := range findTagListFromMemoContent(memo.Content) {
+			for _, tag := range api.FindTagList(memo.Content) {
 				if !slices.Contains(tagNameList, tag) {
 					tagMapSet[tag] = true
 				}
@@ -123,14 +121,123 @@ func (s *Service) registerTagRoutes(rg *gin.RouterGroup) {
 		ctx.JSON(http.StatusOK, composeResponse(tagList))
 	})
 
+	rg.GET("/tag/tree", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+
+		tagList, err := s.Store.FindTagList(ctx, &api.TagFind{
+			CreatorID: user.ID,
+		})
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find tag list")
+			return
+		}
+		tagNameList := []string{}
+		for _, tag := range tagList {
+			tagNameList = append(tagNameList, tag.Name)
+		}
+		ctx.JSON(http.StatusOK, composeResponse(buildTagTree(tagNameList)))
+	})
+
+	rg.POST("/tag/rename", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
+			return
+		}
+
+		tagRename := &api.TagRename{}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(tagRename); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted post tag rename request")
+			return
+		}
+		if err := tagRename.Validate(); err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		tagList, err := s.Store.FindTagList(ctx, &api.TagFind{
+			CreatorID: user.ID,
+		})
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find tag list")
+			return
+		}
+		for _, tag := range tagList {
+			if tag.Name == tagRename.NewName {
+				ctx.String(http.StatusConflict, fmt.Sprintf("Tag %s already exists, merge the tags instead", tag.Name))
+				return
+			}
+		}
+
+		previousMemoMap, err := s.findFederatedTagMemoMap(ctx, user.ID, []string{tagRename.Name})
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find memo list")
+			return
+		}
+		tagRename.CreatorID = user.ID
+		memoIDList, err := s.Store.RenameTag(ctx, tagRename)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to rename tag")
+			return
+		}
+		s.federateTagRewrite(ctx, previousMemoMap, memoIDList)
+		if err := s.createTagRenameActivity(ctx, tagRename, memoIDList); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to create activity")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(&api.TagRewriteResult{
+			Name:       tagRename.NewName,
+			MemoIDList: memoIDList,
+		}))
+	})
+
+	rg.POST("/tag/merge", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
+			return
+		}
+
+		tagMerge := &api.TagMerge{}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(tagMerge); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted post tag merge request")
+			return
+		}
+		if err := tagMerge.Validate(); err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		previousMemoMap, err := s.findFederatedTagMemoMap(ctx, user.ID, tagMerge.NameList)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find memo list")
+			return
+		}
+		tagMerge.CreatorID = user.ID
+		memoIDList, err := s.Store.MergeTag(ctx, tagMerge)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to merge tags")
+			return
+		}
+		s.federateTagRewrite(ctx, previousMemoMap, memoIDList)
+		if err := s.createTagMergeActivity(ctx, tagMerge, memoIDList); err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to create activity")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(&api.TagRewriteResult{
+			Name:       tagMerge.TargetName,
+			MemoIDList: memoIDList,
+		}))
+	})
+
 	rg.POST("/tag/delete", func(ctx *gin.Context) {
 
-		_userID, ok := ctx.Get(getUserIDContextKey())
-		userID, _ok := _userID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
 			return
 		}
+		userID := user.ID
 
 		tagDelete := &api.TagDelete{}
 		if err := json.NewDecoder(ctx.Request.Body).Decode(tagDelete); err != nil {
@@ -155,22 +262,33 @@ func (s *Service) registerTagRoutes(rg *gin.RouterGroup) {
 	})
 }
 
-var tagRegexp = regexp.MustCompile(`#([^\s#]+)`)
-
-func findTagListFromMemoContent(memoContent string) []string {
-	tagMapSet := make(map[string]bool)
-	matches := tagRegexp.FindAllStringSubmatch(memoContent, -1)
-	for _, v := range matches {
-		tagName := v[1]
-		tagMapSet[tagName] = true
-	}
-
-	tagList := []string{}
-	for tag := range tagMapSet {
-		tagList = append(tagList, tag)
+// buildTagTree nests the tags by their slash separated names, the parents of nested tags are added if missing.
+func buildTagTree(tagNameList []string) []*api.TagNode {
+	root := &api.TagNode{Children: []*api.TagNode{}}
+	nodeMap := map[string]*api.TagNode{"": root}
+	sort.Strings(tagNameList)
+	for _, tagName := range tagNameList {
+		parent, path := root, ""
+		for _, segment := range strings.Split(tagName, "/") {
+			if path != "" {
+				path += "/"
+			}
+			path += segment
+			node, ok := nodeMap[path]
+			if !ok {
+				node = &api.TagNode{
+					Name:     segment,
+					Path:     path,
+					Children: []*api.TagNode{},
+				}
+				nodeMap[path] = node
+				parent.Children = append(parent.Children, node)
+			}
+			parent = node
+		}
+		parent.Exist = true
 	}
-	sort.Strings(tagList)
-	return tagList
+	return root.Children
 }
 
 func (s *Service) createTagCreateActivity(ctx *gin.Context, tag *api.Tag) error {
@@ -193,3 +311,91 @@ func (s *Service) createTagCreateActivity(ctx *gin.Context, tag *api.Tag) error
 	}
 	return err
 }
+
+// findFederatedTagMemoMap finds the federated memos of the user with one of the tags or their nested tags, keyed by ID.
+// A tag rewrite doesn't change the visibility, so the other memos are not federated after the rewrite either.
+func (s *Service) findFederatedTagMemoMap(ctx context.Context, userID int, nameList []string) (map[int]*api.Memo, error) {
+	normalStatus := api.Normal
+	memoMap := map[int]*api.Memo{}
+	for _, name := range nameList {
+		name := name
+		memoList, err := s.Store.FindMemoList(ctx, &api.MemoFind{
+			CreatorID:      &userID,
+			RowStatus:      &normalStatus,
+			Tag:            &name,
+			VisibilityList: []api.Visibility{api.Public},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, memo := range memoList {
+			if isFederatedMemo(memo) {
+				memoMap[memo.ID] = memo
+			}
+		}
+	}
+	return memoMap, nil
+}
+
+// federateTagRewrite delivers the rewritten content of the federated memos to the remote followers.
+func (s *Service) federateTagRewrite(ctx *gin.Context, previousMemoMap map[int]*api.Memo, memoIDList []int) {
+	for _, memoID := range memoIDList {
+		previousMemo, ok := previousMemoMap[memoID]
+		if !ok {
+			continue
+		}
+		memoID := memoID
+		memo, err := s.Store.FindMemo(ctx, &api.MemoFind{
+			ID: &memoID,
+		})
+		if err != nil {
+			log.Warn("Failed to find rewritten memo", zap.Int("memoId", memoID), zap.Error(err))
+			continue
+		}
+		s.federateMemo(ctx, previousMemo, memo)
+	}
+}
+
+func (s *Service) createTagRenameActivity(ctx *gin.Context, tagRename *api.TagRename, memoIDList []int) error {
+	payload := api.ActivityTagRenamePayload{
+		TagName:    tagRename.Name,
+		NewTagName: tagRename.NewName,
+		MemoIDList: memoIDList,
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal activity payload")
+	}
+	activity, err := s.Store.CreateActivity(ctx, &api.ActivityCreate{
+		CreatorID: tagRename.CreatorID,
+		Type:      api.ActivityTagRename,
+		Level:     api.ActivityInfo,
+		Payload:   string(payloadBytes),
+	})
+	if err != nil || activity == nil {
+		return errors.Wrap(err, "failed to create activity")
+	}
+	return err
+}
+
+func (s *Service) createTagMergeActivity(ctx *gin.Context, tagMerge *api.TagMerge, memoIDList []int) error {
+	payload := api.ActivityTagMergePayload{
+		TagNameList:   tagMerge.NameList,
+		TargetTagName: tagMerge.TargetName,
+		MemoIDList:    memoIDList,
+	}
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal activity payload")
+	}
+	activity, err := s.Store.CreateActivity(ctx, &api.ActivityCreate{
+		CreatorID: tagMerge.CreatorID,
+		Type:      api.ActivityTagMerge,
+		Level:     api.ActivityInfo,
+		Payload:   string(payloadBytes),
+	})
+	if err != nil || activity == nil {
+		return errors.Wrap(err, "failed to create activity")
+	}
+	return err
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"uamemos/api"
+	"uamemos/common"
+	"uamemos/plugin/markdown"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Service) registerTaskRoutes(rg *gin.RouterGroup) {
+	rg.GET("/task", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+
+		taskFind := &api.TaskFind{
+			CreatorID: &user.ID,
+		}
+		switch status := ctx.Query("status"); status {
+		case "":
+		case "open", "done":
+			done := status == "done"
+			taskFind.Done = &done
+		default:
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("Invalid task status: %s", status))
+			return
+		}
+		if memoID, err := strconv.Atoi(ctx.Query("memoId")); err == nil {
+			taskFind.MemoID = &memoID
+		}
+		if limit, err := strconv.Atoi(ctx.Query("limit")); err == nil {
+			taskFind.Limit = &limit
+		}
+		if offset, err := strconv.Atoi(ctx.Query("offset")); err == nil {
+			taskFind.Offset = &offset
+		}
+
+		taskList, err := s.Store.FindTaskList(ctx, taskFind)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find task list")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(taskList))
+	})
+
+	rg.GET("/task/count", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+
+		// Users managing the others see the counts of every user.
+		canManageUser, err := s.hasPermission(ctx, user, api.PermissionUserManage)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find role permissions")
+			return
+		}
+		var creatorID *int
+		if !canManageUser {
+			creatorID = &user.ID
+		}
+
+		taskCountList, err := s.Store.FindTaskCountList(ctx, creatorID)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find task count list")
+			return
+		}
+		if !canManageUser && len(taskCountList) == 0 {
+			taskCountList = append(taskCountList, &api.TaskCount{CreatorID: user.ID})
+		}
+		ctx.JSON(http.StatusOK, composeResponse(taskCountList))
+	})
+
+	rg.PATCH("/memo/:memoId/task/:index", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx, api.PermissionMemoWrite)
+		if !ok {
+			return
+		}
+
+		memoID, err := strconv.Atoi(ctx.Param("memoId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("memoId")))
+			return
+		}
+		index, err := strconv.Atoi(ctx.Param("index"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("Index is not a number: %s", ctx.Param("index")))
+			return
+		}
+
+		taskPatch := &api.TaskPatch{
+			MemoID: memoID,
+			Index:  index,
+		}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(taskPatch); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted patch task request")
+			return
+		}
+
+		memo, err := s.Store.FindMemo(ctx, &api.MemoFind{
+			ID: &memoID,
+		})
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Memo ID not found: %d", memoID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to find memo")
+			return
+		}
+		if memo.CreatorID != user.ID {
+			ctx.String(http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if memo.RowStatus != api.Normal {
+			ctx.String(http.StatusBadRequest, "Tasks of an archived memo can't be changed")
+			return
+		}
+
+		content, err := markdown.ToggleTask([]byte(memo.Content), taskPatch.Index, taskPatch.Done)
+		if err != nil {
+			ctx.String(http.StatusNotFound, fmt.Sprintf("Task not found: %d", taskPatch.Index))
+			return
+		}
+		if string(content) != memo.Content {
+			currentTs := time.Now().Unix()
+			newContent := string(content)
+			previousMemo := memo
+			// The content is rewritten from the version read above, a concurrent edit is not overwritten.
+			memo, err = s.Store.PatchMemo(ctx, &api.MemoPatch{
+				ID:              memoID,
+				UpdatedTs:       &currentTs,
+				Content:         &newContent,
+				ExpectedVersion: &previousMemo.Version,
+			})
+			if err != nil {
+				if common.ErrorCode(err) == common.Conflict {
+					s.writeMemoConflict(ctx, memoID)
+					return
+				}
+				ctx.String(http.StatusInternalServerError, "Failed to patch memo")
+				return
+			}
+			memo, err = s.Store.ComposeMemo(ctx, memo)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to compose memo")
+				return
+			}
+			s.federateMemo(ctx, previousMemo, memo)
+		}
+
+		taskList, err := s.Store.FindTaskList(ctx, &api.TaskFind{
+			MemoID: &memoID,
+		})
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find task list")
+			return
+		}
+		for _, task := range taskList {
+			if task.Index == taskPatch.Index {
+				ctx.JSON(http.StatusOK, composeResponse(task))
+				return
+			}
+		}
+		ctx.String(http.StatusInternalServerError, "Failed to find task")
+	})
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -18,23 +19,8 @@ import (
 func (s *Service) registerUserRoutes(rg *gin.RouterGroup) {
 
 	rg.POST("/user", func(ctx *gin.Context) {
-		userID, ok := ctx.Get(getUserIDContextKey())
-
-		_userID, _ := userID.(int)
-
+		currentUser, ok := s.authorize(ctx, api.PermissionUserManage)
 		if !ok {
-			ctx.String(http.StatusUnauthorized, "Missing auth session")
-			return
-		}
-		currentUser, err := s.Store.FindUser(ctx, &api.UserFind{
-			ID: &_userID,
-		})
-		if err != nil {
-			ctx.String(http.StatusInternalServerError, "Failed to find user by id")
-			return
-		}
-		if currentUser.Role != api.Host {
-			ctx.String(http.StatusUnauthorized, "Only Host user can create member")
 			return
 		}
 		userCreate := &api.UserCreate{}
@@ -42,10 +28,20 @@ func (s *Service) registerUserRoutes(rg *gin.RouterGroup) {
 			ctx.String(http.StatusBadRequest, "Malformatted post user request")
 			return
 		}
+		if userCreate.Role == "" {
+			userCreate.Role = api.NormalUser
+		}
 		if userCreate.Role == api.Host {
 			ctx.String(http.StatusForbidden, "Could not create host user")
 			return
 		}
+		if err := s.validateRole(ctx, userCreate.Role); err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		if !s.authorizeRoleGrant(ctx, currentUser, userCreate.Role) {
+			return
+		}
 		userCreate.OpenID = common.GenUUID()
 		if err := userCreate.Validate(); err != nil {
 			ctx.String(http.StatusBadRequest, "Invalid user create format")
@@ -86,10 +82,8 @@ func (s *Service) registerUserRoutes(rg *gin.RouterGroup) {
 	})
 
 	rg.POST("/user/setting", func(ctx *gin.Context) {
-		userID, ok := ctx.Get(getUserIDContextKey())
-		_userID, _ := userID.(int)
+		user, ok := s.authorize(ctx)
 		if !ok {
-			ctx.String(http.StatusUnauthorized, "Missing auth session")
 			return
 		}
 
@@ -103,7 +97,19 @@ func (s *Service) registerUserRoutes(rg *gin.RouterGroup) {
 			return
 		}
 
-		userSettingUpsert.UserID = _userID
+		if userSettingUpsert.Key == api.UserSettingMemoVisibilityGroupKey {
+			groupID := 0
+			if err := json.Unmarshal([]byte(userSettingUpsert.Value), &groupID); err != nil {
+				ctx.String(http.StatusBadRequest, "Invalid user setting format")
+				return
+			}
+			if err := s.validateMemoGroup(ctx, groupID, user.ID); err != nil {
+				ctx.String(http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		userSettingUpsert.UserID = user.ID
 		userSetting, err := s.Store.UpsertUserSetting(ctx, userSettingUpsert)
 		if err != nil {
 			ctx.String(http.StatusInternalServerError, "Failed to upsert user setting")
@@ -113,31 +119,35 @@ func (s *Service) registerUserRoutes(rg *gin.RouterGroup) {
 	})
 
 	rg.GET("/user/me", func(ctx *gin.Context) {
-		userID, ok := ctx.Get(getUserIDContextKey())
+		user, ok := s.authorize(ctx)
 		if !ok {
-			ctx.String(http.StatusUnauthorized, "Missing auth session")
 			return
 		}
 
-		_userID, _ := userID.(int)
-
-		userFind := &api.UserFind{
-			ID: &_userID,
+		userSettingList, err := s.Store.FindUserSettingList(ctx, &api.UserSettingFind{
+			UserID: user.ID,
+		})
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find userSettingList")
+			return
 		}
-		user, err := s.Store.FindUser(ctx, userFind)
+		user.UserSettingList = userSettingList
+		permissionList, err := s.findRolePermissionList(ctx, user.Role)
 		if err != nil {
-			ctx.String(http.StatusInternalServerError, "Failed to find user")
+			ctx.String(http.StatusInternalServerError, "Failed to find role permissions")
 			return
 		}
-
-		userSettingList, err := s.Store.FindUserSettingList(ctx, &api.UserSettingFind{
-			UserID: _userID,
+		user.PermissionList = permissionList
+		unread := false
+		unreadNotificationCount, err := s.Store.CountNotification(ctx, &api.NotificationFind{
+			ReceiverID: &user.ID,
+			Read:       &unread,
 		})
 		if err != nil {
-			ctx.String(http.StatusInternalServerError, "Failed to find userSettingList")
+			ctx.String(http.StatusInternalServerError, "Failed to count unread notifications")
 			return
 		}
-		user.UserSettingList = userSettingList
+		user.UnreadNotificationCount = unreadNotificationCount
 		ctx.JSON(http.StatusOK, composeResponse(user))
 	})
 	rg.GET("/user/:id", func(ctx *gin.Context) {
@@ -159,6 +169,21 @@ func (s *Service) registerUserRoutes(rg *gin.RouterGroup) {
 			// data desensitize
 			user.OpenID = ""
 			user.Email = ""
+
+			user.FollowerCount, err = s.Store.CountUserFollow(ctx, &api.UserFollowFind{
+				FollowingID: &user.ID,
+			})
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to count followers")
+				return
+			}
+			user.FollowingCount, err = s.Store.CountUserFollow(ctx, &api.UserFollowFind{
+				FollowerID: &user.ID,
+			})
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to count following users")
+				return
+			}
 		}
 		ctx.JSON(http.StatusOK, composeResponse(user))
 	})
@@ -168,24 +193,11 @@ func (s *Service) registerUserRoutes(rg *gin.RouterGroup) {
 			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("id")))
 			return
 		}
-		currentUserID, ok := ctx.Get(getUserIDContextKey())
-		_currentUserID, _ := currentUserID.(int)
+		currentUser, ok := s.authorize(ctx)
 		if !ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
 			return
 		}
-		currentUser, err := s.Store.FindUser(ctx, &api.UserFind{
-			ID: &_currentUserID,
-		})
-		if err != nil {
-			ctx.String(http.StatusInternalServerError, "Failed to find user")
-			return
-		}
-		if currentUser == nil {
-			ctx.String(http.StatusBadRequest, fmt.Sprintf("Current session user not found with ID: %d", currentUserID))
-			return
-		} else if currentUser.Role != api.Host && _currentUserID != userID {
-			ctx.String(http.StatusForbidden, "Access forbidden for current session user")
+		if currentUser.ID != userID && !s.authorizeUserManagement(ctx, currentUser, userID) {
 			return
 		}
 
@@ -198,6 +210,25 @@ func (s *Service) registerUserRoutes(rg *gin.RouterGroup) {
 			return
 		}
 		userPatch.ID = userID
+		if userPatch.Role != nil {
+			canManageUser, err := s.hasPermission(ctx, currentUser, api.PermissionUserManage)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to find role permissions")
+				return
+			}
+			// Nobody can change the host role, nor grant it.
+			if !canManageUser || currentUser.ID == userID || *userPatch.Role == api.Host {
+				ctx.String(http.StatusForbidden, "Could not change the role of this user")
+				return
+			}
+			if err := s.validateRole(ctx, *userPatch.Role); err != nil {
+				ctx.String(http.StatusBadRequest, err.Error())
+				return
+			}
+			if !s.authorizeRoleGrant(ctx, currentUser, *userPatch.Role) {
+				return
+			}
+		}
 
 		if userPatch.Password != nil && *userPatch.Password != "" {
 			passwordHash, err := bcrypt.GenerateFromPassword([]byte(*userPatch.Password), bcrypt.DefaultCost)
@@ -236,24 +267,8 @@ func (s *Service) registerUserRoutes(rg *gin.RouterGroup) {
 		ctx.JSON(http.StatusOK, composeResponse(user))
 	})
 	rg.DELETE("/user/:id", func(ctx *gin.Context) {
-		currentUserID, ok := ctx.Get(getUserIDContextKey())
-		_currentUserID, _ok := currentUserID.(int)
-		if !ok || !_ok {
-			ctx.String(http.StatusUnauthorized, "Missing user in session")
-			return
-		}
-		currentUser, err := s.Store.FindUser(ctx, &api.UserFind{
-			ID: &_currentUserID,
-		})
-		if err != nil {
-			ctx.String(http.StatusInternalServerError, "Failed to find user")
-			return
-		}
-		if currentUser == nil {
-			ctx.String(http.StatusBadRequest, fmt.Sprintf("Current session user not found with ID: %d", currentUserID))
-			return
-		} else if currentUser.Role != api.Host {
-			ctx.String(http.StatusForbidden, "Access forbidden for current session user")
+		currentUser, ok := s.authorize(ctx, api.PermissionUserManage)
+		if !ok {
 			return
 		}
 
@@ -262,6 +277,9 @@ func (s *Service) registerUserRoutes(rg *gin.RouterGroup) {
 			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("id")))
 			return
 		}
+		if currentUser.ID != userID && !s.authorizeUserManagement(ctx, currentUser, userID) {
+			return
+		}
 
 		userDelete := &api.UserDelete{
 			ID: userID,
@@ -279,6 +297,62 @@ func (s *Service) registerUserRoutes(rg *gin.RouterGroup) {
 	})
 }
 
+// authorizeUserManagement checks that the current user may manage the target user.
+// Only the host user can manage itself, it is never managed by others.
+func (s *Service) authorizeUserManagement(ctx *gin.Context, currentUser *api.User, userID int) bool {
+	canManageUser, err := s.hasPermission(ctx, currentUser, api.PermissionUserManage)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "Failed to find role permissions")
+		return false
+	}
+	if !canManageUser {
+		ctx.String(http.StatusForbidden, "Access forbidden for current session user")
+		return false
+	}
+	user, err := s.Store.FindUser(ctx, &api.UserFind{
+		ID: &userID,
+	})
+	if err != nil {
+		if common.ErrorCode(err) == common.NotFound {
+			ctx.String(http.StatusNotFound, fmt.Sprintf("User ID not found: %d", userID))
+			return false
+		}
+		ctx.String(http.StatusInternalServerError, "Failed to find user")
+		return false
+	}
+	if user.Role == api.Host {
+		ctx.String(http.StatusForbidden, "Access forbidden for current session user")
+		return false
+	}
+	// A user with more permissions than the current user is not managed by it, e.g. its password can't be reset.
+	return s.authorizeRoleGrant(ctx, currentUser, user.Role)
+}
+
+// authorizeRoleGrant checks that the current user is granted every permission of the role,
+// so that nobody grants a role with more permissions than its own.
+func (s *Service) authorizeRoleGrant(ctx *gin.Context, currentUser *api.User, role api.Role) bool {
+	permissionList, err := s.findRolePermissionList(ctx, role)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "Failed to find role permissions")
+		return false
+	}
+	return s.authorizePermissionList(ctx, currentUser, permissionList)
+}
+
+// validateRole checks that the role is a built-in role or an existing custom role.
+func (s *Service) validateRole(ctx context.Context, role api.Role) error {
+	if role.IsBuiltin() {
+		return nil
+	}
+	if _, err := s.Store.FindCustomRole(ctx, &api.CustomRoleFind{Name: &role}); err != nil {
+		if common.ErrorCode(err) == common.NotFound {
+			return fmt.Errorf("role not found: %s", role)
+		}
+		return err
+	}
+	return nil
+}
+
 func (s *Service) createUserCreateActivity(ctx *gin.Context, user *api.User) error {
 	payload := api.ActivityUserCreatePayload{
 		UserID:   user.ID,
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"uamemos/api"
+	"uamemos/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Service) registerUserFollowRoutes(rg *gin.RouterGroup) {
+	rg.POST("/user/:id/follow", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+		followingID, ok := s.findFollowedUserID(ctx)
+		if !ok {
+			return
+		}
+		if followingID == user.ID {
+			ctx.String(http.StatusBadRequest, "Could not follow yourself")
+			return
+		}
+
+		userFollow, err := s.Store.UpsertUserFollow(ctx, &api.UserFollowUpsert{
+			FollowerID:  user.ID,
+			FollowingID: followingID,
+		})
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to follow user")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(userFollow))
+	})
+
+	rg.DELETE("/user/:id/follow", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+		followingID, err := strconv.Atoi(ctx.Param("id"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("id")))
+			return
+		}
+
+		if err := s.Store.DeleteUserFollow(ctx, &api.UserFollowDelete{
+			FollowerID:  user.ID,
+			FollowingID: followingID,
+		}); err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Not following user ID: %d", followingID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to unfollow user")
+			return
+		}
+		ctx.JSON(http.StatusOK, true)
+	})
+
+	rg.GET("/user/:id/follower", func(ctx *gin.Context) {
+		userID, ok := s.findFollowedUserID(ctx)
+		if !ok {
+			return
+		}
+
+		userFollowList, err := s.Store.FindUserFollowList(ctx, &api.UserFollowFind{
+			FollowingID: &userID,
+		})
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find follower list")
+			return
+		}
+		userIDList := []int{}
+		for _, userFollow := range userFollowList {
+			userIDList = append(userIDList, userFollow.FollowerID)
+		}
+		ctx.JSON(http.StatusOK, composeResponse(userIDList))
+	})
+
+	rg.GET("/user/:id/following", func(ctx *gin.Context) {
+		userID, ok := s.findFollowedUserID(ctx)
+		if !ok {
+			return
+		}
+
+		userFollowList, err := s.Store.FindUserFollowList(ctx, &api.UserFollowFind{
+			FollowerID: &userID,
+		})
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find following list")
+			return
+		}
+		userIDList := []int{}
+		for _, userFollow := range userFollowList {
+			userIDList = append(userIDList, userFollow.FollowingID)
+		}
+		ctx.JSON(http.StatusOK, composeResponse(userIDList))
+	})
+}
+
+// findFollowedUserID returns the existing user ID of the request path, or writes the error response.
+func (s *Service) findFollowedUserID(ctx *gin.Context) (int, bool) {
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("id")))
+		return 0, false
+	}
+	if _, err := s.Store.FindUser(ctx, &api.UserFind{ID: &userID}); err != nil {
+		if common.ErrorCode(err) == common.NotFound {
+			ctx.String(http.StatusNotFound, fmt.Sprintf("User ID not found: %d", userID))
+			return 0, false
+		}
+		ctx.String(http.StatusInternalServerError, "Failed to find user")
+		return 0, false
+	}
+	return userID, true
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"uamemos/api"
+	"uamemos/common"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slices"
+)
+
+func (s *Service) registerUserGroupRoutes(rg *gin.RouterGroup) {
+	rg.POST("/group", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx, api.PermissionUserManage)
+		if !ok {
+			return
+		}
+
+		userGroupCreate := &api.UserGroupCreate{}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(userGroupCreate); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted post group request")
+			return
+		}
+		if err := userGroupCreate.Validate(); err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		userGroupCreate.CreatorID = user.ID
+		userGroup, err := s.Store.CreateUserGroup(ctx, userGroupCreate)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to create group")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(userGroup))
+	})
+
+	rg.GET("/group", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+		canManageUser, err := s.hasPermission(ctx, user, api.PermissionUserManage)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find role permissions")
+			return
+		}
+
+		userGroupFind := &api.UserGroupFind{}
+		// Users without user management only see the groups they belong to.
+		if !canManageUser {
+			userGroupFind.MemberID = &user.ID
+		}
+		userGroupList, err := s.Store.FindUserGroupList(ctx, userGroupFind)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find group list")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(userGroupList))
+	})
+
+	rg.GET("/group/:groupId", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+		groupID, err := strconv.Atoi(ctx.Param("groupId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("groupId")))
+			return
+		}
+
+		userGroup, err := s.Store.FindUserGroup(ctx, &api.UserGroupFind{
+			ID: &groupID,
+		})
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Group ID not found: %d", groupID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to find group")
+			return
+		}
+
+		canManageUser, err := s.hasPermission(ctx, user, api.PermissionUserManage)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to find role permissions")
+			return
+		}
+		if !canManageUser && !slices.Contains(userGroup.MemberIDList, user.ID) {
+			ctx.String(http.StatusForbidden, "Access forbidden for current session user")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(userGroup))
+	})
+
+	rg.PATCH("/group/:groupId", func(ctx *gin.Context) {
+		if _, ok := s.authorize(ctx, api.PermissionUserManage); !ok {
+			return
+		}
+		groupID, err := strconv.Atoi(ctx.Param("groupId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("groupId")))
+			return
+		}
+
+		currentTs := time.Now().Unix()
+		userGroupPatch := &api.UserGroupPatch{
+			UpdatedTs: &currentTs,
+		}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(userGroupPatch); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted patch group request")
+			return
+		}
+		userGroupPatch.ID = groupID
+		if err := userGroupPatch.Validate(); err != nil {
+			ctx.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		userGroup, err := s.Store.PatchUserGroup(ctx, userGroupPatch)
+		if err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Group ID not found: %d", groupID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to patch group")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(userGroup))
+	})
+
+	rg.DELETE("/group/:groupId", func(ctx *gin.Context) {
+		if _, ok := s.authorize(ctx, api.PermissionUserManage); !ok {
+			return
+		}
+		groupID, err := strconv.Atoi(ctx.Param("groupId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("groupId")))
+			return
+		}
+
+		if err := s.Store.DeleteUserGroup(ctx, &api.UserGroupDelete{ID: groupID}); err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Group ID not found: %d", groupID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to delete group")
+			return
+		}
+		ctx.JSON(http.StatusOK, true)
+	})
+
+	rg.POST("/group/:groupId/member", func(ctx *gin.Context) {
+		if _, ok := s.authorize(ctx, api.PermissionUserManage); !ok {
+			return
+		}
+		groupID, err := strconv.Atoi(ctx.Param("groupId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("groupId")))
+			return
+		}
+
+		userGroupMemberUpsert := &api.UserGroupMemberUpsert{}
+		if err := json.NewDecoder(ctx.Request.Body).Decode(userGroupMemberUpsert); err != nil {
+			ctx.String(http.StatusBadRequest, "Malformatted post group member request")
+			return
+		}
+		userGroupMemberUpsert.GroupID = groupID
+
+		if _, err := s.Store.FindUserGroup(ctx, &api.UserGroupFind{ID: &groupID}); err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("Group ID not found: %d", groupID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to find group")
+			return
+		}
+		if _, err := s.Store.FindUser(ctx, &api.UserFind{ID: &userGroupMemberUpsert.UserID}); err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("User ID not found: %d", userGroupMemberUpsert.UserID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to find user")
+			return
+		}
+
+		userGroupMember, err := s.Store.UpsertUserGroupMember(ctx, userGroupMemberUpsert)
+		if err != nil {
+			ctx.String(http.StatusInternalServerError, "Failed to upsert group member")
+			return
+		}
+		ctx.JSON(http.StatusOK, composeResponse(userGroupMember))
+	})
+
+	rg.DELETE("/group/:groupId/member/:userId", func(ctx *gin.Context) {
+		user, ok := s.authorize(ctx)
+		if !ok {
+			return
+		}
+		groupID, err := strconv.Atoi(ctx.Param("groupId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("groupId")))
+			return
+		}
+		userID, err := strconv.Atoi(ctx.Param("userId"))
+		if err != nil {
+			ctx.String(http.StatusBadRequest, fmt.Sprintf("ID is not a number: %s", ctx.Param("userId")))
+			return
+		}
+
+		// Members can always leave a group by themselves.
+		if user.ID != userID {
+			canManageUser, err := s.hasPermission(ctx, user, api.PermissionUserManage)
+			if err != nil {
+				ctx.String(http.StatusInternalServerError, "Failed to find role permissions")
+				return
+			}
+			if !canManageUser {
+				ctx.String(http.StatusForbidden, "Access forbidden for current session user")
+				return
+			}
+		}
+
+		if err := s.Store.DeleteUserGroupMember(ctx, &api.UserGroupMemberDelete{
+			GroupID: groupID,
+			UserID:  userID,
+		}); err != nil {
+			if common.ErrorCode(err) == common.NotFound {
+				ctx.String(http.StatusNotFound, fmt.Sprintf("User %d is not a member of group %d", userID, groupID))
+				return
+			}
+			ctx.String(http.StatusInternalServerError, "Failed to delete group member")
+			return
+		}
+		ctx.JSON(http.StatusOK, true)
+	})
+}
+
+// isUserGroupMember reports whether the user belongs to the group.
+func (s *Service) isUserGroupMember(ctx context.Context, groupID, userID int) (bool, error) {
+	userGroupMemberList, err := s.Store.FindUserGroupMemberList(ctx, &api.UserGroupMemberFind{
+		GroupID: &groupID,
+		UserID:  &userID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(userGroupMemberList) > 0, nil
+}
@@ -0,0 +1,237 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"uamemos/api"
+	"uamemos/common"
+)
+
+// CreateUserKeyPair creates the key pair of the user, the existing key pair is kept and returned if any.
+func (s *Store) CreateUserKeyPair(ctx context.Context, create *api.UserKeyPairCreate) (*api.UserKeyPair, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO user_key_pair (
+			user_id,
+			public_key,
+			private_key
+		)
+		VALUES (?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE
+		SET
+			user_id = EXCLUDED.user_id
+		RETURNING user_id, created_ts, public_key, private_key
+	`
+	var userKeyPair api.UserKeyPair
+	if err := tx.QueryRowContext(ctx, query, create.UserID, create.PublicKey, create.PrivateKey).Scan(
+		&userKeyPair.UserID,
+		&userKeyPair.CreatedTs,
+		&userKeyPair.PublicKey,
+		&userKeyPair.PrivateKey,
+	); err != nil {
+		return nil, FormatError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return &userKeyPair, nil
+}
+
+func (s *Store) FindUserKeyPair(ctx context.Context, find *api.UserKeyPairFind) (*api.UserKeyPair, error) {
+	query := `
+		SELECT
+			user_id,
+			created_ts,
+			public_key,
+			private_key
+		FROM user_key_pair
+		WHERE user_id = ?
+	`
+	var userKeyPair api.UserKeyPair
+	if err := s.db.QueryRowContext(ctx, query, find.UserID).Scan(
+		&userKeyPair.UserID,
+		&userKeyPair.CreatedTs,
+		&userKeyPair.PublicKey,
+		&userKeyPair.PrivateKey,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("not found")}
+		}
+		return nil, FormatError(err)
+	}
+
+	return &userKeyPair, nil
+}
+
+func (s *Store) UpsertActivityPubFollower(ctx context.Context, upsert *api.ActivityPubFollowerUpsert) (*api.ActivityPubFollower, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO activitypub_follower (
+			user_id,
+			actor,
+			inbox
+		)
+		VALUES (?, ?, ?)
+		ON CONFLICT(user_id, actor) DO UPDATE
+		SET
+			inbox = EXCLUDED.inbox
+		RETURNING id, user_id, created_ts, actor, inbox
+	`
+	var follower api.ActivityPubFollower
+	if err := tx.QueryRowContext(ctx, query, upsert.UserID, upsert.Actor, upsert.Inbox).Scan(
+		&follower.ID,
+		&follower.UserID,
+		&follower.CreatedTs,
+		&follower.Actor,
+		&follower.Inbox,
+	); err != nil {
+		return nil, FormatError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return &follower, nil
+}
+
+func (s *Store) FindActivityPubFollowerList(ctx context.Context, find *api.ActivityPubFollowerFind) ([]*api.ActivityPubFollower, error) {
+	where, args := findActivityPubFollowerWhere(find)
+	query := `
+		SELECT
+			id,
+			user_id,
+			created_ts,
+			actor,
+			inbox
+		FROM activitypub_follower
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY id ASC
+	`
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	list := make([]*api.ActivityPubFollower, 0)
+	for rows.Next() {
+		var follower api.ActivityPubFollower
+		if err := rows.Scan(
+			&follower.ID,
+			&follower.UserID,
+			&follower.CreatedTs,
+			&follower.Actor,
+			&follower.Inbox,
+		); err != nil {
+			return nil, FormatError(err)
+		}
+		list = append(list, &follower)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return list, nil
+}
+
+func (s *Store) CountActivityPubFollower(ctx context.Context, find *api.ActivityPubFollowerFind) (int, error) {
+	where, args := findActivityPubFollowerWhere(find)
+
+	query := `SELECT COUNT(*) FROM activitypub_follower WHERE ` + strings.Join(where, " AND ")
+	var count int
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, FormatError(err)
+	}
+	return count, nil
+}
+
+func (s *Store) DeleteActivityPubFollower(ctx context.Context, delete *api.ActivityPubFollowerDelete) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return FormatError(err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM activitypub_follower WHERE user_id = ? AND actor = ?`, delete.UserID, delete.Actor)
+	if err != nil {
+		return FormatError(err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return &common.Error{Code: common.NotFound, Err: fmt.Errorf("activitypub follower not found")}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func findActivityPubFollowerWhere(find *api.ActivityPubFollowerFind) ([]string, []any) {
+	where, args := []string{"1 = 1"}, []any{}
+
+	if v := find.UserID; v != nil {
+		where, args = append(where, "user_id = ?"), append(args, *v)
+	}
+	if v := find.Actor; v != nil {
+		where, args = append(where, "actor = ?"), append(args, *v)
+	}
+
+	return where, args
+}
+
+func vacuumUserKeyPair(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		user_key_pair
+	WHERE
+		user_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func vacuumActivityPubFollower(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		activitypub_follower
+	WHERE
+		user_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
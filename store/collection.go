@@ -0,0 +1,483 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"uamemos/api"
+	"uamemos/common"
+
+	"golang.org/x/exp/slices"
+)
+
+// collectionRaw is the store model for a Collection.
+// Fields have exactly the same meanings as Collection.
+type collectionRaw struct {
+	ID int
+
+	// Standard fields
+	CreatorID int
+	CreatedTs int64
+	UpdatedTs int64
+
+	// Domain specific fields
+	Name        string
+	Description string
+	Visibility  api.Visibility
+	MemoIDList  []int
+}
+
+func (raw *collectionRaw) toCollection() *api.Collection {
+	return &api.Collection{
+		ID: raw.ID,
+
+		CreatorID: raw.CreatorID,
+		CreatedTs: raw.CreatedTs,
+		UpdatedTs: raw.UpdatedTs,
+
+		Name:        raw.Name,
+		Description: raw.Description,
+		Visibility:  raw.Visibility,
+		MemoIDList:  raw.MemoIDList,
+	}
+}
+
+func (s *Store) CreateCollection(ctx context.Context, create *api.CollectionCreate) (*api.Collection, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	collectionRaw, err := createCollection(ctx, tx, create)
+	if err != nil {
+		return nil, err
+	}
+	if err := setCollectionMemoList(ctx, tx, collectionRaw.ID, create.MemoIDList); err != nil {
+		return nil, err
+	}
+	if collectionRaw.MemoIDList, err = findCollectionMemoIDList(ctx, tx, collectionRaw.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return collectionRaw.toCollection(), nil
+}
+
+func (s *Store) PatchCollection(ctx context.Context, patch *api.CollectionPatch) (*api.Collection, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	collectionRaw, err := patchCollection(ctx, tx, patch)
+	if err != nil {
+		return nil, err
+	}
+	if patch.MemoIDList != nil {
+		if err := setCollectionMemoList(ctx, tx, collectionRaw.ID, patch.MemoIDList); err != nil {
+			return nil, err
+		}
+	}
+	if collectionRaw.MemoIDList, err = findCollectionMemoIDList(ctx, tx, collectionRaw.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return collectionRaw.toCollection(), nil
+}
+
+func (s *Store) FindCollectionList(ctx context.Context, find *api.CollectionFind) ([]*api.Collection, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	collectionRawList, err := findCollectionList(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*api.Collection{}
+	for _, raw := range collectionRawList {
+		list = append(list, raw.toCollection())
+	}
+
+	return list, nil
+}
+
+func (s *Store) FindCollection(ctx context.Context, find *api.CollectionFind) (*api.Collection, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	list, err := findCollectionList(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("not found")}
+	}
+
+	return list[0].toCollection(), nil
+}
+
+func (s *Store) DeleteCollection(ctx context.Context, delete *api.CollectionDelete) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return FormatError(err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM collection WHERE id = ?`, delete.ID)
+	if err != nil {
+		return FormatError(err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return &common.Error{Code: common.NotFound, Err: fmt.Errorf("collection not found")}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM collection_memo WHERE collection_id = ?`, delete.ID); err != nil {
+		return FormatError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+// UpsertCollectionMemo adds the memo to the collection or moves it to the position, and returns the updated collection.
+func (s *Store) UpsertCollectionMemo(ctx context.Context, upsert *api.CollectionMemoUpsert) (*api.Collection, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	memoIDList, err := findCollectionMemoIDList(ctx, tx, upsert.CollectionID)
+	if err != nil {
+		return nil, err
+	}
+	if index := slices.Index(memoIDList, upsert.MemoID); index >= 0 {
+		memoIDList = slices.Delete(memoIDList, index, index+1)
+	}
+	if len(memoIDList) >= api.MaxCollectionMemoCount {
+		return nil, &common.Error{Code: common.Invalid, Err: fmt.Errorf("too many memos, up to %d memos in a collection", api.MaxCollectionMemoCount)}
+	}
+	position := len(memoIDList)
+	if v := upsert.Position; v != nil && *v >= 0 && *v < position {
+		position = *v
+	}
+	memoIDList = slices.Insert(memoIDList, position, upsert.MemoID)
+
+	if err := setCollectionMemoList(ctx, tx, upsert.CollectionID, memoIDList); err != nil {
+		return nil, err
+	}
+	collectionRaw, err := touchCollection(ctx, tx, upsert.CollectionID)
+	if err != nil {
+		return nil, err
+	}
+	collectionRaw.MemoIDList = memoIDList
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return collectionRaw.toCollection(), nil
+}
+
+func (s *Store) DeleteCollectionMemo(ctx context.Context, delete *api.CollectionMemoDelete) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return FormatError(err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM collection_memo WHERE collection_id = ? AND memo_id = ?`, delete.CollectionID, delete.MemoID)
+	if err != nil {
+		return FormatError(err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return &common.Error{Code: common.NotFound, Err: fmt.Errorf("collection memo not found")}
+	}
+	if _, err := touchCollection(ctx, tx, delete.CollectionID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func createCollection(ctx context.Context, tx *sql.Tx, create *api.CollectionCreate) (*collectionRaw, error) {
+	query := `
+		INSERT INTO collection (
+			creator_id,
+			name,
+			description,
+			visibility
+		)
+		VALUES (?, ?, ?, ?)
+		RETURNING id, creator_id, created_ts, updated_ts, name, description, visibility
+	`
+	var collectionRaw collectionRaw
+	if err := tx.QueryRowContext(ctx, query, create.CreatorID, create.Name, create.Description, create.Visibility).Scan(
+		&collectionRaw.ID,
+		&collectionRaw.CreatorID,
+		&collectionRaw.CreatedTs,
+		&collectionRaw.UpdatedTs,
+		&collectionRaw.Name,
+		&collectionRaw.Description,
+		&collectionRaw.Visibility,
+	); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return &collectionRaw, nil
+}
+
+func patchCollection(ctx context.Context, tx *sql.Tx, patch *api.CollectionPatch) (*collectionRaw, error) {
+	set, args := []string{}, []any{}
+
+	if v := patch.UpdatedTs; v != nil {
+		set, args = append(set, "updated_ts = ?"), append(args, *v)
+	}
+	if v := patch.Name; v != nil {
+		set, args = append(set, "name = ?"), append(args, *v)
+	}
+	if v := patch.Description; v != nil {
+		set, args = append(set, "description = ?"), append(args, *v)
+	}
+	if v := patch.Visibility; v != nil {
+		set, args = append(set, "visibility = ?"), append(args, *v)
+	}
+
+	args = append(args, patch.ID)
+
+	query := `
+		UPDATE collection
+		SET ` + strings.Join(set, ", ") + `
+		WHERE id = ?
+		RETURNING id, creator_id, created_ts, updated_ts, name, description, visibility
+	`
+	var collectionRaw collectionRaw
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&collectionRaw.ID,
+		&collectionRaw.CreatorID,
+		&collectionRaw.CreatedTs,
+		&collectionRaw.UpdatedTs,
+		&collectionRaw.Name,
+		&collectionRaw.Description,
+		&collectionRaw.Visibility,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("collection not found")}
+		}
+		return nil, FormatError(err)
+	}
+
+	return &collectionRaw, nil
+}
+
+// touchCollection updates the updated time of the collection after its memos are changed.
+func touchCollection(ctx context.Context, tx *sql.Tx, collectionID int) (*collectionRaw, error) {
+	query := `
+		UPDATE collection
+		SET updated_ts = strftime('%s', 'now')
+		WHERE id = ?
+		RETURNING id, creator_id, created_ts, updated_ts, name, description, visibility
+	`
+	var collectionRaw collectionRaw
+	if err := tx.QueryRowContext(ctx, query, collectionID).Scan(
+		&collectionRaw.ID,
+		&collectionRaw.CreatorID,
+		&collectionRaw.CreatedTs,
+		&collectionRaw.UpdatedTs,
+		&collectionRaw.Name,
+		&collectionRaw.Description,
+		&collectionRaw.Visibility,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("collection not found")}
+		}
+		return nil, FormatError(err)
+	}
+
+	return &collectionRaw, nil
+}
+
+func findCollectionList(ctx context.Context, tx *sql.Tx, find *api.CollectionFind) ([]*collectionRaw, error) {
+	where, args := []string{"1 = 1"}, []any{}
+
+	if v := find.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := find.CreatorID; v != nil {
+		where, args = append(where, "creator_id = ?"), append(args, *v)
+	}
+	if v := find.VisibilityList; len(v) != 0 {
+		list := []string{}
+		for _, visibility := range v {
+			list = append(list, "?")
+			args = append(args, visibility)
+		}
+		where = append(where, fmt.Sprintf("visibility IN (%s)", strings.Join(list, ",")))
+	}
+	if v := find.MemoID; v != nil {
+		where, args = append(where, "id IN (SELECT collection_id FROM collection_memo WHERE memo_id = ?)"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			creator_id,
+			created_ts,
+			updated_ts,
+			name,
+			description,
+			visibility
+		FROM collection
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY updated_ts DESC, id DESC`,
+		args...,
+	)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+
+	collectionRawList := make([]*collectionRaw, 0)
+	for rows.Next() {
+		var collectionRaw collectionRaw
+		if err := rows.Scan(
+			&collectionRaw.ID,
+			&collectionRaw.CreatorID,
+			&collectionRaw.CreatedTs,
+			&collectionRaw.UpdatedTs,
+			&collectionRaw.Name,
+			&collectionRaw.Description,
+			&collectionRaw.Visibility,
+		); err != nil {
+			rows.Close()
+			return nil, FormatError(err)
+		}
+
+		collectionRawList = append(collectionRawList, &collectionRaw)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, FormatError(err)
+	}
+	rows.Close()
+
+	for _, collectionRaw := range collectionRawList {
+		if collectionRaw.MemoIDList, err = findCollectionMemoIDList(ctx, tx, collectionRaw.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return collectionRawList, nil
+}
+
+// findCollectionMemoIDList finds the IDs of the memos of the collection in order.
+func findCollectionMemoIDList(ctx context.Context, tx *sql.Tx, collectionID int) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT memo_id FROM collection_memo WHERE collection_id = ? ORDER BY position ASC
+	`, collectionID)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	memoIDList := []int{}
+	for rows.Next() {
+		var memoID int
+		if err := rows.Scan(&memoID); err != nil {
+			return nil, FormatError(err)
+		}
+		memoIDList = append(memoIDList, memoID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return memoIDList, nil
+}
+
+// setCollectionMemoList replaces the memos of the collection, the positions follow the order of the list.
+func setCollectionMemoList(ctx context.Context, tx *sql.Tx, collectionID int, memoIDList []int) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM collection_memo WHERE collection_id = ?`, collectionID); err != nil {
+		return FormatError(err)
+	}
+	for position, memoID := range memoIDList {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO collection_memo (collection_id, memo_id, position) VALUES (?, ?, ?)
+		`, collectionID, memoID, position); err != nil {
+			return FormatError(err)
+		}
+	}
+
+	return nil
+}
+
+func vacuumCollection(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		collection
+	WHERE
+		creator_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func vacuumCollectionMemo(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		collection_memo
+	WHERE
+		collection_id NOT IN (
+			SELECT
+				id
+			FROM
+				collection
+		)
+		OR memo_id NOT IN (
+			SELECT
+				id
+			FROM
+				memo
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,282 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"uamemos/api"
+	"uamemos/common"
+)
+
+type customRoleRaw struct {
+	CreatedTs int64
+	UpdatedTs int64
+
+	// Domain specific fields
+	Name           api.Role
+	Description    string
+	PermissionList []api.Permission
+}
+
+func (raw *customRoleRaw) toCustomRole() *api.CustomRole {
+	return &api.CustomRole{
+		CreatedTs: raw.CreatedTs,
+		UpdatedTs: raw.UpdatedTs,
+
+		Name:           raw.Name,
+		Description:    raw.Description,
+		PermissionList: raw.PermissionList,
+	}
+}
+
+func (s *Store) CreateCustomRole(ctx context.Context, create *api.CustomRoleCreate) (*api.CustomRole, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	customRoleRaw, err := createCustomRoleRaw(ctx, tx, create)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	s.customRoleCache.Store(customRoleRaw.Name, customRoleRaw)
+	return customRoleRaw.toCustomRole(), nil
+}
+
+func (s *Store) PatchCustomRole(ctx context.Context, patch *api.CustomRolePatch) (*api.CustomRole, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	customRoleRaw, err := patchCustomRoleRaw(ctx, tx, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	s.customRoleCache.Store(customRoleRaw.Name, customRoleRaw)
+	return customRoleRaw.toCustomRole(), nil
+}
+
+func (s *Store) FindCustomRoleList(ctx context.Context, find *api.CustomRoleFind) ([]*api.CustomRole, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	customRoleRawList, err := findCustomRoleRawList(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*api.CustomRole{}
+	for _, raw := range customRoleRawList {
+		s.customRoleCache.Store(raw.Name, raw)
+		list = append(list, raw.toCustomRole())
+	}
+
+	return list, nil
+}
+
+func (s *Store) FindCustomRole(ctx context.Context, find *api.CustomRoleFind) (*api.CustomRole, error) {
+	if find.Name != nil {
+		if customRole, ok := s.customRoleCache.Load(*find.Name); ok {
+			return customRole.(*customRoleRaw).toCustomRole(), nil
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	list, err := findCustomRoleRawList(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("not found")}
+	}
+
+	customRoleRaw := list[0]
+	s.customRoleCache.Store(customRoleRaw.Name, customRoleRaw)
+	return customRoleRaw.toCustomRole(), nil
+}
+
+func (s *Store) DeleteCustomRole(ctx context.Context, delete *api.CustomRoleDelete) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return FormatError(err)
+	}
+	defer tx.Rollback()
+
+	if err := deleteCustomRole(ctx, tx, delete); err != nil {
+		return FormatError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return FormatError(err)
+	}
+
+	s.customRoleCache.Delete(delete.Name)
+	return nil
+}
+
+func createCustomRoleRaw(ctx context.Context, tx *sql.Tx, create *api.CustomRoleCreate) (*customRoleRaw, error) {
+	permissionList := create.PermissionList
+	if permissionList == nil {
+		permissionList = []api.Permission{}
+	}
+	permissionListBytes, err := json.Marshal(permissionList)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO custom_role (
+			name,
+			description,
+			permission_list
+		)
+		VALUES (?, ?, ?)
+		RETURNING created_ts, updated_ts
+	`
+	customRoleRaw := customRoleRaw{
+		Name:           create.Name,
+		Description:    create.Description,
+		PermissionList: permissionList,
+	}
+	if err := tx.QueryRowContext(ctx, query, create.Name, create.Description, string(permissionListBytes)).Scan(
+		&customRoleRaw.CreatedTs,
+		&customRoleRaw.UpdatedTs,
+	); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return &customRoleRaw, nil
+}
+
+func patchCustomRoleRaw(ctx context.Context, tx *sql.Tx, patch *api.CustomRolePatch) (*customRoleRaw, error) {
+	set, args := []string{}, []any{}
+	if v := patch.UpdatedTs; v != nil {
+		set, args = append(set, "updated_ts = ?"), append(args, *v)
+	}
+	if v := patch.Description; v != nil {
+		set, args = append(set, "description = ?"), append(args, *v)
+	}
+	if v := patch.PermissionList; v != nil {
+		permissionListBytes, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		set, args = append(set, "permission_list = ?"), append(args, string(permissionListBytes))
+	}
+	args = append(args, patch.Name)
+
+	query := `
+		UPDATE custom_role
+		SET ` + strings.Join(set, ", ") + `
+		WHERE name = ?
+		RETURNING name, created_ts, updated_ts, description, permission_list
+	`
+	var customRoleRaw customRoleRaw
+	var permissionList string
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&customRoleRaw.Name,
+		&customRoleRaw.CreatedTs,
+		&customRoleRaw.UpdatedTs,
+		&customRoleRaw.Description,
+		&permissionList,
+	); err != nil {
+		return nil, FormatError(err)
+	}
+	if err := json.Unmarshal([]byte(permissionList), &customRoleRaw.PermissionList); err != nil {
+		return nil, err
+	}
+
+	return &customRoleRaw, nil
+}
+
+func findCustomRoleRawList(ctx context.Context, tx *sql.Tx, find *api.CustomRoleFind) ([]*customRoleRaw, error) {
+	where, args := []string{"1 = 1"}, []any{}
+
+	if v := find.Name; v != nil {
+		where, args = append(where, "name = ?"), append(args, *v)
+	}
+
+	query := `
+		SELECT
+			name,
+			created_ts,
+			updated_ts,
+			description,
+			permission_list
+		FROM custom_role
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY name ASC
+	`
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	customRoleRawList := make([]*customRoleRaw, 0)
+	for rows.Next() {
+		var customRoleRaw customRoleRaw
+		var permissionList string
+		if err := rows.Scan(
+			&customRoleRaw.Name,
+			&customRoleRaw.CreatedTs,
+			&customRoleRaw.UpdatedTs,
+			&customRoleRaw.Description,
+			&permissionList,
+		); err != nil {
+			return nil, FormatError(err)
+		}
+		if err := json.Unmarshal([]byte(permissionList), &customRoleRaw.PermissionList); err != nil {
+			return nil, err
+		}
+		customRoleRawList = append(customRoleRawList, &customRoleRaw)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return customRoleRawList, nil
+}
+
+func deleteCustomRole(ctx context.Context, tx *sql.Tx, delete *api.CustomRoleDelete) error {
+	where, args := []string{"name = ?"}, []any{delete.Name}
+
+	stmt := `DELETE FROM custom_role WHERE ` + strings.Join(where, " AND ")
+	result, err := tx.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return FormatError(err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return &common.Error{Code: common.NotFound, Err: fmt.Errorf("custom role not found")}
+	}
+
+	return nil
+}
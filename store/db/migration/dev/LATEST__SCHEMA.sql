@@ -19,7 +19,7 @@ CREATE TABLE user (
   updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
   row_status TEXT NOT NULL CHECK (row_status IN ('NORMAL', 'ARCHIVED')) DEFAULT 'NORMAL',
   username TEXT NOT NULL UNIQUE,
-  role TEXT NOT NULL CHECK (role IN ('HOST', 'ADMIN', 'USER')) DEFAULT 'USER',
+  role TEXT NOT NULL DEFAULT 'USER',
   email TEXT NOT NULL DEFAULT '',
   nickname TEXT NOT NULL DEFAULT '',
   password_hash TEXT NOT NULL,
@@ -27,6 +27,33 @@ CREATE TABLE user (
   avatar_url TEXT NOT NULL DEFAULT ''
 );
 
+-- custom_role
+CREATE TABLE custom_role (
+  name TEXT NOT NULL PRIMARY KEY,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  description TEXT NOT NULL DEFAULT '',
+  permission_list TEXT NOT NULL DEFAULT '[]'
+);
+
+-- user_group
+CREATE TABLE user_group (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  creator_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  name TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT ''
+);
+
+-- user_group_member
+CREATE TABLE user_group_member (
+  group_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  UNIQUE(group_id, user_id)
+);
+
 -- user_setting
 CREATE TABLE user_setting (
   user_id INTEGER NOT NULL,
@@ -35,6 +62,32 @@ CREATE TABLE user_setting (
   UNIQUE(user_id, key)
 );
 
+-- user_follow
+CREATE TABLE user_follow (
+  follower_id INTEGER NOT NULL,
+  following_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  UNIQUE(follower_id, following_id)
+);
+
+-- user_key_pair
+CREATE TABLE user_key_pair (
+  user_id INTEGER NOT NULL PRIMARY KEY,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  public_key TEXT NOT NULL,
+  private_key TEXT NOT NULL
+);
+
+-- activitypub_follower
+CREATE TABLE activitypub_follower (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  actor TEXT NOT NULL,
+  inbox TEXT NOT NULL,
+  UNIQUE(user_id, actor)
+);
+
 -- memo
 CREATE TABLE memo (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -43,7 +96,13 @@ CREATE TABLE memo (
   updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
   row_status TEXT NOT NULL CHECK (row_status IN ('NORMAL', 'ARCHIVED')) DEFAULT 'NORMAL',
   content TEXT NOT NULL DEFAULT '',
-  visibility TEXT NOT NULL CHECK (visibility IN ('PUBLIC', 'PROTECTED', 'PRIVATE')) DEFAULT 'PRIVATE'
+  visibility TEXT NOT NULL CHECK (visibility IN ('PUBLIC', 'PROTECTED', 'PRIVATE', 'GROUP')) DEFAULT 'PRIVATE',
+  group_id INTEGER NOT NULL DEFAULT 0,
+  parent_id INTEGER NOT NULL DEFAULT 0,
+  version INTEGER NOT NULL DEFAULT 1,
+  latitude REAL DEFAULT NULL,
+  longitude REAL DEFAULT NULL,
+  place_name TEXT NOT NULL DEFAULT ''
 );
 
 -- memo_organizer
@@ -52,9 +111,47 @@ CREATE TABLE memo_organizer (
   memo_id INTEGER NOT NULL,
   user_id INTEGER NOT NULL,
   pinned INTEGER NOT NULL CHECK (pinned IN (0, 1)) DEFAULT 0,
+  pinned_position INTEGER NOT NULL DEFAULT 0,
+  bookmarked INTEGER NOT NULL CHECK (bookmarked IN (0, 1)) DEFAULT 0,
+  read_later INTEGER NOT NULL CHECK (read_later IN (0, 1)) DEFAULT 0,
   UNIQUE(memo_id, user_id)
 );
 
+-- memo_reaction
+CREATE TABLE memo_reaction (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  memo_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  reaction TEXT NOT NULL,
+  UNIQUE(memo_id, user_id, reaction)
+);
+
+-- notification
+CREATE TABLE notification (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  receiver_id INTEGER NOT NULL,
+  sender_id INTEGER NOT NULL,
+  type TEXT NOT NULL CHECK (type IN ('COMMENT', 'REACTION', 'MENTION')),
+  memo_id INTEGER NOT NULL,
+  reaction TEXT NOT NULL DEFAULT '',
+  read INTEGER NOT NULL CHECK (read IN (0, 1)) DEFAULT 0
+);
+
+-- memo_share
+CREATE TABLE memo_share (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  creator_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  memo_id INTEGER NOT NULL,
+  token TEXT NOT NULL UNIQUE,
+  expired_ts BIGINT NOT NULL DEFAULT 0,
+  password_hash TEXT NOT NULL DEFAULT '',
+  max_view_count INTEGER NOT NULL DEFAULT 0,
+  view_count INTEGER NOT NULL DEFAULT 0
+);
+
 -- shortcut
 CREATE TABLE shortcut (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -66,6 +163,37 @@ CREATE TABLE shortcut (
   payload TEXT NOT NULL DEFAULT '{}'
 );
 
+-- memo_template
+CREATE TABLE memo_template (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  creator_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  scope TEXT NOT NULL CHECK (scope IN ('USER', 'SYSTEM')) DEFAULT 'USER',
+  name TEXT NOT NULL,
+  content TEXT NOT NULL DEFAULT ''
+);
+
+-- collection
+CREATE TABLE collection (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  creator_id INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  name TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  visibility TEXT NOT NULL CHECK (visibility IN ('PUBLIC', 'PROTECTED', 'PRIVATE')) DEFAULT 'PRIVATE'
+);
+
+-- collection_memo
+CREATE TABLE collection_memo (
+  collection_id INTEGER NOT NULL,
+  memo_id INTEGER NOT NULL,
+  position INTEGER NOT NULL DEFAULT 0,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  UNIQUE(collection_id, memo_id)
+);
+
 -- resource
 CREATE TABLE resource (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -79,6 +207,8 @@ CREATE TABLE resource (
   size INTEGER NOT NULL DEFAULT 0,
   internal_path TEXT NOT NULL DEFAULT '',
   public_id TEXT NOT NULL DEFAULT '',
+  latitude REAL DEFAULT NULL,
+  longitude REAL DEFAULT NULL,
   UNIQUE(id, public_id)
 );
 
@@ -98,6 +228,25 @@ CREATE TABLE tag (
   UNIQUE(name, creator_id)
 );
 
+-- memo_tag
+CREATE TABLE memo_tag (
+  memo_id INTEGER NOT NULL,
+  tag TEXT NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  UNIQUE(memo_id, tag)
+);
+
+-- memo_task
+CREATE TABLE memo_task (
+  memo_id INTEGER NOT NULL,
+  idx INTEGER NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  updated_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  content TEXT NOT NULL DEFAULT '',
+  done INTEGER NOT NULL CHECK (done IN (0, 1)) DEFAULT 0,
+  UNIQUE(memo_id, idx)
+);
+
 -- activity
 CREATE TABLE activity (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -123,4 +272,13 @@ CREATE TABLE idp (
   type TEXT NOT NULL,
   identifier_filter TEXT NOT NULL DEFAULT '',
   config TEXT NOT NULL DEFAULT '{}'
+);
+
+-- user_idp
+CREATE TABLE user_idp (
+  user_id INTEGER NOT NULL,
+  idp_id INTEGER NOT NULL,
+  subject TEXT NOT NULL,
+  created_ts BIGINT NOT NULL DEFAULT (strftime('%s', 'now')),
+  UNIQUE(idp_id, subject)
 );
\ No newline at end of file
@@ -14,10 +14,14 @@ type IdentityProviderType string
 
 const (
 	IdentityProviderOAuth2 IdentityProviderType = "OAUTH2"
+	IdentityProviderLDAP   IdentityProviderType = "LDAP"
+	IdentityProviderOIDC   IdentityProviderType = "OIDC"
 )
 
 type IdentityProviderConfig struct {
 	OAuth2Config *IdentityProviderOAuth2Config
+	LDAPConfig   *IdentityProviderLDAPConfig
+	OIDCConfig   *IdentityProviderOIDCConfig
 }
 
 type IdentityProviderOAuth2Config struct {
@@ -30,6 +34,35 @@ type IdentityProviderOAuth2Config struct {
 	FieldMapping *FieldMapping `json:"fieldMapping"`
 }
 
+type IdentityProviderLDAPConfig struct {
+	// URL is the address of the directory server, e.g. ldap://ldap.example.com:389 or ldaps://ldap.example.com:636.
+	URL string `json:"url"`
+	// StartTLS upgrades a plain ldap:// connection with the StartTLS extended operation.
+	StartTLS           bool `json:"startTls"`
+	InsecureSkipVerify bool `json:"insecureSkipVerify"`
+	// BindDN and BindPassword are the service account used to search for users.
+	// Anonymous search is used when BindDN is empty.
+	BindDN       string `json:"bindDn"`
+	BindPassword string `json:"bindPassword"`
+	// BaseDN is the search base for users, e.g. ou=people,dc=example,dc=com.
+	BaseDN string `json:"baseDn"`
+	// UserFilter is the search filter for users, the "{username}" placeholder is replaced with the escaped username.
+	// e.g. (&(objectClass=person)(uid={username}))
+	UserFilter   string        `json:"userFilter"`
+	FieldMapping *FieldMapping `json:"fieldMapping"`
+}
+
+type IdentityProviderOIDCConfig struct {
+	// Issuer is the OpenID Provider issuer URL, the endpoints and signing keys are discovered
+	// from its .well-known/openid-configuration document.
+	Issuer       string `json:"issuer"`
+	ClientID     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret"`
+	// Scopes defaults to "openid profile email" when empty.
+	Scopes       []string      `json:"scopes"`
+	FieldMapping *FieldMapping `json:"fieldMapping"`
+}
+
 type FieldMapping struct {
 	Identifier  string `json:"identifier"`
 	DisplayName string `json:"displayName"`
@@ -67,14 +100,9 @@ func (s *Store) CreateIdentityProvider(ctx context.Context, create *IdentityProv
 	}
 	defer tx.Rollback()
 
-	var configBytes []byte
-	if create.Type == IdentityProviderOAuth2 {
-		configBytes, err = json.Marshal(create.Config.OAuth2Config)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		return nil, fmt.Errorf("unsupported idp type %s", string(create.Type))
+	configBytes, err := marshalIdentityProviderConfig(create.Type, create.Config)
+	if err != nil {
+		return nil, err
 	}
 	query := `
 		INSERT INTO idp (
@@ -165,14 +193,9 @@ func (s *Store) UpdateIdentityProvider(ctx context.Context, update *UpdateIdenti
 		set, args = append(set, "identifier_filter = ?"), append(args, *v)
 	}
 	if v := update.Config; v != nil {
-		var configBytes []byte
-		if update.Type == IdentityProviderOAuth2 {
-			configBytes, err = json.Marshal(update.Config.OAuth2Config)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			return nil, fmt.Errorf("unsupported idp type %s", string(update.Type))
+		configBytes, err := marshalIdentityProviderConfig(update.Type, v)
+		if err != nil {
+			return nil, err
 		}
 		set, args = append(set, "config = ?"), append(args, string(configBytes))
 	}
@@ -195,17 +218,11 @@ func (s *Store) UpdateIdentityProvider(ctx context.Context, update *UpdateIdenti
 	); err != nil {
 		return nil, FormatError(err)
 	}
-	if identityProviderMessage.Type == IdentityProviderOAuth2 {
-		oauth2Config := &IdentityProviderOAuth2Config{}
-		if err := json.Unmarshal([]byte(identityProviderConfig), oauth2Config); err != nil {
-			return nil, err
-		}
-		identityProviderMessage.Config = &IdentityProviderConfig{
-			OAuth2Config: oauth2Config,
-		}
-	} else {
-		return nil, fmt.Errorf("unsupported idp type %s", string(identityProviderMessage.Type))
+	config, err := unmarshalIdentityProviderConfig(identityProviderMessage.Type, identityProviderConfig)
+	if err != nil {
+		return nil, err
 	}
+	identityProviderMessage.Config = config
 	if err := tx.Commit(); err != nil {
 		return nil, FormatError(err)
 	}
@@ -234,6 +251,9 @@ func (s *Store) DeleteIdentityProvider(ctx context.Context, delete *DeleteIdenti
 	if rows == 0 {
 		return &common.Error{Code: common.NotFound, Err: fmt.Errorf("idp not found")}
 	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_idp WHERE idp_id = ?`, delete.ID); err != nil {
+		return FormatError(err)
+	}
 	if err := tx.Commit(); err != nil {
 		return err
 	}
@@ -276,17 +296,11 @@ func listIdentityProviders(ctx context.Context, tx *sql.Tx, find *FindIdentityPr
 		); err != nil {
 			return nil, FormatError(err)
 		}
-		if identityProviderMessage.Type == IdentityProviderOAuth2 {
-			oauth2Config := &IdentityProviderOAuth2Config{}
-			if err := json.Unmarshal([]byte(identityProviderConfig), oauth2Config); err != nil {
-				return nil, err
-			}
-			identityProviderMessage.Config = &IdentityProviderConfig{
-				OAuth2Config: oauth2Config,
-			}
-		} else {
-			return nil, fmt.Errorf("unsupported idp type %s", string(identityProviderMessage.Type))
+		config, err := unmarshalIdentityProviderConfig(identityProviderMessage.Type, identityProviderConfig)
+		if err != nil {
+			return nil, err
 		}
+		identityProviderMessage.Config = config
 		identityProviderMessages = append(identityProviderMessages, &identityProviderMessage)
 	}
 
@@ -296,3 +310,47 @@ func listIdentityProviders(ctx context.Context, tx *sql.Tx, find *FindIdentityPr
 
 	return identityProviderMessages, nil
 }
+
+func marshalIdentityProviderConfig(identityProviderType IdentityProviderType, config *IdentityProviderConfig) ([]byte, error) {
+	switch identityProviderType {
+	case IdentityProviderOAuth2:
+		return json.Marshal(config.OAuth2Config)
+	case IdentityProviderLDAP:
+		return json.Marshal(config.LDAPConfig)
+	case IdentityProviderOIDC:
+		return json.Marshal(config.OIDCConfig)
+	default:
+		return nil, fmt.Errorf("unsupported idp type %s", string(identityProviderType))
+	}
+}
+
+func unmarshalIdentityProviderConfig(identityProviderType IdentityProviderType, config string) (*IdentityProviderConfig, error) {
+	switch identityProviderType {
+	case IdentityProviderOAuth2:
+		oauth2Config := &IdentityProviderOAuth2Config{}
+		if err := json.Unmarshal([]byte(config), oauth2Config); err != nil {
+			return nil, err
+		}
+		return &IdentityProviderConfig{
+			OAuth2Config: oauth2Config,
+		}, nil
+	case IdentityProviderLDAP:
+		ldapConfig := &IdentityProviderLDAPConfig{}
+		if err := json.Unmarshal([]byte(config), ldapConfig); err != nil {
+			return nil, err
+		}
+		return &IdentityProviderConfig{
+			LDAPConfig: ldapConfig,
+		}, nil
+	case IdentityProviderOIDC:
+		oidcConfig := &IdentityProviderOIDCConfig{}
+		if err := json.Unmarshal([]byte(config), oidcConfig); err != nil {
+			return nil, err
+		}
+		return &IdentityProviderConfig{
+			OIDCConfig: oidcConfig,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported idp type %s", string(identityProviderType))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
 	"strings"
 	"uamemos/api"
 	"uamemos/common"
@@ -21,7 +22,13 @@ type memoRaw struct {
 	// Domain specific fields
 	Content    string
 	Visibility api.Visibility
-	Pinned     bool
+	GroupID    int
+	ParentID   int
+	Version    int
+	// Latitude and Longitude are nil for a memo without a location.
+	Latitude  *float64
+	Longitude *float64
+	PlaceName string
 }
 
 // toMemo creates an instance of Memo based on the memoRaw.
@@ -39,7 +46,21 @@ func (raw *memoRaw) toMemo() *api.Memo {
 		// Domain specific fields
 		Content:    raw.Content,
 		Visibility: raw.Visibility,
-		Pinned:     raw.Pinned,
+		GroupID:    raw.GroupID,
+		ParentID:   raw.ParentID,
+		Version:    raw.Version,
+		Location:   raw.toLocation(),
+	}
+}
+
+func (raw *memoRaw) toLocation() *api.Location {
+	if raw.Latitude == nil || raw.Longitude == nil {
+		return nil
+	}
+	return &api.Location{
+		Latitude:  *raw.Latitude,
+		Longitude: *raw.Longitude,
+		PlaceName: raw.PlaceName,
 	}
 }
 
@@ -50,10 +71,30 @@ func (s *Store) ComposeMemo(ctx context.Context, memo *api.Memo) (*api.Memo, err
 	if err := s.ComposeMemoResourceList(ctx, memo); err != nil {
 		return nil, err
 	}
+	if err := s.ComposeMemoCommentCount(ctx, memo); err != nil {
+		return nil, err
+	}
+	if err := s.ComposeMemoReactionList(ctx, memo); err != nil {
+		return nil, err
+	}
+	if err := s.ComposeMemoOrganizer(ctx, memo); err != nil {
+		return nil, err
+	}
 
 	return memo, nil
 }
 
+// ComposeMemoCommentCount counts the normal comments directly under the memo.
+func (s *Store) ComposeMemoCommentCount(ctx context.Context, memo *api.Memo) error {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM memo WHERE parent_id = ? AND row_status = ?
+	`, memo.ID, api.Normal)
+	if err := row.Scan(&memo.CommentCount); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
 func (s *Store) CreateMemo(ctx context.Context, create *api.MemoCreate) (*api.Memo, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -65,6 +106,12 @@ func (s *Store) CreateMemo(ctx context.Context, create *api.MemoCreate) (*api.Me
 	if err != nil {
 		return nil, err
 	}
+	if err := syncMemoTagList(ctx, tx, memoRaw.ID, memoRaw.Content); err != nil {
+		return nil, err
+	}
+	if err := syncMemoTaskList(ctx, tx, memoRaw.ID, memoRaw.Content); err != nil {
+		return nil, err
+	}
 
 	if err := tx.Commit(); err != nil {
 		return nil, FormatError(err)
@@ -90,11 +137,30 @@ func (s *Store) PatchMemo(ctx context.Context, patch *api.MemoPatch) (*api.Memo,
 	if err != nil {
 		return nil, err
 	}
+	if patch.Content != nil {
+		if err := syncMemoTagList(ctx, tx, memoRaw.ID, memoRaw.Content); err != nil {
+			return nil, err
+		}
+		if err := syncMemoTaskList(ctx, tx, memoRaw.ID, memoRaw.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	descendantIDList := []int{}
+	if patch.Visibility != nil || patch.GroupID != nil {
+		descendantIDList, err = inheritMemoVisibility(ctx, tx, memoRaw)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	if err := tx.Commit(); err != nil {
 		return nil, FormatError(err)
 	}
 
+	for _, id := range descendantIDList {
+		s.memoCache.Delete(id)
+	}
 	s.memoCache.Store(memoRaw.ID, memoRaw)
 	memo, err := s.ComposeMemo(ctx, memoRaw.toMemo())
 	if err != nil {
@@ -129,6 +195,22 @@ func (s *Store) FindMemoList(ctx context.Context, find *api.MemoFind) ([]*api.Me
 	return list, nil
 }
 
+// CountMemo counts the memos of the find, the cursor and the pagination are ignored.
+func (s *Store) CountMemo(ctx context.Context, find *api.MemoFind) (int, error) {
+	where, args := findMemoWhere(find)
+
+	query := `
+		SELECT COUNT(*)
+		FROM memo
+		` + memoOrganizerJoin(find) + `
+		WHERE ` + strings.Join(where, " AND ")
+	var count int
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, FormatError(err)
+	}
+	return count, nil
+}
+
 func (s *Store) FindMemo(ctx context.Context, find *api.MemoFind) (*api.Memo, error) {
 	if find.ID != nil {
 		if memo, ok := s.memoCache.Load(*find.ID); ok {
@@ -173,9 +255,18 @@ func (s *Store) DeleteMemo(ctx context.Context, delete *api.MemoDelete) error {
 	}
 	defer tx.Rollback()
 
+	descendantIDList, err := findMemoDescendantIDList(ctx, tx, delete.ID)
+	if err != nil {
+		return err
+	}
 	if err := deleteMemo(ctx, tx, delete); err != nil {
 		return FormatError(err)
 	}
+	for _, id := range descendantIDList {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM memo WHERE id = ?`, id); err != nil {
+			return FormatError(err)
+		}
+	}
 	if err := vacuum(ctx, tx); err != nil {
 		return err
 	}
@@ -184,25 +275,31 @@ func (s *Store) DeleteMemo(ctx context.Context, delete *api.MemoDelete) error {
 		return FormatError(err)
 	}
 
+	for _, id := range descendantIDList {
+		s.memoCache.Delete(id)
+	}
 	s.memoCache.Delete(delete.ID)
 	return nil
 }
 
 func createMemoRaw(ctx context.Context, tx *sql.Tx, create *api.MemoCreate) (*memoRaw, error) {
-	set := []string{"creator_id", "content", "visibility"}
-	args := []any{create.CreatorID, create.Content, create.Visibility}
-	placeholder := []string{"?", "?", "?"}
+	set := []string{"creator_id", "content", "visibility", "group_id", "parent_id"}
+	args := []any{create.CreatorID, create.Content, create.Visibility, create.GroupID, create.ParentID}
+	placeholder := []string{"?", "?", "?", "?", "?"}
 
 	if v := create.CreatedTs; v != nil {
 		set, args, placeholder = append(set, "created_ts"), append(args, *v), append(placeholder, "?")
 	}
+	if v := create.Location; v != nil {
+		set, args, placeholder = append(set, "latitude", "longitude", "place_name"), append(args, v.Latitude, v.Longitude, v.PlaceName), append(placeholder, "?", "?", "?")
+	}
 
 	query := `
 		INSERT INTO memo (
 			` + strings.Join(set, ", ") + `
 		)
 		VALUES (` + strings.Join(placeholder, ",") + `)
-		RETURNING id, creator_id, created_ts, updated_ts, row_status, content, visibility
+		RETURNING id, creator_id, created_ts, updated_ts, row_status, content, visibility, group_id, parent_id, version, latitude, longitude, place_name
 	`
 	var memoRaw memoRaw
 	if err := tx.QueryRowContext(ctx, query, args...).Scan(
@@ -213,6 +310,12 @@ func createMemoRaw(ctx context.Context, tx *sql.Tx, create *api.MemoCreate) (*me
 		&memoRaw.RowStatus,
 		&memoRaw.Content,
 		&memoRaw.Visibility,
+		&memoRaw.GroupID,
+		&memoRaw.ParentID,
+		&memoRaw.Version,
+		&memoRaw.Latitude,
+		&memoRaw.Longitude,
+		&memoRaw.PlaceName,
 	); err != nil {
 		return nil, FormatError(err)
 	}
@@ -221,7 +324,7 @@ func createMemoRaw(ctx context.Context, tx *sql.Tx, create *api.MemoCreate) (*me
 }
 
 func patchMemoRaw(ctx context.Context, tx *sql.Tx, patch *api.MemoPatch) (*memoRaw, error) {
-	set, args := []string{}, []any{}
+	set, args := []string{"version = version + 1"}, []any{}
 
 	if v := patch.CreatedTs; v != nil {
 		set, args = append(set, "created_ts = ?"), append(args, *v)
@@ -238,14 +341,29 @@ func patchMemoRaw(ctx context.Context, tx *sql.Tx, patch *api.MemoPatch) (*memoR
 	if v := patch.Visibility; v != nil {
 		set, args = append(set, "visibility = ?"), append(args, *v)
 	}
+	if v := patch.GroupID; v != nil {
+		set, args = append(set, "group_id = ?"), append(args, *v)
+	}
+	if v := patch.Location; v != nil {
+		set, args = append(set, "latitude = ?", "longitude = ?", "place_name = ?"), append(args, v.Latitude, v.Longitude, v.PlaceName)
+	} else if patch.ClearLocation {
+		set = append(set, "latitude = NULL", "longitude = NULL", "place_name = ''")
+	}
 
+	where := []string{"id = ?"}
 	args = append(args, patch.ID)
+	if v := patch.ExpectedVersion; v != nil {
+		where, args = append(where, "version = ?"), append(args, *v)
+	}
+	if v := patch.ExpectedUpdatedTs; v != nil {
+		where, args = append(where, "updated_ts = ?"), append(args, *v)
+	}
 
 	query := `
 		UPDATE memo
 		SET ` + strings.Join(set, ", ") + `
-		WHERE id = ?
-		RETURNING id, creator_id, created_ts, updated_ts, row_status, content, visibility
+		WHERE ` + strings.Join(where, " AND ") + `
+		RETURNING id, creator_id, created_ts, updated_ts, row_status, content, visibility, group_id, parent_id, version, latitude, longitude, place_name
 	`
 	var memoRaw memoRaw
 	if err := tx.QueryRowContext(ctx, query, args...).Scan(
@@ -256,38 +374,47 @@ func patchMemoRaw(ctx context.Context, tx *sql.Tx, patch *api.MemoPatch) (*memoR
 		&memoRaw.RowStatus,
 		&memoRaw.Content,
 		&memoRaw.Visibility,
+		&memoRaw.GroupID,
+		&memoRaw.ParentID,
+		&memoRaw.Version,
+		&memoRaw.Latitude,
+		&memoRaw.Longitude,
+		&memoRaw.PlaceName,
 	); err != nil {
-		return nil, FormatError(err)
+		if err != sql.ErrNoRows {
+			return nil, FormatError(err)
+		}
+		// Without a row, either the memo is not found or a precondition has failed.
+		var exist bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM memo WHERE id = ?)`, patch.ID).Scan(&exist); err != nil {
+			return nil, FormatError(err)
+		}
+		if exist {
+			return nil, &common.Error{Code: common.Conflict, Err: fmt.Errorf("memo %d has been changed", patch.ID)}
+		}
+		return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("memo not found: %d", patch.ID)}
 	}
 
 	return &memoRaw, nil
 }
 
 func findMemoRawList(ctx context.Context, tx *sql.Tx, find *api.MemoFind) ([]*memoRaw, error) {
-	where, args := []string{"1 = 1"}, []any{}
-
-	if v := find.ID; v != nil {
-		where, args = append(where, "memo.id = ?"), append(args, *v)
-	}
-	if v := find.CreatorID; v != nil {
-		where, args = append(where, "memo.creator_id = ?"), append(args, *v)
-	}
-	if v := find.RowStatus; v != nil {
-		where, args = append(where, "memo.row_status = ?"), append(args, *v)
-	}
-	if v := find.Pinned; v != nil {
-		where = append(where, "memo_organizer.pinned = 1")
-	}
-	if v := find.ContentSearch; v != nil {
-		where, args = append(where, "memo.content LIKE ?"), append(args, "%"+*v+"%")
-	}
-	if v := find.VisibilityList; len(v) != 0 {
-		list := []string{}
-		for _, visibility := range v {
-			list = append(list, fmt.Sprintf("$%d", len(args)+1))
-			args = append(args, visibility)
+	where, args := findMemoWhere(find)
+	sortColumn := "memo.created_ts"
+	if find.Sort == api.MemoSortUpdated {
+		sortColumn = "memo.updated_ts"
+	}
+	orderBy := sortColumn + " DESC, memo.id DESC"
+	if find.PinnedFirst == nil || *find.PinnedFirst {
+		orderBy = "IFNULL(memo_organizer.pinned, 0) DESC, " + memoPinnedPositionColumn + " ASC, " + orderBy
+	}
+	if v := find.Cursor; v != nil {
+		if v.Pinned != nil {
+			// The position is negated as the pinned memos are in its ascending order.
+			where, args = append(where, "(IFNULL(memo_organizer.pinned, 0), -"+memoPinnedPositionColumn+", "+sortColumn+", memo.id) < (?, ?, ?, ?)"), append(args, *v.Pinned, -v.PinnedPosition, v.Ts, v.ID)
+		} else {
+			where, args = append(where, "("+sortColumn+", memo.id) < (?, ?)"), append(args, v.Ts, v.ID)
 		}
-		where = append(where, fmt.Sprintf("memo.visibility in (%s)", strings.Join(list, ",")))
 	}
 
 	query := `
@@ -299,11 +426,16 @@ func findMemoRawList(ctx context.Context, tx *sql.Tx, find *api.MemoFind) ([]*me
 			memo.row_status,
 			memo.content,
 			memo.visibility,
-			IFNULL(memo_organizer.pinned, 0) AS pinned
+			memo.group_id,
+			memo.parent_id,
+			memo.version,
+			memo.latitude,
+			memo.longitude,
+			memo.place_name
 		FROM memo
-		LEFT JOIN memo_organizer ON memo_organizer.memo_id = memo.id AND memo_organizer.user_id = memo.creator_id
+		` + memoOrganizerJoin(find) + `
 		WHERE ` + strings.Join(where, " AND ") + `
-		ORDER BY pinned DESC, memo.created_ts DESC
+		ORDER BY ` + orderBy + `
 	`
 	if find.Limit != nil {
 		query = fmt.Sprintf("%s LIMIT %d", query, *find.Limit)
@@ -321,7 +453,6 @@ func findMemoRawList(ctx context.Context, tx *sql.Tx, find *api.MemoFind) ([]*me
 	memoRawList := make([]*memoRaw, 0)
 	for rows.Next() {
 		var memoRaw memoRaw
-		var pinned sql.NullBool
 		if err := rows.Scan(
 			&memoRaw.ID,
 			&memoRaw.CreatorID,
@@ -330,14 +461,16 @@ func findMemoRawList(ctx context.Context, tx *sql.Tx, find *api.MemoFind) ([]*me
 			&memoRaw.RowStatus,
 			&memoRaw.Content,
 			&memoRaw.Visibility,
-			&pinned,
+			&memoRaw.GroupID,
+			&memoRaw.ParentID,
+			&memoRaw.Version,
+			&memoRaw.Latitude,
+			&memoRaw.Longitude,
+			&memoRaw.PlaceName,
 		); err != nil {
 			return nil, FormatError(err)
 		}
 
-		if pinned.Valid {
-			memoRaw.Pinned = pinned.Bool
-		}
 		memoRawList = append(memoRawList, &memoRaw)
 	}
 
@@ -348,6 +481,125 @@ func findMemoRawList(ctx context.Context, tx *sql.Tx, find *api.MemoFind) ([]*me
 	return memoRawList, nil
 }
 
+// memoPinnedPositionColumn is the pinned position of the joined memo_organizer, 0 for an unpinned memo.
+const memoPinnedPositionColumn = "IFNULL(CASE WHEN memo_organizer.pinned = 1 THEN memo_organizer.pinned_position END, 0)"
+
+// memoOrganizerJoin joins the memo_organizer of the viewer for the viewer's own memos, the explored memos and the bookmarks,
+// or of the creator otherwise, so that another user's memos are pinned by that user.
+func memoOrganizerJoin(find *api.MemoFind) string {
+	organizerUserID := "memo.creator_id"
+	if v := find.ViewerID; v != nil && (find.CreatorID == nil || *find.CreatorID == *v || find.Bookmarked != nil || find.ReadLater != nil) {
+		organizerUserID = strconv.Itoa(*v)
+	}
+	return "LEFT JOIN memo_organizer ON memo_organizer.memo_id = memo.id AND memo_organizer.user_id = " + organizerUserID
+}
+
+// findMemoWhere builds the conditions of the find except the cursor, the memo_organizer of memoOrganizerJoin is joined.
+func findMemoWhere(find *api.MemoFind) ([]string, []any) {
+	where, args := []string{"1 = 1"}, []any{}
+
+	if v := find.ID; v != nil {
+		where, args = append(where, "memo.id = ?"), append(args, *v)
+	}
+	if v := find.CreatorID; v != nil {
+		where, args = append(where, "memo.creator_id = ?"), append(args, *v)
+	}
+	if v := find.ParentID; v != nil {
+		where, args = append(where, "memo.parent_id = ?"), append(args, *v)
+	} else if find.ID == nil {
+		where = append(where, "memo.parent_id = 0")
+	}
+	if v := find.RowStatus; v != nil {
+		where, args = append(where, "memo.row_status = ?"), append(args, *v)
+	}
+	if v := find.Pinned; v != nil {
+		where, args = append(where, "IFNULL(memo_organizer.pinned, 0) = ?"), append(args, *v)
+	}
+	if v := find.Bookmarked; v != nil {
+		where, args = append(where, "IFNULL(memo_organizer.bookmarked, 0) = ?"), append(args, *v)
+	}
+	if v := find.ReadLater; v != nil {
+		where, args = append(where, "IFNULL(memo_organizer.read_later, 0) = ?"), append(args, *v)
+	}
+	if v := find.HasLocation; v != nil {
+		if *v {
+			where = append(where, "memo.latitude IS NOT NULL")
+		} else {
+			where = append(where, "memo.latitude IS NULL")
+		}
+	}
+	if v := find.BoundingBox; v != nil {
+		where, args = append(where, "memo.latitude BETWEEN ? AND ?"), append(args, v.MinLatitude, v.MaxLatitude)
+		if v.MinLongitude <= v.MaxLongitude {
+			where, args = append(where, "memo.longitude BETWEEN ? AND ?"), append(args, v.MinLongitude, v.MaxLongitude)
+		} else {
+			// The box crosses the antimeridian.
+			where, args = append(where, "(memo.longitude >= ? OR memo.longitude <= ?)"), append(args, v.MinLongitude, v.MaxLongitude)
+		}
+	}
+	if v := find.ContentSearch; v != nil {
+		where, args = append(where, "memo.content LIKE ?"), append(args, "%"+*v+"%")
+	}
+	if v := find.Tag; v != nil {
+		where, args = append(where, `memo.id IN (SELECT memo_id FROM memo_tag WHERE tag = ? OR tag LIKE ? ESCAPE '\')`), append(args, *v, escapeLikePattern(*v)+"/%")
+	}
+	if v := find.TimelineUserID; v != nil {
+		// The user's own memos are all found, the visibility only limits those of the followed users.
+		args = append(args, *v, *v)
+		var visibilityWhere string
+		visibilityWhere, args = findMemoVisibilityWhere(find, args)
+		where = append(where, "(memo.creator_id = ? OR (memo.creator_id IN (SELECT following_id FROM user_follow WHERE follower_id = ?) AND "+visibilityWhere+"))")
+	} else if find.IncludeViewerMemos && find.ViewerID != nil {
+		args = append(args, *find.ViewerID)
+		var visibilityWhere string
+		visibilityWhere, args = findMemoVisibilityWhere(find, args)
+		where = append(where, "(memo.creator_id = ? OR "+visibilityWhere+")")
+	} else if len(find.VisibilityList) != 0 {
+		var visibilityWhere string
+		visibilityWhere, args = findMemoVisibilityWhere(find, args)
+		where = append(where, visibilityWhere)
+	}
+	if v := find.Filter; v != nil {
+		var filterWhere string
+		filterWhere, args = findMemoFilterWhere(v, args)
+		where = append(where, filterWhere)
+	}
+
+	return where, args
+}
+
+// findMemoVisibilityWhere builds the condition of find.VisibilityList, the args are appended in order.
+func findMemoVisibilityWhere(find *api.MemoFind, args []any) (string, []any) {
+	if len(find.VisibilityList) == 0 {
+		return "1 = 1", args
+	}
+	list, includeGroup := []string{}, false
+	for _, visibility := range find.VisibilityList {
+		if visibility == api.Group {
+			includeGroup = true
+			continue
+		}
+		list = append(list, fmt.Sprintf("$%d", len(args)+1))
+		args = append(args, visibility)
+	}
+	conditions := []string{}
+	if len(list) != 0 {
+		conditions = append(conditions, fmt.Sprintf("memo.visibility in (%s)", strings.Join(list, ",")))
+	}
+	// GROUP visibility memos are only found for their creator and the members of their group.
+	if includeGroup && find.ViewerID != nil {
+		conditions = append(conditions, fmt.Sprintf(
+			"(memo.visibility = $%d AND (memo.creator_id = $%d OR memo.group_id IN (SELECT group_id FROM user_group_member WHERE user_id = $%d)))",
+			len(args)+1, len(args)+2, len(args)+2,
+		))
+		args = append(args, api.Group, *find.ViewerID)
+	}
+	if len(conditions) == 0 {
+		conditions = append(conditions, "1 = 0")
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
 func deleteMemo(ctx context.Context, tx *sql.Tx, delete *api.MemoDelete) error {
 	where, args := []string{"id = ?"}, []any{delete.ID}
 
@@ -365,6 +617,53 @@ func deleteMemo(ctx context.Context, tx *sql.Tx, delete *api.MemoDelete) error {
 	return nil
 }
 
+// inheritMemoVisibility sets the visibility of the memo to its comments, which inherit the visibility of the memo they belong to.
+// It returns the IDs of the comments.
+func inheritMemoVisibility(ctx context.Context, tx *sql.Tx, memoRaw *memoRaw) ([]int, error) {
+	descendantIDList, err := findMemoDescendantIDList(ctx, tx, memoRaw.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range descendantIDList {
+		if _, err := tx.ExecContext(ctx, `UPDATE memo SET visibility = ?, group_id = ?, version = version + 1 WHERE id = ?`, memoRaw.Visibility, memoRaw.GroupID, id); err != nil {
+			return nil, FormatError(err)
+		}
+	}
+	return descendantIDList, nil
+}
+
+// findMemoDescendantIDList finds the comments under the memo, including the replies of those comments.
+func findMemoDescendantIDList(ctx context.Context, tx *sql.Tx, id int) ([]int, error) {
+	query := `
+		WITH RECURSIVE descendant(id) AS (
+			SELECT id FROM memo WHERE parent_id = ?
+			UNION ALL
+			SELECT memo.id FROM memo JOIN descendant ON memo.parent_id = descendant.id
+		)
+		SELECT id FROM descendant
+	`
+	rows, err := tx.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	idList := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, FormatError(err)
+		}
+		idList = append(idList, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return idList, nil
+}
+
 func vacuumMemo(ctx context.Context, tx *sql.Tx) error {
 	stmt := `
 	DELETE FROM 
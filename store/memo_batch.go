@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"uamemos/api"
+	"uamemos/common"
+)
+
+// BatchMemo applies the operation of the batch to the memos in a transaction, nothing is changed when one of the memos isn't the creator's.
+// It returns the memos before the operation.
+func (s *Store) BatchMemo(ctx context.Context, batch *api.MemoBatch) ([]*api.Memo, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	memoRawList := []*memoRaw{}
+	if batch.ParsedFilter != nil {
+		pinnedFirst := false
+		memoRawList, err = findMemoRawList(ctx, tx, &api.MemoFind{
+			CreatorID:   &batch.CreatorID,
+			Filter:      batch.ParsedFilter,
+			PinnedFirst: &pinnedFirst,
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		memoIDSet := map[int]bool{}
+		for _, id := range batch.IDList {
+			if memoIDSet[id] {
+				continue
+			}
+			memoIDSet[id] = true
+			id := id
+			list, err := findMemoRawList(ctx, tx, &api.MemoFind{ID: &id})
+			if err != nil {
+				return nil, err
+			}
+			if len(list) == 0 {
+				return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("memo not found: %d", id)}
+			}
+			if list[0].CreatorID != batch.CreatorID {
+				return nil, &common.Error{Code: common.NotAuthorized, Err: fmt.Errorf("memo of another user: %d", id)}
+			}
+			memoRawList = append(memoRawList, list[0])
+		}
+	}
+
+	changedIDList := []int{}
+	for _, raw := range memoRawList {
+		changedIDList = append(changedIDList, raw.ID)
+		switch batch.Operation {
+		case api.MemoBatchArchive, api.MemoBatchRestore:
+			rowStatus := api.Archived
+			if batch.Operation == api.MemoBatchRestore {
+				rowStatus = api.Normal
+			}
+			if _, err := patchMemoRaw(ctx, tx, &api.MemoPatch{
+				ID:        raw.ID,
+				UpdatedTs: &batch.UpdatedTs,
+				RowStatus: &rowStatus,
+			}); err != nil {
+				return nil, err
+			}
+		case api.MemoBatchVisibility:
+			if raw.ParentID != 0 {
+				return nil, &common.Error{Code: common.Invalid, Err: fmt.Errorf("comment visibility is inherited from its memo: %d", raw.ID)}
+			}
+			patchedRaw, err := patchMemoRaw(ctx, tx, &api.MemoPatch{
+				ID:         raw.ID,
+				UpdatedTs:  &batch.UpdatedTs,
+				Visibility: &batch.Visibility,
+				GroupID:    &batch.GroupID,
+			})
+			if err != nil {
+				return nil, err
+			}
+			descendantIDList, err := inheritMemoVisibility(ctx, tx, patchedRaw)
+			if err != nil {
+				return nil, err
+			}
+			changedIDList = append(changedIDList, descendantIDList...)
+		case api.MemoBatchPin, api.MemoBatchUnpin:
+			pinned := batch.Operation == api.MemoBatchPin
+			if err := upsertMemoOrganizer(ctx, tx, &api.MemoOrganizerUpsert{
+				MemoID: raw.ID,
+				UserID: raw.CreatorID,
+				Pinned: &pinned,
+			}); err != nil {
+				return nil, err
+			}
+		case api.MemoBatchDelete:
+			descendantIDList, err := findMemoDescendantIDList(ctx, tx, raw.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, id := range append(descendantIDList, raw.ID) {
+				if _, err := tx.ExecContext(ctx, `DELETE FROM memo WHERE id = ?`, id); err != nil {
+					return nil, FormatError(err)
+				}
+			}
+			changedIDList = append(changedIDList, descendantIDList...)
+		default:
+			return nil, &common.Error{Code: common.Invalid, Err: fmt.Errorf("invalid operation: %s", batch.Operation)}
+		}
+	}
+	if batch.Operation == api.MemoBatchDelete {
+		if err := vacuum(ctx, tx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	for _, id := range changedIDList {
+		s.memoCache.Delete(id)
+	}
+	list := []*api.Memo{}
+	for _, raw := range memoRawList {
+		list = append(list, raw.toMemo())
+	}
+
+	return list, nil
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"strings"
+
+	"uamemos/api"
+)
+
+// findMemoFilterWhere builds the condition of a parsed filter, the args are appended in order.
+func findMemoFilterWhere(filter *api.MemoFilter, args []any) (string, []any) {
+	switch filter.Operator {
+	case api.MemoFilterAnd, api.MemoFilterOr:
+		conditions := []string{}
+		for _, child := range filter.Children {
+			var condition string
+			condition, args = findMemoFilterWhere(child, args)
+			conditions = append(conditions, condition)
+		}
+		return "(" + strings.Join(conditions, " "+string(filter.Operator)+" ") + ")", args
+	case api.MemoFilterNot:
+		condition, args := findMemoFilterWhere(filter.Children[0], args)
+		return "NOT " + condition, args
+	}
+
+	switch filter.Key {
+	case api.MemoFilterText:
+		return `memo.content LIKE ? ESCAPE '\'`, append(args, "%"+escapeLikePattern(filter.Value)+"%")
+	case api.MemoFilterTag:
+		return `memo.id IN (SELECT memo_id FROM memo_tag WHERE tag = ? OR tag LIKE ? ESCAPE '\')`, append(args, filter.Value, escapeLikePattern(filter.Value)+"/%")
+	case api.MemoFilterCreated, api.MemoFilterUpdated:
+		column := "memo.created_ts"
+		if filter.Key == api.MemoFilterUpdated {
+			column = "memo.updated_ts"
+		}
+		conditions := []string{}
+		if filter.FromTs != nil {
+			conditions, args = append(conditions, column+" >= ?"), append(args, *filter.FromTs)
+		}
+		if filter.ToTs != nil {
+			conditions, args = append(conditions, column+" < ?"), append(args, *filter.ToTs)
+		}
+		return "(" + strings.Join(conditions, " AND ") + ")", args
+	case api.MemoFilterVisibility:
+		list := []string{}
+		for _, visibility := range filter.VisibilityList {
+			list, args = append(list, "?"), append(args, visibility)
+		}
+		return "memo.visibility IN (" + strings.Join(list, ",") + ")", args
+	case api.MemoFilterHas:
+		if filter.Value == api.MemoFilterHasResource {
+			return "EXISTS (SELECT 1 FROM memo_resource WHERE memo_resource.memo_id = memo.id)", args
+		}
+		return "(memo.content LIKE '%http://%' OR memo.content LIKE '%https://%')", args
+	case api.MemoFilterPinned:
+		if filter.Pinned {
+			return "IFNULL(memo_organizer.pinned, 0) = 1", args
+		}
+		return "IFNULL(memo_organizer.pinned, 0) = 0", args
+	}
+	return "1 = 0", args
+}
+
+// escapeLikePattern escapes the wildcards of a LIKE pattern using a backslash.
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
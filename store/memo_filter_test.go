@@ -0,0 +1,57 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"uamemos/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindMemoFilterWhere(t *testing.T) {
+	filter, err := api.ParseMemoFilter(`(#work OR "50%") -created:2023-01-01..2023-01-31 visibility:public,private pinned:true`)
+	require.NoError(t, err)
+
+	// The filter args are appended after the args of the preceding conditions.
+	where, args := findMemoFilterWhere(filter, []any{1})
+	require.Equal(t, "("+
+		`(memo.id IN (SELECT memo_id FROM memo_tag WHERE tag = ? OR tag LIKE ? ESCAPE '\') OR memo.content LIKE ? ESCAPE '\')`+
+		" AND NOT (memo.created_ts >= ? AND memo.created_ts < ?)"+
+		" AND memo.visibility IN (?,?)"+
+		" AND IFNULL(memo_organizer.pinned, 0) = 1"+
+		")", where)
+	require.Equal(t, []any{
+		1,
+		"work",
+		"work/%",
+		`%50\%%`,
+		time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).Unix(),
+		time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC).Unix(),
+		api.Public,
+		api.Private,
+	}, args)
+	require.Equal(t, len(args)-1, strings.Count(where, "?"))
+}
+
+func TestFindMemoFilterWhereOpenRange(t *testing.T) {
+	filter, err := api.ParseMemoFilter("updated:2023-01-31.. OR created:..2023-01-31")
+	require.NoError(t, err)
+
+	where, args := findMemoFilterWhere(filter, nil)
+	require.Equal(t, "((memo.updated_ts >= ?) OR (memo.created_ts < ?))", where)
+	require.Equal(t, []any{
+		time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC).Unix(),
+		time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC).Unix(),
+	}, args)
+}
+
+func TestFindMemoFilterWhereEpochRange(t *testing.T) {
+	filter, err := api.ParseMemoFilter("created:1970-01-01.. OR updated:..1969-12-31")
+	require.NoError(t, err)
+
+	where, args := findMemoFilterWhere(filter, nil)
+	require.Equal(t, "((memo.created_ts >= ?) OR (memo.updated_ts < ?))", where)
+	require.Equal(t, []any{int64(0), int64(0)}, args)
+}
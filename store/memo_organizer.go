@@ -16,18 +16,24 @@ type memoOrganizerRaw struct {
 	ID int
 
 	// Domain specific fields
-	MemoID int
-	UserID int
-	Pinned bool
+	MemoID         int
+	UserID         int
+	Pinned         bool
+	PinnedPosition int
+	Bookmarked     bool
+	ReadLater      bool
 }
 
 func (raw *memoOrganizerRaw) toMemoOrganizer() *api.MemoOrganizer {
 	return &api.MemoOrganizer{
 		ID: raw.ID,
 
-		MemoID: raw.MemoID,
-		UserID: raw.UserID,
-		Pinned: raw.Pinned,
+		MemoID:         raw.MemoID,
+		UserID:         raw.UserID,
+		Pinned:         raw.Pinned,
+		PinnedPosition: raw.PinnedPosition,
+		Bookmarked:     raw.Bookmarked,
+		ReadLater:      raw.ReadLater,
 	}
 }
 
@@ -84,62 +90,151 @@ func (s *Store) DeleteMemoOrganizer(ctx context.Context, delete *api.MemoOrganiz
 	return nil
 }
 
+// ComposeMemoOrganizer composes the organizer fields of the viewer carried by the context.
+// Without a viewer, only the pinned fields of the creator are composed, its bookmarks and read later list are private.
+func (s *Store) ComposeMemoOrganizer(ctx context.Context, memo *api.Memo) error {
+	userID, hasViewer := ViewerIDFromContext(ctx)
+	if !hasViewer {
+		userID = memo.CreatorID
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return FormatError(err)
+	}
+	defer tx.Rollback()
+
+	memoOrganizerRaw, err := findMemoOrganizer(ctx, tx, &api.MemoOrganizerFind{
+		MemoID: memo.ID,
+		UserID: userID,
+	})
+	if err != nil {
+		if common.ErrorCode(err) == common.NotFound {
+			memo.Pinned, memo.PinnedPosition, memo.Bookmarked, memo.ReadLater = false, 0, false, false
+			return nil
+		}
+		return err
+	}
+	memo.Pinned = memoOrganizerRaw.Pinned
+	memo.PinnedPosition = memoOrganizerRaw.PinnedPosition
+	memo.Bookmarked, memo.ReadLater = false, false
+	if hasViewer {
+		memo.Bookmarked = memoOrganizerRaw.Bookmarked
+		memo.ReadLater = memoOrganizerRaw.ReadLater
+	}
+	return nil
+}
+
+// OrderPinnedMemo sets the pinned positions of the memos in the order of the list, the memos must be pinned by the user.
+func (s *Store) OrderPinnedMemo(ctx context.Context, order *api.MemoPinnedOrder) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return FormatError(err)
+	}
+	defer tx.Rollback()
+
+	for i, memoID := range order.MemoIDList {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE memo_organizer SET pinned_position = ? WHERE memo_id = ? AND user_id = ? AND pinned = 1
+		`, i+1, memoID, order.UserID)
+		if err != nil {
+			return FormatError(err)
+		}
+		rows, _ := result.RowsAffected()
+		if rows == 0 {
+			return &common.Error{Code: common.NotFound, Err: fmt.Errorf("pinned memo not found: %d", memoID)}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
 func findMemoOrganizer(ctx context.Context, tx *sql.Tx, find *api.MemoOrganizerFind) (*memoOrganizerRaw, error) {
 	query := `
 		SELECT
 			id,
 			memo_id,
 			user_id,
-			pinned
+			pinned,
+			pinned_position,
+			bookmarked,
+			read_later
 		FROM memo_organizer
 		WHERE memo_id = ? AND user_id = ?
 	`
-	row, err := tx.QueryContext(ctx, query, find.MemoID, find.UserID)
-	if err != nil {
-		return nil, FormatError(err)
-	}
-	defer row.Close()
-
-	if !row.Next() {
-		return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("not found")}
-	}
-
 	var memoOrganizerRaw memoOrganizerRaw
-	if err := row.Scan(
+	if err := tx.QueryRowContext(ctx, query, find.MemoID, find.UserID).Scan(
 		&memoOrganizerRaw.ID,
 		&memoOrganizerRaw.MemoID,
 		&memoOrganizerRaw.UserID,
 		&memoOrganizerRaw.Pinned,
+		&memoOrganizerRaw.PinnedPosition,
+		&memoOrganizerRaw.Bookmarked,
+		&memoOrganizerRaw.ReadLater,
 	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("not found")}
+		}
 		return nil, FormatError(err)
 	}
 
-	if err := row.Err(); err != nil {
-		return nil, err
-	}
-
 	return &memoOrganizerRaw, nil
 }
 
 func upsertMemoOrganizer(ctx context.Context, tx *sql.Tx, upsert *api.MemoOrganizerUpsert) error {
+	raw, err := findMemoOrganizer(ctx, tx, &api.MemoOrganizerFind{
+		MemoID: upsert.MemoID,
+		UserID: upsert.UserID,
+	})
+	if err != nil {
+		if common.ErrorCode(err) != common.NotFound {
+			return err
+		}
+		raw = &memoOrganizerRaw{MemoID: upsert.MemoID, UserID: upsert.UserID}
+	}
+	if v := upsert.Pinned; v != nil {
+		raw.Pinned = *v
+	}
+	if v := upsert.PinnedPosition; v != nil {
+		raw.PinnedPosition = *v
+	}
+	if v := upsert.Bookmarked; v != nil {
+		raw.Bookmarked = *v
+	}
+	if v := upsert.ReadLater; v != nil {
+		raw.ReadLater = *v
+	}
+	if !raw.Pinned {
+		raw.PinnedPosition = 0
+	}
+
 	query := `
 		INSERT INTO memo_organizer (
 			memo_id,
 			user_id,
-			pinned
+			pinned,
+			pinned_position,
+			bookmarked,
+			read_later
 		)
-		VALUES (?, ?, ?)
-		ON CONFLICT(memo_id, user_id) DO UPDATE 
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(memo_id, user_id) DO UPDATE
 		SET
-			pinned = EXCLUDED.pinned
-		RETURNING id, memo_id, user_id, pinned
+			pinned = EXCLUDED.pinned,
+			pinned_position = EXCLUDED.pinned_position,
+			bookmarked = EXCLUDED.bookmarked,
+			read_later = EXCLUDED.read_later
 	`
-	var memoOrganizer api.MemoOrganizer
-	if err := tx.QueryRowContext(ctx, query, upsert.MemoID, upsert.UserID, upsert.Pinned).Scan(
-		&memoOrganizer.ID,
-		&memoOrganizer.MemoID,
-		&memoOrganizer.UserID,
-		&memoOrganizer.Pinned,
+	if _, err := tx.ExecContext(ctx, query,
+		raw.MemoID,
+		raw.UserID,
+		raw.Pinned,
+		raw.PinnedPosition,
+		raw.Bookmarked,
+		raw.ReadLater,
 	); err != nil {
 		return FormatError(err)
 	}
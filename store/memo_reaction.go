@@ -0,0 +1,209 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"uamemos/api"
+	"uamemos/common"
+)
+
+// UpsertMemoReaction adds the reaction of the user, created is false when the user has already given it.
+func (s *Store) UpsertMemoReaction(ctx context.Context, upsert *api.MemoReactionUpsert) (memoReaction *api.MemoReaction, created bool, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	list, err := findMemoReactionList(ctx, tx, &api.MemoReactionFind{
+		MemoID:   &upsert.MemoID,
+		UserID:   &upsert.UserID,
+		Reaction: &upsert.Reaction,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(list) != 0 {
+		return list[0], false, nil
+	}
+
+	query := `
+		INSERT INTO memo_reaction (
+			memo_id,
+			user_id,
+			reaction
+		)
+		VALUES (?, ?, ?)
+		RETURNING id, created_ts, memo_id, user_id, reaction
+	`
+	memoReaction = &api.MemoReaction{}
+	if err := tx.QueryRowContext(ctx, query, upsert.MemoID, upsert.UserID, upsert.Reaction).Scan(
+		&memoReaction.ID,
+		&memoReaction.CreatedTs,
+		&memoReaction.MemoID,
+		&memoReaction.UserID,
+		&memoReaction.Reaction,
+	); err != nil {
+		return nil, false, FormatError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, FormatError(err)
+	}
+
+	return memoReaction, true, nil
+}
+
+func (s *Store) FindMemoReactionList(ctx context.Context, find *api.MemoReactionFind) ([]*api.MemoReaction, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	return findMemoReactionList(ctx, tx, find)
+}
+
+func (s *Store) DeleteMemoReaction(ctx context.Context, delete *api.MemoReactionDelete) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return FormatError(err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM memo_reaction WHERE memo_id = ? AND user_id = ? AND reaction = ?
+	`, delete.MemoID, delete.UserID, delete.Reaction)
+	if err != nil {
+		return FormatError(err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return &common.Error{Code: common.NotFound, Err: fmt.Errorf("memo reaction not found")}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+// ComposeMemoReactionList aggregates the reactions of the memo, ordered by the first time each was given.
+func (s *Store) ComposeMemoReactionList(ctx context.Context, memo *api.Memo) error {
+	viewerID, _ := ViewerIDFromContext(ctx)
+	query := `
+		SELECT
+			reaction,
+			COUNT(*),
+			MAX(user_id = ?)
+		FROM memo_reaction
+		WHERE memo_id = ?
+		GROUP BY reaction
+		ORDER BY MIN(id) ASC
+	`
+	rows, err := s.db.QueryContext(ctx, query, viewerID, memo.ID)
+	if err != nil {
+		return FormatError(err)
+	}
+	defer rows.Close()
+
+	memo.ReactionList = []*api.MemoReactionSummary{}
+	for rows.Next() {
+		var summary api.MemoReactionSummary
+		if err := rows.Scan(
+			&summary.Reaction,
+			&summary.Count,
+			&summary.ReactedByMe,
+		); err != nil {
+			return FormatError(err)
+		}
+		memo.ReactionList = append(memo.ReactionList, &summary)
+	}
+
+	if err := rows.Err(); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func findMemoReactionList(ctx context.Context, tx *sql.Tx, find *api.MemoReactionFind) ([]*api.MemoReaction, error) {
+	where, args := []string{"1 = 1"}, []any{}
+
+	if v := find.MemoID; v != nil {
+		where, args = append(where, "memo_id = ?"), append(args, *v)
+	}
+	if v := find.UserID; v != nil {
+		where, args = append(where, "user_id = ?"), append(args, *v)
+	}
+	if v := find.Reaction; v != nil {
+		where, args = append(where, "reaction = ?"), append(args, *v)
+	}
+
+	query := `
+		SELECT
+			id,
+			created_ts,
+			memo_id,
+			user_id,
+			reaction
+		FROM memo_reaction
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY id ASC
+	`
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	list := make([]*api.MemoReaction, 0)
+	for rows.Next() {
+		var memoReaction api.MemoReaction
+		if err := rows.Scan(
+			&memoReaction.ID,
+			&memoReaction.CreatedTs,
+			&memoReaction.MemoID,
+			&memoReaction.UserID,
+			&memoReaction.Reaction,
+		); err != nil {
+			return nil, FormatError(err)
+		}
+		list = append(list, &memoReaction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return list, nil
+}
+
+func vacuumMemoReaction(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		memo_reaction
+	WHERE
+		memo_id NOT IN (
+			SELECT
+				id
+			FROM
+				memo
+		)
+		OR user_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
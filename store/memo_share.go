@@ -0,0 +1,297 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"uamemos/api"
+	"uamemos/common"
+)
+
+type memoShareRaw struct {
+	ID int
+
+	// Standard fields
+	CreatorID int
+	CreatedTs int64
+
+	// Domain specific fields
+	MemoID       int
+	Token        string
+	ExpiredTs    int64
+	PasswordHash string
+	MaxViewCount int
+	ViewCount    int
+}
+
+func (raw *memoShareRaw) toMemoShare() *api.MemoShare {
+	return &api.MemoShare{
+		ID: raw.ID,
+
+		CreatorID: raw.CreatorID,
+		CreatedTs: raw.CreatedTs,
+
+		MemoID:       raw.MemoID,
+		Token:        raw.Token,
+		ExpiredTs:    raw.ExpiredTs,
+		PasswordHash: raw.PasswordHash,
+		HasPassword:  raw.PasswordHash != "",
+		MaxViewCount: raw.MaxViewCount,
+		ViewCount:    raw.ViewCount,
+	}
+}
+
+func (s *Store) CreateMemoShare(ctx context.Context, create *api.MemoShareCreate) (*api.MemoShare, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	memoShareRaw, err := createMemoShareRaw(ctx, tx, create)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return memoShareRaw.toMemoShare(), nil
+}
+
+func (s *Store) FindMemoShareList(ctx context.Context, find *api.MemoShareFind) ([]*api.MemoShare, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	memoShareRawList, err := findMemoShareRawList(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*api.MemoShare{}
+	for _, raw := range memoShareRawList {
+		list = append(list, raw.toMemoShare())
+	}
+
+	return list, nil
+}
+
+func (s *Store) FindMemoShare(ctx context.Context, find *api.MemoShareFind) (*api.MemoShare, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	list, err := findMemoShareRawList(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("not found")}
+	}
+
+	return list[0].toMemoShare(), nil
+}
+
+// IncreaseMemoShareViewCount counts a view of the share link.
+// It returns an Invalid error when the link has reached its max view count.
+func (s *Store) IncreaseMemoShareViewCount(ctx context.Context, id int) (*api.MemoShare, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE memo_share
+		SET view_count = view_count + 1
+		WHERE id = ? AND (max_view_count = 0 OR view_count < max_view_count)
+		RETURNING id, creator_id, created_ts, memo_id, token, expired_ts, password_hash, max_view_count, view_count
+	`
+	var memoShareRaw memoShareRaw
+	if err := tx.QueryRowContext(ctx, query, id).Scan(
+		&memoShareRaw.ID,
+		&memoShareRaw.CreatorID,
+		&memoShareRaw.CreatedTs,
+		&memoShareRaw.MemoID,
+		&memoShareRaw.Token,
+		&memoShareRaw.ExpiredTs,
+		&memoShareRaw.PasswordHash,
+		&memoShareRaw.MaxViewCount,
+		&memoShareRaw.ViewCount,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &common.Error{Code: common.Invalid, Err: fmt.Errorf("memo share has reached its max view count")}
+		}
+		return nil, FormatError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return memoShareRaw.toMemoShare(), nil
+}
+
+func (s *Store) DeleteMemoShare(ctx context.Context, delete *api.MemoShareDelete) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return FormatError(err)
+	}
+	defer tx.Rollback()
+
+	if err := deleteMemoShare(ctx, tx, delete); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func createMemoShareRaw(ctx context.Context, tx *sql.Tx, create *api.MemoShareCreate) (*memoShareRaw, error) {
+	query := `
+		INSERT INTO memo_share (
+			creator_id,
+			memo_id,
+			token,
+			expired_ts,
+			password_hash,
+			max_view_count
+		)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id, creator_id, created_ts, memo_id, token, expired_ts, password_hash, max_view_count, view_count
+	`
+	var memoShareRaw memoShareRaw
+	if err := tx.QueryRowContext(ctx, query,
+		create.CreatorID,
+		create.MemoID,
+		create.Token,
+		create.ExpiredTs,
+		create.PasswordHash,
+		create.MaxViewCount,
+	).Scan(
+		&memoShareRaw.ID,
+		&memoShareRaw.CreatorID,
+		&memoShareRaw.CreatedTs,
+		&memoShareRaw.MemoID,
+		&memoShareRaw.Token,
+		&memoShareRaw.ExpiredTs,
+		&memoShareRaw.PasswordHash,
+		&memoShareRaw.MaxViewCount,
+		&memoShareRaw.ViewCount,
+	); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return &memoShareRaw, nil
+}
+
+func findMemoShareRawList(ctx context.Context, tx *sql.Tx, find *api.MemoShareFind) ([]*memoShareRaw, error) {
+	where, args := []string{"1 = 1"}, []any{}
+
+	if v := find.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := find.CreatorID; v != nil {
+		where, args = append(where, "creator_id = ?"), append(args, *v)
+	}
+	if v := find.MemoID; v != nil {
+		where, args = append(where, "memo_id = ?"), append(args, *v)
+	}
+	if v := find.Token; v != nil {
+		where, args = append(where, "token = ?"), append(args, *v)
+	}
+
+	query := `
+		SELECT
+			id,
+			creator_id,
+			created_ts,
+			memo_id,
+			token,
+			expired_ts,
+			password_hash,
+			max_view_count,
+			view_count
+		FROM memo_share
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY created_ts DESC, id DESC
+	`
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	memoShareRawList := make([]*memoShareRaw, 0)
+	for rows.Next() {
+		var memoShareRaw memoShareRaw
+		if err := rows.Scan(
+			&memoShareRaw.ID,
+			&memoShareRaw.CreatorID,
+			&memoShareRaw.CreatedTs,
+			&memoShareRaw.MemoID,
+			&memoShareRaw.Token,
+			&memoShareRaw.ExpiredTs,
+			&memoShareRaw.PasswordHash,
+			&memoShareRaw.MaxViewCount,
+			&memoShareRaw.ViewCount,
+		); err != nil {
+			return nil, FormatError(err)
+		}
+		memoShareRawList = append(memoShareRawList, &memoShareRaw)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return memoShareRawList, nil
+}
+
+func deleteMemoShare(ctx context.Context, tx *sql.Tx, delete *api.MemoShareDelete) error {
+	where, args := []string{"id = ?"}, []any{delete.ID}
+
+	stmt := `DELETE FROM memo_share WHERE ` + strings.Join(where, " AND ")
+	result, err := tx.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return FormatError(err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return &common.Error{Code: common.NotFound, Err: fmt.Errorf("memo share not found")}
+	}
+
+	return nil
+}
+
+func vacuumMemoShare(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		memo_share
+	WHERE
+		memo_id NOT IN (
+			SELECT
+				id
+			FROM
+				memo
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"strings"
+
+	"uamemos/api"
+)
+
+// FindMemoCreatedTsList finds the created time of the memos, from the oldest.
+func (s *Store) FindMemoCreatedTsList(ctx context.Context, find *api.MemoFind) ([]int64, error) {
+	where, args := findMemoWhere(find)
+
+	query := `
+		SELECT memo.created_ts
+		FROM memo
+		` + memoOrganizerJoin(find) + `
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY memo.created_ts ASC
+	`
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	list := make([]int64, 0)
+	for rows.Next() {
+		var createdTs int64
+		if err := rows.Scan(&createdTs); err != nil {
+			return nil, FormatError(err)
+		}
+		list = append(list, createdTs)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return list, nil
+}
+
+// FindMemoTagUsageList finds the indexed tags of the memos, from the oldest memo.
+func (s *Store) FindMemoTagUsageList(ctx context.Context, find *api.MemoFind) ([]*api.MemoTagUsage, error) {
+	where, args := findMemoWhere(find)
+
+	query := `
+		SELECT
+			memo_tag.tag,
+			memo.created_ts
+		FROM memo
+		` + memoOrganizerJoin(find) + `
+		JOIN memo_tag ON memo_tag.memo_id = memo.id
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY memo.created_ts ASC, memo_tag.tag ASC
+	`
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	list := make([]*api.MemoTagUsage, 0)
+	for rows.Next() {
+		var usage api.MemoTagUsage
+		if err := rows.Scan(
+			&usage.Tag,
+			&usage.MemoCreatedTs,
+		); err != nil {
+			return nil, FormatError(err)
+		}
+		list = append(list, &usage)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return list, nil
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"uamemos/api"
+)
+
+// syncMemoTagList indexes the tags in the memo content, the created time of the tags kept in the content is preserved.
+func syncMemoTagList(ctx context.Context, tx *sql.Tx, memoID int, content string) error {
+	tagList := api.FindTagList(content)
+
+	where, args := []string{"memo_id = ?"}, []any{memoID}
+	if len(tagList) != 0 {
+		placeholder := []string{}
+		for _, tag := range tagList {
+			placeholder, args = append(placeholder, "?"), append(args, tag)
+		}
+		where = append(where, "tag NOT IN ("+strings.Join(placeholder, ",")+")")
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM memo_tag WHERE `+strings.Join(where, " AND "), args...); err != nil {
+		return FormatError(err)
+	}
+
+	for _, tag := range tagList {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO memo_tag (
+				memo_id,
+				tag
+			)
+			VALUES (?, ?)
+			ON CONFLICT(memo_id, tag) DO NOTHING
+		`, memoID, tag); err != nil {
+			return FormatError(err)
+		}
+	}
+
+	return nil
+}
+
+func vacuumMemoTag(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		memo_tag
+	WHERE
+		memo_id NOT IN (
+			SELECT
+				id
+			FROM
+				memo
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
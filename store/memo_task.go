@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"uamemos/api"
+	"uamemos/plugin/markdown"
+)
+
+func (s *Store) FindTaskList(ctx context.Context, find *api.TaskFind) ([]*api.Task, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	return findTaskList(ctx, tx, find)
+}
+
+// FindTaskCountList counts the tasks in the normal memos grouped by the creators, ordered by the creator ID.
+func (s *Store) FindTaskCountList(ctx context.Context, creatorID *int) ([]*api.TaskCount, error) {
+	where, args := []string{"memo.row_status = ?"}, []any{api.Normal}
+	if creatorID != nil {
+		where, args = append(where, "memo.creator_id = ?"), append(args, *creatorID)
+	}
+
+	query := `
+		SELECT
+			memo.creator_id,
+			COALESCE(SUM(memo_task.done = 0), 0),
+			COALESCE(SUM(memo_task.done = 1), 0)
+		FROM memo_task
+		JOIN memo ON memo.id = memo_task.memo_id
+		WHERE ` + strings.Join(where, " AND ") + `
+		GROUP BY memo.creator_id
+		ORDER BY memo.creator_id ASC
+	`
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	list := make([]*api.TaskCount, 0)
+	for rows.Next() {
+		var taskCount api.TaskCount
+		if err := rows.Scan(
+			&taskCount.CreatorID,
+			&taskCount.OpenCount,
+			&taskCount.DoneCount,
+		); err != nil {
+			return nil, FormatError(err)
+		}
+		list = append(list, &taskCount)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return list, nil
+}
+
+func findTaskList(ctx context.Context, tx *sql.Tx, find *api.TaskFind) ([]*api.Task, error) {
+	where, args := []string{"memo.row_status = ?"}, []any{api.Normal}
+
+	if v := find.MemoID; v != nil {
+		where, args = append(where, "memo_task.memo_id = ?"), append(args, *v)
+	}
+	if v := find.CreatorID; v != nil {
+		where, args = append(where, "memo.creator_id = ?"), append(args, *v)
+	}
+	if v := find.Done; v != nil {
+		where, args = append(where, "memo_task.done = ?"), append(args, *v)
+	}
+
+	query := `
+		SELECT
+			memo_task.memo_id,
+			memo_task.idx,
+			memo.creator_id,
+			memo_task.created_ts,
+			memo_task.updated_ts,
+			memo_task.content,
+			memo_task.done
+		FROM memo_task
+		JOIN memo ON memo.id = memo_task.memo_id
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY memo.created_ts DESC, memo_task.memo_id DESC, memo_task.idx ASC
+	`
+	if find.Limit != nil {
+		query, args = query+" LIMIT ?", append(args, *find.Limit)
+		if find.Offset != nil {
+			query, args = query+" OFFSET ?", append(args, *find.Offset)
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	list := make([]*api.Task, 0)
+	for rows.Next() {
+		var task api.Task
+		if err := rows.Scan(
+			&task.MemoID,
+			&task.Index,
+			&task.CreatorID,
+			&task.CreatedTs,
+			&task.UpdatedTs,
+			&task.Content,
+			&task.Done,
+		); err != nil {
+			return nil, FormatError(err)
+		}
+		list = append(list, &task)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return list, nil
+}
+
+// syncMemoTaskList indexes the task list items in the memo content by their position, the updated time is kept for unchanged tasks.
+func syncMemoTaskList(ctx context.Context, tx *sql.Tx, memoID int, content string) error {
+	taskList := markdown.ExtractTasks([]byte(content))
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM memo_task WHERE memo_id = ? AND idx >= ?`, memoID, len(taskList)); err != nil {
+		return FormatError(err)
+	}
+
+	for _, task := range taskList {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO memo_task (
+				memo_id,
+				idx,
+				content,
+				done
+			)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(memo_id, idx) DO UPDATE
+			SET
+				updated_ts = CASE WHEN content = EXCLUDED.content AND done = EXCLUDED.done THEN updated_ts ELSE strftime('%s', 'now') END,
+				content = EXCLUDED.content,
+				done = EXCLUDED.done
+		`, memoID, task.Index, task.Text, task.Checked); err != nil {
+			return FormatError(err)
+		}
+	}
+
+	return nil
+}
+
+func vacuumMemoTask(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		memo_task
+	WHERE
+		memo_id NOT IN (
+			SELECT
+				id
+			FROM
+				memo
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
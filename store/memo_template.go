@@ -0,0 +1,289 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"uamemos/api"
+	"uamemos/common"
+)
+
+// memoTemplateRaw is the store model for a MemoTemplate.
+// Fields have exactly the same meanings as MemoTemplate.
+type memoTemplateRaw struct {
+	ID int
+
+	// Standard fields
+	CreatorID int
+	CreatedTs int64
+	UpdatedTs int64
+
+	// Domain specific fields
+	Scope   api.MemoTemplateScope
+	Name    string
+	Content string
+}
+
+func (raw *memoTemplateRaw) toMemoTemplate() *api.MemoTemplate {
+	return &api.MemoTemplate{
+		ID: raw.ID,
+
+		CreatorID: raw.CreatorID,
+		CreatedTs: raw.CreatedTs,
+		UpdatedTs: raw.UpdatedTs,
+
+		Scope:   raw.Scope,
+		Name:    raw.Name,
+		Content: raw.Content,
+	}
+}
+
+func (s *Store) CreateMemoTemplate(ctx context.Context, create *api.MemoTemplateCreate) (*api.MemoTemplate, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	memoTemplateRaw, err := createMemoTemplate(ctx, tx, create)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return memoTemplateRaw.toMemoTemplate(), nil
+}
+
+func (s *Store) PatchMemoTemplate(ctx context.Context, patch *api.MemoTemplatePatch) (*api.MemoTemplate, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	memoTemplateRaw, err := patchMemoTemplate(ctx, tx, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return memoTemplateRaw.toMemoTemplate(), nil
+}
+
+func (s *Store) FindMemoTemplateList(ctx context.Context, find *api.MemoTemplateFind) ([]*api.MemoTemplate, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	memoTemplateRawList, err := findMemoTemplateList(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*api.MemoTemplate{}
+	for _, raw := range memoTemplateRawList {
+		list = append(list, raw.toMemoTemplate())
+	}
+
+	return list, nil
+}
+
+func (s *Store) FindMemoTemplate(ctx context.Context, find *api.MemoTemplateFind) (*api.MemoTemplate, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	list, err := findMemoTemplateList(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("not found")}
+	}
+
+	return list[0].toMemoTemplate(), nil
+}
+
+func (s *Store) DeleteMemoTemplate(ctx context.Context, delete *api.MemoTemplateDelete) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return FormatError(err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM memo_template WHERE id = ?`, delete.ID)
+	if err != nil {
+		return FormatError(err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return &common.Error{Code: common.NotFound, Err: fmt.Errorf("memo template not found")}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func createMemoTemplate(ctx context.Context, tx *sql.Tx, create *api.MemoTemplateCreate) (*memoTemplateRaw, error) {
+	query := `
+		INSERT INTO memo_template (
+			creator_id,
+			scope,
+			name,
+			content
+		)
+		VALUES (?, ?, ?, ?)
+		RETURNING id, creator_id, created_ts, updated_ts, scope, name, content
+	`
+	var memoTemplateRaw memoTemplateRaw
+	if err := tx.QueryRowContext(ctx, query, create.CreatorID, create.Scope, create.Name, create.Content).Scan(
+		&memoTemplateRaw.ID,
+		&memoTemplateRaw.CreatorID,
+		&memoTemplateRaw.CreatedTs,
+		&memoTemplateRaw.UpdatedTs,
+		&memoTemplateRaw.Scope,
+		&memoTemplateRaw.Name,
+		&memoTemplateRaw.Content,
+	); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return &memoTemplateRaw, nil
+}
+
+func patchMemoTemplate(ctx context.Context, tx *sql.Tx, patch *api.MemoTemplatePatch) (*memoTemplateRaw, error) {
+	set, args := []string{}, []any{}
+
+	if v := patch.UpdatedTs; v != nil {
+		set, args = append(set, "updated_ts = ?"), append(args, *v)
+	}
+	if v := patch.Name; v != nil {
+		set, args = append(set, "name = ?"), append(args, *v)
+	}
+	if v := patch.Content; v != nil {
+		set, args = append(set, "content = ?"), append(args, *v)
+	}
+
+	args = append(args, patch.ID)
+
+	query := `
+		UPDATE memo_template
+		SET ` + strings.Join(set, ", ") + `
+		WHERE id = ?
+		RETURNING id, creator_id, created_ts, updated_ts, scope, name, content
+	`
+	var memoTemplateRaw memoTemplateRaw
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&memoTemplateRaw.ID,
+		&memoTemplateRaw.CreatorID,
+		&memoTemplateRaw.CreatedTs,
+		&memoTemplateRaw.UpdatedTs,
+		&memoTemplateRaw.Scope,
+		&memoTemplateRaw.Name,
+		&memoTemplateRaw.Content,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("memo template not found")}
+		}
+		return nil, FormatError(err)
+	}
+
+	return &memoTemplateRaw, nil
+}
+
+func findMemoTemplateList(ctx context.Context, tx *sql.Tx, find *api.MemoTemplateFind) ([]*memoTemplateRaw, error) {
+	where, args := []string{"1 = 1"}, []any{}
+
+	if v := find.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := find.CreatorID; v != nil {
+		where, args = append(where, "creator_id = ?"), append(args, *v)
+	}
+	if v := find.Scope; v != nil {
+		where, args = append(where, "scope = ?"), append(args, *v)
+	}
+	if v := find.UserID; v != nil {
+		where, args = append(where, "(creator_id = ? OR scope = ?)"), append(args, *v, api.MemoTemplateSystem)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			creator_id,
+			created_ts,
+			updated_ts,
+			scope,
+			name,
+			content
+		FROM memo_template
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY scope DESC, name ASC, id ASC`,
+		args...,
+	)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	memoTemplateRawList := make([]*memoTemplateRaw, 0)
+	for rows.Next() {
+		var memoTemplateRaw memoTemplateRaw
+		if err := rows.Scan(
+			&memoTemplateRaw.ID,
+			&memoTemplateRaw.CreatorID,
+			&memoTemplateRaw.CreatedTs,
+			&memoTemplateRaw.UpdatedTs,
+			&memoTemplateRaw.Scope,
+			&memoTemplateRaw.Name,
+			&memoTemplateRaw.Content,
+		); err != nil {
+			return nil, FormatError(err)
+		}
+
+		memoTemplateRawList = append(memoTemplateRawList, &memoTemplateRaw)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return memoTemplateRawList, nil
+}
+
+// vacuumMemoTemplate deletes the user templates of deleted users, the system templates are kept.
+func vacuumMemoTemplate(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		memo_template
+	WHERE
+		scope = 'USER'
+		AND creator_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package store
+
+import (
+	"testing"
+
+	"uamemos/api"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoOrganizerJoin(t *testing.T) {
+	viewerID, creatorID, bookmarked := 1, 2, true
+	tests := []struct {
+		name string
+		find *api.MemoFind
+		want string
+	}{
+		{name: "anonymous", find: &api.MemoFind{CreatorID: &creatorID}, want: "memo.creator_id"},
+		{name: "own memos", find: &api.MemoFind{CreatorID: &viewerID, ViewerID: &viewerID}, want: "1"},
+		{name: "explore", find: &api.MemoFind{ViewerID: &viewerID}, want: "1"},
+		{name: "other user's memos", find: &api.MemoFind{CreatorID: &creatorID, ViewerID: &viewerID}, want: "memo.creator_id"},
+		{name: "bookmarks", find: &api.MemoFind{CreatorID: &creatorID, ViewerID: &viewerID, Bookmarked: &bookmarked}, want: "1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, "LEFT JOIN memo_organizer ON memo_organizer.memo_id = memo.id AND memo_organizer.user_id = "+test.want, memoOrganizerJoin(test.find))
+		})
+	}
+}
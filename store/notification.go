@@ -0,0 +1,317 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"uamemos/api"
+	"uamemos/common"
+)
+
+type notificationRaw struct {
+	ID int
+
+	// Standard fields
+	CreatedTs int64
+
+	// Domain specific fields
+	ReceiverID int
+	SenderID   int
+	Type       api.NotificationType
+	MemoID     int
+	Reaction   string
+	Read       bool
+}
+
+func (raw *notificationRaw) toNotification() *api.Notification {
+	return &api.Notification{
+		ID: raw.ID,
+
+		CreatedTs: raw.CreatedTs,
+
+		ReceiverID: raw.ReceiverID,
+		SenderID:   raw.SenderID,
+		Type:       raw.Type,
+		MemoID:     raw.MemoID,
+		Reaction:   raw.Reaction,
+		Read:       raw.Read,
+	}
+}
+
+func (s *Store) ComposeNotificationSender(ctx context.Context, notification *api.Notification) error {
+	user, err := s.FindUser(ctx, &api.UserFind{
+		ID: &notification.SenderID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if user.Nickname != "" {
+		notification.SenderName = user.Nickname
+	} else {
+		notification.SenderName = user.Name
+	}
+	return nil
+}
+
+func (s *Store) CreateNotification(ctx context.Context, create *api.NotificationCreate) (*api.Notification, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO notification (
+			receiver_id,
+			sender_id,
+			type,
+			memo_id,
+			reaction
+		)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id, created_ts, receiver_id, sender_id, type, memo_id, reaction, read
+	`
+	var notificationRaw notificationRaw
+	if err := tx.QueryRowContext(ctx, query, create.ReceiverID, create.SenderID, create.Type, create.MemoID, create.Reaction).Scan(
+		&notificationRaw.ID,
+		&notificationRaw.CreatedTs,
+		&notificationRaw.ReceiverID,
+		&notificationRaw.SenderID,
+		&notificationRaw.Type,
+		&notificationRaw.MemoID,
+		&notificationRaw.Reaction,
+		&notificationRaw.Read,
+	); err != nil {
+		return nil, FormatError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	notification := notificationRaw.toNotification()
+	if err := s.ComposeNotificationSender(ctx, notification); err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+func (s *Store) PatchNotification(ctx context.Context, patch *api.NotificationPatch) (*api.Notification, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	set, args := []string{}, []any{}
+	if v := patch.Read; v != nil {
+		set, args = append(set, "read = ?"), append(args, *v)
+	}
+	args = append(args, patch.ID, patch.ReceiverID)
+
+	query := `
+		UPDATE notification
+		SET ` + strings.Join(set, ", ") + `
+		WHERE id = ? AND receiver_id = ?
+		RETURNING id, created_ts, receiver_id, sender_id, type, memo_id, reaction, read
+	`
+	var notificationRaw notificationRaw
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&notificationRaw.ID,
+		&notificationRaw.CreatedTs,
+		&notificationRaw.ReceiverID,
+		&notificationRaw.SenderID,
+		&notificationRaw.Type,
+		&notificationRaw.MemoID,
+		&notificationRaw.Reaction,
+		&notificationRaw.Read,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("notification not found")}
+		}
+		return nil, FormatError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	notification := notificationRaw.toNotification()
+	if err := s.ComposeNotificationSender(ctx, notification); err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+// ReadAllNotification marks all the notifications of the receiver as read.
+func (s *Store) ReadAllNotification(ctx context.Context, receiverID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return FormatError(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE notification SET read = 1 WHERE receiver_id = ? AND read = 0`, receiverID); err != nil {
+		return FormatError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func (s *Store) FindNotificationList(ctx context.Context, find *api.NotificationFind) ([]*api.Notification, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	notificationRawList, err := findNotificationRawList(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*api.Notification{}
+	for _, raw := range notificationRawList {
+		notification := raw.toNotification()
+		if err := s.ComposeNotificationSender(ctx, notification); err != nil {
+			return nil, err
+		}
+		list = append(list, notification)
+	}
+
+	return list, nil
+}
+
+func (s *Store) FindNotification(ctx context.Context, find *api.NotificationFind) (*api.Notification, error) {
+	list, err := s.FindNotificationList(ctx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("not found")}
+	}
+
+	return list[0], nil
+}
+
+// CountNotification counts the notifications matching the find, the pagination is ignored.
+func (s *Store) CountNotification(ctx context.Context, find *api.NotificationFind) (int, error) {
+	where, args := findNotificationWhere(find)
+
+	query := `SELECT COUNT(*) FROM notification WHERE ` + strings.Join(where, " AND ")
+	var count int
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, FormatError(err)
+	}
+	return count, nil
+}
+
+func findNotificationWhere(find *api.NotificationFind) ([]string, []any) {
+	where, args := []string{"1 = 1"}, []any{}
+
+	if v := find.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := find.ReceiverID; v != nil {
+		where, args = append(where, "receiver_id = ?"), append(args, *v)
+	}
+	if v := find.Read; v != nil {
+		where, args = append(where, "read = ?"), append(args, *v)
+	}
+
+	return where, args
+}
+
+func findNotificationRawList(ctx context.Context, tx *sql.Tx, find *api.NotificationFind) ([]*notificationRaw, error) {
+	where, args := findNotificationWhere(find)
+
+	query := `
+		SELECT
+			id,
+			created_ts,
+			receiver_id,
+			sender_id,
+			type,
+			memo_id,
+			reaction,
+			read
+		FROM notification
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY created_ts DESC, id DESC
+	`
+	if find.Limit != nil {
+		query = fmt.Sprintf("%s LIMIT %d", query, *find.Limit)
+		if find.Offset != nil {
+			query = fmt.Sprintf("%s OFFSET %d", query, *find.Offset)
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	notificationRawList := make([]*notificationRaw, 0)
+	for rows.Next() {
+		var notificationRaw notificationRaw
+		if err := rows.Scan(
+			&notificationRaw.ID,
+			&notificationRaw.CreatedTs,
+			&notificationRaw.ReceiverID,
+			&notificationRaw.SenderID,
+			&notificationRaw.Type,
+			&notificationRaw.MemoID,
+			&notificationRaw.Reaction,
+			&notificationRaw.Read,
+		); err != nil {
+			return nil, FormatError(err)
+		}
+		notificationRawList = append(notificationRawList, &notificationRaw)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return notificationRawList, nil
+}
+
+func vacuumNotification(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		notification
+	WHERE
+		memo_id NOT IN (
+			SELECT
+				id
+			FROM
+				memo
+		)
+		OR receiver_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)
+		OR sender_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
@@ -27,6 +27,9 @@ type resourceRaw struct {
 	Size             int64
 	PublicID         string
 	LinkedMemoAmount int
+	// Latitude and Longitude are nil for a resource without a location.
+	Latitude  *float64
+	Longitude *float64
 }
 
 func (raw *resourceRaw) toResource() *api.Resource {
@@ -46,10 +49,28 @@ func (raw *resourceRaw) toResource() *api.Resource {
 		Type:             raw.Type,
 		Size:             raw.Size,
 		PublicID:         raw.PublicID,
+		Location:         raw.toLocation(),
 		LinkedMemoAmount: raw.LinkedMemoAmount,
 	}
 }
 
+// composeResourceLocation shows the location of the resource when the viewer carried by the context is its creator.
+func composeResourceLocation(ctx context.Context, resource *api.Resource) {
+	if viewerID, ok := ViewerIDFromContext(ctx); ok && viewerID == resource.CreatorID {
+		resource.CreatorLocation = resource.Location
+	}
+}
+
+func (raw *resourceRaw) toLocation() *api.Location {
+	if raw.Latitude == nil || raw.Longitude == nil {
+		return nil
+	}
+	return &api.Location{
+		Latitude:  *raw.Latitude,
+		Longitude: *raw.Longitude,
+	}
+}
+
 func (s *Store) ComposeMemoResourceList(ctx context.Context, memo *api.Memo) error {
 	resourceList, err := s.FindResourceList(ctx, &api.ResourceFind{
 		MemoID: &memo.ID,
@@ -101,6 +122,7 @@ func (s *Store) CreateResource(ctx context.Context, create *api.ResourceCreate)
 	}
 
 	resource := resourceRaw.toResource()
+	composeResourceLocation(ctx, resource)
 
 	return resource, nil
 }
@@ -119,7 +141,9 @@ func (s *Store) FindResourceList(ctx context.Context, find *api.ResourceFind) ([
 
 	resourceList := []*api.Resource{}
 	for _, raw := range resourceRawList {
-		resourceList = append(resourceList, raw.toResource())
+		resource := raw.toResource()
+		composeResourceLocation(ctx, resource)
+		resourceList = append(resourceList, resource)
 	}
 
 	return resourceList, nil
@@ -143,6 +167,7 @@ func (s *Store) FindResource(ctx context.Context, find *api.ResourceFind) (*api.
 
 	resourceRaw := list[0]
 	resource := resourceRaw.toResource()
+	composeResourceLocation(ctx, resource)
 
 	return resource, nil
 }
@@ -185,14 +210,19 @@ func (s *Store) PatchResource(ctx context.Context, patch *api.ResourcePatch) (*a
 	}
 
 	resource := resourceRaw.toResource()
+	composeResourceLocation(ctx, resource)
 
 	return resource, nil
 }
 
 func createResourceImpl(ctx context.Context, tx *sql.Tx, create *api.ResourceCreate) (*resourceRaw, error) {
-	fields := []string{"filename", "blob", "external_link", "type", "size", "creator_id", "internal_path", "public_id"}
-	values := []any{create.Filename, create.Blob, create.ExternalLink, create.Type, create.Size, create.CreatorID, create.InternalPath, create.PublicID}
-	placeholders := []string{"?", "?", "?", "?", "?", "?", "?", "?"}
+	var latitude, longitude *float64
+	if v := create.Location; v != nil {
+		latitude, longitude = &v.Latitude, &v.Longitude
+	}
+	fields := []string{"filename", "blob", "external_link", "type", "size", "creator_id", "internal_path", "public_id", "latitude", "longitude"}
+	values := []any{create.Filename, create.Blob, create.ExternalLink, create.Type, create.Size, create.CreatorID, create.InternalPath, create.PublicID, latitude, longitude}
+	placeholders := []string{"?", "?", "?", "?", "?", "?", "?", "?", "?", "?"}
 	query := `
 		INSERT INTO resource (
 			` + strings.Join(fields, ",") + `
@@ -211,6 +241,8 @@ func createResourceImpl(ctx context.Context, tx *sql.Tx, create *api.ResourceCre
 		&resourceRaw.CreatorID,
 		&resourceRaw.InternalPath,
 		&resourceRaw.PublicID,
+		&resourceRaw.Latitude,
+		&resourceRaw.Longitude,
 	}
 	dests = append(dests, []any{&resourceRaw.CreatedTs, &resourceRaw.UpdatedTs}...)
 	if err := tx.QueryRowContext(ctx, query, values...).Scan(dests...); err != nil {
@@ -234,7 +266,7 @@ func patchResourceImpl(ctx context.Context, tx *sql.Tx, patch *api.ResourcePatch
 	}
 
 	args = append(args, patch.ID)
-	fields := []string{"id", "filename", "external_link", "type", "size", "creator_id", "created_ts", "updated_ts", "internal_path", "public_id"}
+	fields := []string{"id", "filename", "external_link", "type", "size", "creator_id", "created_ts", "updated_ts", "internal_path", "public_id", "latitude", "longitude"}
 	query := `
 		UPDATE resource
 		SET ` + strings.Join(set, ", ") + `
@@ -252,6 +284,8 @@ func patchResourceImpl(ctx context.Context, tx *sql.Tx, patch *api.ResourcePatch
 		&resourceRaw.UpdatedTs,
 		&resourceRaw.InternalPath,
 		&resourceRaw.PublicID,
+		&resourceRaw.Latitude,
+		&resourceRaw.Longitude,
 	}
 	if err := tx.QueryRowContext(ctx, query, args...).Scan(dests...); err != nil {
 		return nil, FormatError(err)
@@ -279,7 +313,7 @@ func findResourceListImpl(ctx context.Context, tx *sql.Tx, find *api.ResourceFin
 		where, args = append(where, "resource.public_id = ?"), append(args, *v)
 	}
 
-	fields := []string{"resource.id", "resource.filename", "resource.external_link", "resource.type", "resource.size", "resource.creator_id", "resource.created_ts", "resource.updated_ts", "internal_path", "public_id"}
+	fields := []string{"resource.id", "resource.filename", "resource.external_link", "resource.type", "resource.size", "resource.creator_id", "resource.created_ts", "resource.updated_ts", "internal_path", "public_id", "resource.latitude", "resource.longitude"}
 	if find.GetBlob {
 		fields = append(fields, "resource.blob")
 	}
@@ -322,6 +356,8 @@ func findResourceListImpl(ctx context.Context, tx *sql.Tx, find *api.ResourceFin
 			&resourceRaw.UpdatedTs,
 			&resourceRaw.InternalPath,
 			&resourceRaw.PublicID,
+			&resourceRaw.Latitude,
+			&resourceRaw.Longitude,
 		}
 		if find.GetBlob {
 			dests = append(dests, &resourceRaw.Blob)
@@ -16,6 +16,7 @@ type Store struct {
 	memoCache        sync.Map // map[int]*memoRaw
 	shortcutCache    sync.Map // map[int]*shortcutRaw
 	idpCache         sync.Map // map[int]*identityProviderMessage
+	customRoleCache  sync.Map // map[api.Role]*customRoleRaw
 }
 
 func New(db *sql.DB, profile *profile.Profile) *Store {
@@ -25,6 +26,24 @@ func New(db *sql.DB, profile *profile.Profile) *Store {
 	}
 }
 
+type viewerIDContextKey struct{}
+
+// WithViewerID returns a context carrying the user viewing the memos, so that viewer specific fields get composed.
+func WithViewerID(ctx context.Context, viewerID int) context.Context {
+	return context.WithValue(ctx, viewerIDContextKey{}, viewerID)
+}
+
+// WithoutViewerID returns a context without the viewing user, so that the memos get composed as for an anonymous viewer.
+func WithoutViewerID(ctx context.Context) context.Context {
+	return context.WithValue(ctx, viewerIDContextKey{}, nil)
+}
+
+// ViewerIDFromContext returns the user viewing the memos, if any.
+func ViewerIDFromContext(ctx context.Context) (int, bool) {
+	viewerID, ok := ctx.Value(viewerIDContextKey{}).(int)
+	return viewerID, ok
+}
+
 func (s *Store) Vacuum(ctx context.Context) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -68,6 +87,45 @@ func vacuum(ctx context.Context, tx *sql.Tx) error {
 	if err := vacuumMemoResource(ctx, tx); err != nil {
 		return err
 	}
+	if err := vacuumUserGroupMember(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumMemoShare(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumMemoReaction(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumNotification(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumUserFollow(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumUserKeyPair(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumUserIdentityProvider(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumActivityPubFollower(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumMemoTag(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumMemoTask(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumMemoTemplate(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumCollection(ctx, tx); err != nil {
+		return err
+	}
+	if err := vacuumCollectionMemo(ctx, tx); err != nil {
+		return err
+	}
 	if err := vacuumTag(ctx, tx); err != nil {
 		// Prevent revive warning.
 		return err
@@ -4,21 +4,31 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
 	"strings"
 
 	"uamemos/api"
 	"uamemos/common"
+	"uamemos/plugin/markdown"
+
+	"golang.org/x/exp/slices"
 )
 
 type tagRaw struct {
 	Name      string
 	CreatorID int
+
+	MemoCount  int
+	LastUsedTs int64
 }
 
 func (raw *tagRaw) toTag() *api.Tag {
 	return &api.Tag{
 		Name:      raw.Name,
 		CreatorID: raw.CreatorID,
+
+		MemoCount:  raw.MemoCount,
+		LastUsedTs: raw.LastUsedTs,
 	}
 }
 
@@ -81,6 +91,144 @@ func (s *Store) DeleteTag(ctx context.Context, delete *api.TagDelete) error {
 	return nil
 }
 
+// RenameTag renames the tag and its nested tags, both in the tag list and in the content of the creator's memos.
+// It returns the IDs of the rewritten memos.
+func (s *Store) RenameTag(ctx context.Context, rename *api.TagRename) ([]int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	memoIDList, err := rewriteTag(ctx, tx, rename.CreatorID, rename.Name, rename.NewName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	for _, id := range memoIDList {
+		s.memoCache.Delete(id)
+	}
+	return memoIDList, nil
+}
+
+// MergeTag renames the tags of the list and their nested tags into the target tag in one transaction.
+// It returns the IDs of the rewritten memos.
+func (s *Store) MergeTag(ctx context.Context, merge *api.TagMerge) ([]int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	memoIDList := []int{}
+	for _, name := range merge.NameList {
+		list, err := rewriteTag(ctx, tx, merge.CreatorID, name, merge.TargetName)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range list {
+			if !slices.Contains(memoIDList, id) {
+				memoIDList = append(memoIDList, id)
+			}
+		}
+	}
+	if _, err := upsertTag(ctx, tx, &api.TagUpsert{Name: merge.TargetName, CreatorID: merge.CreatorID}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	for _, id := range memoIDList {
+		s.memoCache.Delete(id)
+	}
+	return memoIDList, nil
+}
+
+// rewriteTag replaces the tag and its nested tags with the new name in the creator's memos and tag list.
+func rewriteTag(ctx context.Context, tx *sql.Tx, creatorID int, name, newName string) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id, content FROM memo WHERE creator_id = ? AND content LIKE ? ESCAPE '\'`, creatorID, "%#"+escapeLikePattern(name)+"%")
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	contentMap := map[int]string{}
+	for rows.Next() {
+		var id int
+		var content string
+		if err := rows.Scan(&id, &content); err != nil {
+			rows.Close()
+			return nil, FormatError(err)
+		}
+		contentMap[id] = content
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, FormatError(err)
+	}
+	rows.Close()
+
+	memoIDList := []int{}
+	for id, content := range contentMap {
+		newContent := replaceContentTag(content, name, newName)
+		if newContent == content {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE memo SET content = ?, updated_ts = strftime('%s', 'now'), version = version + 1 WHERE id = ?`, newContent, id); err != nil {
+			return nil, FormatError(err)
+		}
+		if err := syncMemoTagList(ctx, tx, id, newContent); err != nil {
+			return nil, err
+		}
+		if err := syncMemoTaskList(ctx, tx, id, newContent); err != nil {
+			return nil, err
+		}
+		memoIDList = append(memoIDList, id)
+	}
+	sort.Ints(memoIDList)
+
+	tagRawList, err := findTagList(ctx, tx, &api.TagFind{CreatorID: creatorID})
+	if err != nil {
+		return nil, err
+	}
+	for _, tagRaw := range tagRawList {
+		newTagName, ok := renameTagName(tagRaw.Name, name, newName)
+		if !ok {
+			continue
+		}
+		if err := deleteTag(ctx, tx, &api.TagDelete{Name: tagRaw.Name, CreatorID: creatorID}); err != nil {
+			return nil, err
+		}
+		if _, err := upsertTag(ctx, tx, &api.TagUpsert{Name: newTagName, CreatorID: creatorID}); err != nil {
+			return nil, err
+		}
+	}
+
+	return memoIDList, nil
+}
+
+// replaceContentTag replaces the tag and its nested tags in the content.
+func replaceContentTag(content, name, newName string) string {
+	return string(markdown.ReplaceTags([]byte(content), func(tagName string) (string, bool) {
+		return renameTagName(tagName, name, newName)
+	}))
+}
+
+// renameTagName renames the tag if it is the tag of the name or one of its nested tags.
+func renameTagName(tagName, name, newName string) (string, bool) {
+	if tagName == name {
+		return newName, true
+	}
+	if strings.HasPrefix(tagName, name+"/") {
+		return newName + strings.TrimPrefix(tagName, name), true
+	}
+	return "", false
+}
+
 func upsertTag(ctx context.Context, tx *sql.Tx, upsert *api.TagUpsert) (*tagRaw, error) {
 	query := `
 		INSERT INTO tag (
@@ -104,15 +252,29 @@ func upsertTag(ctx context.Context, tx *sql.Tx, upsert *api.TagUpsert) (*tagRaw,
 }
 
 func findTagList(ctx context.Context, tx *sql.Tx, find *api.TagFind) ([]*tagRaw, error) {
-	where, args := []string{"creator_id = ?"}, []any{find.CreatorID}
+	where, args := []string{"tag.creator_id = ?"}, []any{find.CreatorID}
 
+	// The usage of a tag is counted from the memo tag index of the creator's normal memos.
 	query := `
 		SELECT
-			name,
-			creator_id
+			tag.name,
+			tag.creator_id,
+			IFNULL(tag_usage.memo_count, 0),
+			IFNULL(tag_usage.last_used_ts, 0)
 		FROM tag
+		LEFT JOIN (
+			SELECT
+				memo_tag.tag,
+				memo.creator_id,
+				COUNT(*) AS memo_count,
+				MAX(memo_tag.created_ts) AS last_used_ts
+			FROM memo_tag
+			JOIN memo ON memo.id = memo_tag.memo_id
+			WHERE memo.row_status = 'NORMAL'
+			GROUP BY memo_tag.tag, memo.creator_id
+		) AS tag_usage ON tag_usage.tag = tag.name AND tag_usage.creator_id = tag.creator_id
 		WHERE ` + strings.Join(where, " AND ") + `
-		ORDER BY name ASC
+		ORDER BY tag.name ASC
 	`
 	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -126,6 +288,8 @@ func findTagList(ctx context.Context, tx *sql.Tx, find *api.TagFind) ([]*tagRaw,
 		if err := rows.Scan(
 			&tagRaw.Name,
 			&tagRaw.CreatorID,
+			&tagRaw.MemoCount,
+			&tagRaw.LastUsedTs,
 		); err != nil {
 			return nil, FormatError(err)
 		}
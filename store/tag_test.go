@@ -0,0 +1,59 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameTagName(t *testing.T) {
+	tests := []struct {
+		tagName string
+		want    string
+		ok      bool
+	}{
+		{tagName: "work", want: "job", ok: true},
+		{tagName: "work/archive", want: "job/archive", ok: true},
+		{tagName: "work/a/b", want: "job/a/b", ok: true},
+		{tagName: "workshop", ok: false},
+		{tagName: "home/work", ok: false},
+		{tagName: "Work", ok: false},
+	}
+	for _, test := range tests {
+		t.Run(test.tagName, func(t *testing.T) {
+			newTagName, ok := renameTagName(test.tagName, "work", "job")
+			require.Equal(t, test.ok, ok)
+			require.Equal(t, test.want, newTagName)
+		})
+	}
+}
+
+func TestReplaceContentTag(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{
+			content: "#work and #work/archive",
+			want:    "#job and #job/archive",
+		},
+		{
+			content: "#workshop #home/work",
+			want:    "#workshop #home/work",
+		},
+		{
+			content: "- [ ] review #work\n- [x] file #work/archive",
+			want:    "- [ ] review #job\n- [x] file #job/archive",
+		},
+		{
+			// Tags in code aren't tags.
+			content: "`#work` #work\n\n```\n#work\n```",
+			want:    "`#work` #job\n\n```\n#work\n```",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.content, func(t *testing.T) {
+			require.Equal(t, test.want, replaceContentTag(test.content, "work", "job"))
+		})
+	}
+}
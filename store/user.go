@@ -275,6 +275,9 @@ func patchUser(ctx context.Context, tx *sql.Tx, patch *api.UserPatch) (*userRaw,
 	if v := patch.Username; v != nil {
 		set, args = append(set, "username = ?"), append(args, *v)
 	}
+	if v := patch.Role; v != nil {
+		set, args = append(set, "role = ?"), append(args, *v)
+	}
 	if v := patch.Email; v != nil {
 		set, args = append(set, "email = ?"), append(args, *v)
 	}
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"uamemos/api"
+	"uamemos/common"
+)
+
+func (s *Store) UpsertUserFollow(ctx context.Context, upsert *api.UserFollowUpsert) (*api.UserFollow, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO user_follow (
+			follower_id,
+			following_id
+		)
+		VALUES (?, ?)
+		ON CONFLICT(follower_id, following_id) DO UPDATE
+		SET
+			follower_id = EXCLUDED.follower_id
+		RETURNING follower_id, following_id, created_ts
+	`
+	var userFollow api.UserFollow
+	if err := tx.QueryRowContext(ctx, query, upsert.FollowerID, upsert.FollowingID).Scan(
+		&userFollow.FollowerID,
+		&userFollow.FollowingID,
+		&userFollow.CreatedTs,
+	); err != nil {
+		return nil, FormatError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return &userFollow, nil
+}
+
+func (s *Store) FindUserFollowList(ctx context.Context, find *api.UserFollowFind) ([]*api.UserFollow, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	where, args := findUserFollowWhere(find)
+	query := `
+		SELECT
+			follower_id,
+			following_id,
+			created_ts
+		FROM user_follow
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY created_ts DESC
+	`
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	list := make([]*api.UserFollow, 0)
+	for rows.Next() {
+		var userFollow api.UserFollow
+		if err := rows.Scan(
+			&userFollow.FollowerID,
+			&userFollow.FollowingID,
+			&userFollow.CreatedTs,
+		); err != nil {
+			return nil, FormatError(err)
+		}
+		list = append(list, &userFollow)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return list, nil
+}
+
+func (s *Store) CountUserFollow(ctx context.Context, find *api.UserFollowFind) (int, error) {
+	where, args := findUserFollowWhere(find)
+
+	query := `SELECT COUNT(*) FROM user_follow WHERE ` + strings.Join(where, " AND ")
+	var count int
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, FormatError(err)
+	}
+	return count, nil
+}
+
+func (s *Store) DeleteUserFollow(ctx context.Context, delete *api.UserFollowDelete) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return FormatError(err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM user_follow WHERE follower_id = ? AND following_id = ?`, delete.FollowerID, delete.FollowingID)
+	if err != nil {
+		return FormatError(err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return &common.Error{Code: common.NotFound, Err: fmt.Errorf("user follow not found")}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func findUserFollowWhere(find *api.UserFollowFind) ([]string, []any) {
+	where, args := []string{"1 = 1"}, []any{}
+
+	if v := find.FollowerID; v != nil {
+		where, args = append(where, "follower_id = ?"), append(args, *v)
+	}
+	if v := find.FollowingID; v != nil {
+		where, args = append(where, "following_id = ?"), append(args, *v)
+	}
+
+	return where, args
+}
+
+func vacuumUserFollow(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		user_follow
+	WHERE
+		follower_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)
+		OR following_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,442 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"uamemos/api"
+	"uamemos/common"
+)
+
+type userGroupRaw struct {
+	ID int
+
+	// Standard fields
+	CreatorID int
+	CreatedTs int64
+	UpdatedTs int64
+
+	// Domain specific fields
+	Name        string
+	Description string
+}
+
+func (raw *userGroupRaw) toUserGroup() *api.UserGroup {
+	return &api.UserGroup{
+		ID: raw.ID,
+
+		CreatorID: raw.CreatorID,
+		CreatedTs: raw.CreatedTs,
+		UpdatedTs: raw.UpdatedTs,
+
+		Name:        raw.Name,
+		Description: raw.Description,
+	}
+}
+
+func (s *Store) ComposeUserGroupMemberIDList(ctx context.Context, userGroup *api.UserGroup) error {
+	memberList, err := s.FindUserGroupMemberList(ctx, &api.UserGroupMemberFind{
+		GroupID: &userGroup.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	userGroup.MemberIDList = []int{}
+	for _, member := range memberList {
+		userGroup.MemberIDList = append(userGroup.MemberIDList, member.UserID)
+	}
+	return nil
+}
+
+func (s *Store) CreateUserGroup(ctx context.Context, create *api.UserGroupCreate) (*api.UserGroup, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	userGroupRaw, err := createUserGroupRaw(ctx, tx, create)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	userGroup := userGroupRaw.toUserGroup()
+	if err := s.ComposeUserGroupMemberIDList(ctx, userGroup); err != nil {
+		return nil, err
+	}
+	return userGroup, nil
+}
+
+func (s *Store) PatchUserGroup(ctx context.Context, patch *api.UserGroupPatch) (*api.UserGroup, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	userGroupRaw, err := patchUserGroupRaw(ctx, tx, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	userGroup := userGroupRaw.toUserGroup()
+	if err := s.ComposeUserGroupMemberIDList(ctx, userGroup); err != nil {
+		return nil, err
+	}
+	return userGroup, nil
+}
+
+func (s *Store) FindUserGroupList(ctx context.Context, find *api.UserGroupFind) ([]*api.UserGroup, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	userGroupRawList, err := findUserGroupRawList(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*api.UserGroup{}
+	for _, raw := range userGroupRawList {
+		userGroup := raw.toUserGroup()
+		if err := s.ComposeUserGroupMemberIDList(ctx, userGroup); err != nil {
+			return nil, err
+		}
+		list = append(list, userGroup)
+	}
+
+	return list, nil
+}
+
+func (s *Store) FindUserGroup(ctx context.Context, find *api.UserGroupFind) (*api.UserGroup, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	list, err := findUserGroupRawList(ctx, tx, find)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("not found")}
+	}
+
+	userGroup := list[0].toUserGroup()
+	if err := s.ComposeUserGroupMemberIDList(ctx, userGroup); err != nil {
+		return nil, err
+	}
+	return userGroup, nil
+}
+
+// DeleteUserGroup deletes the user group with its members. The GROUP visibility memos of the group become private.
+func (s *Store) DeleteUserGroup(ctx context.Context, delete *api.UserGroupDelete) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return FormatError(err)
+	}
+	defer tx.Rollback()
+
+	if err := deleteUserGroup(ctx, tx, delete); err != nil {
+		return FormatError(err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE memo
+		SET visibility = ?, group_id = 0, version = version + 1
+		WHERE visibility = ? AND group_id = ?
+	`, api.Private, api.Group, delete.ID); err != nil {
+		return FormatError(err)
+	}
+	if err := vacuumUserGroupMember(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return FormatError(err)
+	}
+
+	s.memoCache.Range(func(key, value any) bool {
+		if value.(*memoRaw).GroupID == delete.ID {
+			s.memoCache.Delete(key)
+		}
+		return true
+	})
+	return nil
+}
+
+func (s *Store) UpsertUserGroupMember(ctx context.Context, upsert *api.UserGroupMemberUpsert) (*api.UserGroupMember, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO user_group_member (
+			group_id,
+			user_id
+		)
+		VALUES (?, ?)
+		ON CONFLICT(group_id, user_id) DO UPDATE
+		SET
+			group_id = EXCLUDED.group_id
+		RETURNING group_id, user_id, created_ts
+	`
+	var userGroupMember api.UserGroupMember
+	if err := tx.QueryRowContext(ctx, query, upsert.GroupID, upsert.UserID).Scan(
+		&userGroupMember.GroupID,
+		&userGroupMember.UserID,
+		&userGroupMember.CreatedTs,
+	); err != nil {
+		return nil, FormatError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return &userGroupMember, nil
+}
+
+func (s *Store) FindUserGroupMemberList(ctx context.Context, find *api.UserGroupMemberFind) ([]*api.UserGroupMember, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	where, args := []string{"1 = 1"}, []any{}
+	if v := find.GroupID; v != nil {
+		where, args = append(where, "group_id = ?"), append(args, *v)
+	}
+	if v := find.UserID; v != nil {
+		where, args = append(where, "user_id = ?"), append(args, *v)
+	}
+
+	query := `
+		SELECT
+			group_id,
+			user_id,
+			created_ts
+		FROM user_group_member
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY created_ts ASC
+	`
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	list := make([]*api.UserGroupMember, 0)
+	for rows.Next() {
+		var userGroupMember api.UserGroupMember
+		if err := rows.Scan(
+			&userGroupMember.GroupID,
+			&userGroupMember.UserID,
+			&userGroupMember.CreatedTs,
+		); err != nil {
+			return nil, FormatError(err)
+		}
+		list = append(list, &userGroupMember)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return list, nil
+}
+
+func (s *Store) DeleteUserGroupMember(ctx context.Context, delete *api.UserGroupMemberDelete) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return FormatError(err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM user_group_member WHERE group_id = ? AND user_id = ?`, delete.GroupID, delete.UserID)
+	if err != nil {
+		return FormatError(err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return &common.Error{Code: common.NotFound, Err: fmt.Errorf("user group member not found")}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+func createUserGroupRaw(ctx context.Context, tx *sql.Tx, create *api.UserGroupCreate) (*userGroupRaw, error) {
+	query := `
+		INSERT INTO user_group (
+			creator_id,
+			name,
+			description
+		)
+		VALUES (?, ?, ?)
+		RETURNING id, creator_id, created_ts, updated_ts, name, description
+	`
+	var userGroupRaw userGroupRaw
+	if err := tx.QueryRowContext(ctx, query, create.CreatorID, create.Name, create.Description).Scan(
+		&userGroupRaw.ID,
+		&userGroupRaw.CreatorID,
+		&userGroupRaw.CreatedTs,
+		&userGroupRaw.UpdatedTs,
+		&userGroupRaw.Name,
+		&userGroupRaw.Description,
+	); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return &userGroupRaw, nil
+}
+
+func patchUserGroupRaw(ctx context.Context, tx *sql.Tx, patch *api.UserGroupPatch) (*userGroupRaw, error) {
+	set, args := []string{}, []any{}
+	if v := patch.UpdatedTs; v != nil {
+		set, args = append(set, "updated_ts = ?"), append(args, *v)
+	}
+	if v := patch.Name; v != nil {
+		set, args = append(set, "name = ?"), append(args, *v)
+	}
+	if v := patch.Description; v != nil {
+		set, args = append(set, "description = ?"), append(args, *v)
+	}
+	args = append(args, patch.ID)
+
+	query := `
+		UPDATE user_group
+		SET ` + strings.Join(set, ", ") + `
+		WHERE id = ?
+		RETURNING id, creator_id, created_ts, updated_ts, name, description
+	`
+	var userGroupRaw userGroupRaw
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&userGroupRaw.ID,
+		&userGroupRaw.CreatorID,
+		&userGroupRaw.CreatedTs,
+		&userGroupRaw.UpdatedTs,
+		&userGroupRaw.Name,
+		&userGroupRaw.Description,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("user group not found")}
+		}
+		return nil, FormatError(err)
+	}
+
+	return &userGroupRaw, nil
+}
+
+func findUserGroupRawList(ctx context.Context, tx *sql.Tx, find *api.UserGroupFind) ([]*userGroupRaw, error) {
+	where, args := []string{"1 = 1"}, []any{}
+
+	if v := find.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := find.MemberID; v != nil {
+		where, args = append(where, "id IN (SELECT group_id FROM user_group_member WHERE user_id = ?)"), append(args, *v)
+	}
+
+	query := `
+		SELECT
+			id,
+			creator_id,
+			created_ts,
+			updated_ts,
+			name,
+			description
+		FROM user_group
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY name ASC
+	`
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer rows.Close()
+
+	userGroupRawList := make([]*userGroupRaw, 0)
+	for rows.Next() {
+		var userGroupRaw userGroupRaw
+		if err := rows.Scan(
+			&userGroupRaw.ID,
+			&userGroupRaw.CreatorID,
+			&userGroupRaw.CreatedTs,
+			&userGroupRaw.UpdatedTs,
+			&userGroupRaw.Name,
+			&userGroupRaw.Description,
+		); err != nil {
+			return nil, FormatError(err)
+		}
+		userGroupRawList = append(userGroupRawList, &userGroupRaw)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return userGroupRawList, nil
+}
+
+func deleteUserGroup(ctx context.Context, tx *sql.Tx, delete *api.UserGroupDelete) error {
+	where, args := []string{"id = ?"}, []any{delete.ID}
+
+	stmt := `DELETE FROM user_group WHERE ` + strings.Join(where, " AND ")
+	result, err := tx.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return FormatError(err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return &common.Error{Code: common.NotFound, Err: fmt.Errorf("user group not found")}
+	}
+
+	return nil
+}
+
+func vacuumUserGroupMember(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		user_group_member
+	WHERE
+		user_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)
+		OR group_id NOT IN (
+			SELECT
+				id
+			FROM
+				user_group
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
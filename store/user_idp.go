@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"uamemos/api"
+	"uamemos/common"
+)
+
+// UserIdentityProviderMessage links a user to its subject at an identity provider.
+// A user signs in through an identity provider only when it is linked to the provider.
+type UserIdentityProviderMessage struct {
+	UserID             int
+	IdentityProviderID int
+	Subject            string
+}
+
+type FindUserIdentityProviderMessage struct {
+	IdentityProviderID int
+	Subject            string
+}
+
+func (s *Store) GetUserIdentityProvider(ctx context.Context, find *FindUserIdentityProviderMessage) (*UserIdentityProviderMessage, error) {
+	userIdentityProviderMessage := &UserIdentityProviderMessage{}
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT
+			user_id,
+			idp_id,
+			subject
+		FROM user_idp
+		WHERE idp_id = ? AND subject = ?
+	`, find.IdentityProviderID, find.Subject).Scan(
+		&userIdentityProviderMessage.UserID,
+		&userIdentityProviderMessage.IdentityProviderID,
+		&userIdentityProviderMessage.Subject,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &common.Error{Code: common.NotFound, Err: fmt.Errorf("user idp not found")}
+		}
+		return nil, FormatError(err)
+	}
+	return userIdentityProviderMessage, nil
+}
+
+// CreateIdentityProviderUser creates the user and links it to the subject at the identity provider in one transaction.
+func (s *Store) CreateIdentityProviderUser(ctx context.Context, create *api.UserCreate, link *UserIdentityProviderMessage) (*api.User, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, FormatError(err)
+	}
+	defer tx.Rollback()
+
+	userRaw, err := createUser(ctx, tx, create)
+	if err != nil {
+		return nil, err
+	}
+	link.UserID = userRaw.ID
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO user_idp (
+			user_id,
+			idp_id,
+			subject
+		)
+		VALUES (?, ?, ?)
+	`, link.UserID, link.IdentityProviderID, link.Subject); err != nil {
+		return nil, FormatError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, FormatError(err)
+	}
+
+	s.userCache.Store(userRaw.ID, userRaw)
+	return userRaw.toUser(), nil
+}
+
+func vacuumUserIdentityProvider(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+	DELETE FROM
+		user_idp
+	WHERE
+		user_id NOT IN (
+			SELECT
+				id
+			FROM
+				user
+		)
+		OR idp_id NOT IN (
+			SELECT
+				id
+			FROM
+				idp
+		)`
+	_, err := tx.ExecContext(ctx, stmt)
+	if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}